@@ -7,14 +7,18 @@ import (
 	"github.com/FrozenPear42/switch-library-manager/data"
 	"github.com/FrozenPear42/switch-library-manager/keys"
 	"github.com/FrozenPear42/switch-library-manager/nut"
+	"github.com/FrozenPear42/switch-library-manager/process"
 	"github.com/FrozenPear42/switch-library-manager/settings"
 	"github.com/FrozenPear42/switch-library-manager/storage"
 	"github.com/FrozenPear42/switch-library-manager/utils"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
+	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 )
 
 type ServerReporter struct {
@@ -35,8 +39,13 @@ type App struct {
 	fullDB             storage.SwitchDatabase
 	configProvider     settings.ConfigurationProvider
 	libraryManager     data.LibraryManager
+	libraryWatcher     *data.LibraryWatcher
+	catalogUpdater     *data.CatalogUpdater
 	nutServer          *nut.Server
 	recentStartupEvent EventMessage
+	workingDirectory   string
+	organizeMutex      sync.Mutex
+	organizePlan       *process.OrganizePlan
 }
 
 // NewApp creates a new App application struct
@@ -104,14 +113,15 @@ func (a *App) startup(ctx context.Context) {
 		runtime.Quit(a.ctx)
 	}
 
-	libraryManager := data.NewLibraryManager(logger.Sugar(), keyProvider, config.ScanDirectories)
+	libraryManager := data.NewLibraryManager(logger.Sugar(), database, keyProvider, config.ScanDirectories, config.ScanRecursive, config.ScanWorkers)
 
 	reporter := &ServerReporter{
 		ctx:    a.ctx,
 		logger: logger.Sugar(),
 	}
-	a.nutServer = nut.NewServer(config.NUTSettings.Host, config.NUTSettings.Port, libraryManager, reporter)
+	a.nutServer = nut.NewServer(config.NUTSettings, config.ScanDirectories, libraryManager, database, database, nut.NewImageCache(filepath.Join(workingDirectory, nut.ImageCacheDirectoryName)), reporter)
 
+	a.workingDirectory = workingDirectory
 	a.fullDB = database
 	a.configProvider = configurationProvider
 	a.sugarLogger = logger.Sugar()
@@ -127,29 +137,75 @@ func (a *App) startup(ctx context.Context) {
 		runtime.Quit(a.ctx)
 	}
 
-	_, err = a.fullDB.GetCatalogEntries(nil, 1, 0)
+	_, err = a.fullDB.GetCatalogEntries(nil, 1, "")
 	if err != nil {
 		sugar.Error("Failed to initialize database\n", err)
 		runtime.Quit(a.ctx)
 	}
 
-	err = a.libraryManager.Rescan(false, func(current, total int, message string) {
-		sugar.Debugf("processing: %v/%v, %v", current, total, message)
-	})
+	// library is served from the DB right away, rescan refreshes it in the background
+	go func() {
+		_, err := a.libraryManager.Rescan(false, func(current, total int, message string) {
+			sugar.Debugf("processing: %v/%v, %v", current, total, message)
+		})
+		if err != nil {
+			sugar.Error("Failed to scan files\n", err)
+			return
+		}
+		a.onLibraryChanged(config.ScanDirectories)
+	}()
+
+	if config.WatchDirectories {
+		a.libraryWatcher = data.NewLibraryWatcher(logger.Sugar(), a.libraryManager, config.ScanDirectories, config.ScanRecursive, a.onLibraryChanged)
+		err = a.libraryWatcher.Start()
+		if err != nil {
+			sugar.Error("Failed to start library watcher\n", err)
+		}
+	}
+
+	a.catalogUpdater = data.NewCatalogUpdater(logger.Sugar(), a.fullDB, a.libraryManager, config.CatalogUpdateInterval, func() error {
+		return a.buildCatalog(func(step, total int, message string) {
+			a.sugarLogger.Debugf("catalog update: %v/%v %v", step, total, message)
+		})
+	}, a.onCatalogChanged)
+	a.catalogUpdater.Start()
+
+	_, err = a.nutServer.Listen()
 	if err != nil {
-		sugar.Error("Failed to init scan files\n", err)
+		sugar.Error("Failed to start NUT server\n", err)
 		runtime.Quit(a.ctx)
 	}
 
-	go func() {
-		err = a.nutServer.Listen()
+	a.sugarLogger.Infof("initialized")
+}
+
+func (a *App) shutdown(ctx context.Context) {
+	if a.nutServer != nil {
+		err := a.nutServer.Shutdown(ctx)
 		if err != nil {
-			sugar.Error("Failed to start NUT server\n", err)
-			runtime.Quit(a.ctx)
+			a.sugarLogger.Errorf("failed to stop NUT server: %v", err)
 		}
-	}()
+	}
+	if a.catalogUpdater != nil {
+		a.catalogUpdater.Close()
+	}
+	if a.libraryWatcher != nil {
+		err := a.libraryWatcher.Close()
+		if err != nil {
+			a.sugarLogger.Errorf("failed to close library watcher: %v", err)
+		}
+	}
+}
 
-	a.sugarLogger.Infof("initialized")
+func (a *App) onLibraryChanged(paths []string) {
+	a.sugarLogger.Infof("library changed: %v", paths)
+	eventMessage := EventMessage{
+		Type: string(EventTypeLibraryChanged),
+		Data: EventLibraryChangedPayload{
+			Paths: paths,
+		},
+	}
+	runtime.EventsEmit(a.ctx, string(EventTypeLibraryChanged), eventMessage)
 }
 
 func (a *App) initializeSwitchDB() error {
@@ -169,10 +225,15 @@ func (a *App) initializeSwitchDB() error {
 		runtime.EventsEmit(a.ctx, string(EventTypeStartupProgress), eventMessage)
 	}
 
-	config := a.configProvider.GetCurrentConfig()
-
-	err := data.BuildCatalog(a.fullDB, config.TitlesEndpoint, config.VersionsEndpoint, updateProgress)
+	err := a.buildCatalog(updateProgress)
 	if err != nil {
+		page, pageErr := a.fullDB.GetCatalogEntries(nil, 1, "")
+		if pageErr == nil && page.TotalCount > 0 {
+			// previously built catalog is still usable, e.g. when offline
+			a.sugarLogger.Warnf("could not refresh title catalog, using stored one: %v", err)
+			updateProgress(1, 1, "Done...")
+			return nil
+		}
 		a.sugarLogger.Errorf("could not build title catalog: %v", err)
 		runtime.Quit(a.ctx)
 
@@ -180,6 +241,89 @@ func (a *App) initializeSwitchDB() error {
 	return nil
 }
 
+func (a *App) buildCatalog(progress data.ProgressCallback) error {
+	config := a.configProvider.GetCurrentConfig()
+	sources, err := config.AdditionalSources()
+	if err != nil {
+		return err
+	}
+	titlesSource, versionsSource := config.CatalogSources()
+	return data.BuildCatalog(a.fullDB, filepath.Join(a.workingDirectory, data.CatalogCacheDirectoryName), titlesSource, versionsSource, sources, progress)
+}
+
+// ImportCatalog asks user for a catalog bundle, a zip archive with titles.json and versions.json, and rebuilds
+// the catalog from it. Returns path of the imported bundle or empty string if user cancelled the dialog.
+func (a *App) ImportCatalog() (string, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import catalog",
+		Filters: []runtime.FileFilter{{
+			DisplayName: "Catalog bundle (*.zip)",
+			Pattern:     "*.zip",
+		}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not select catalog bundle: %w", err)
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	sources, err := a.configProvider.GetCurrentConfig().AdditionalSources()
+	if err != nil {
+		return "", err
+	}
+	changes, err := a.catalogUpdater.UpdateWith(func() error {
+		return data.ImportCatalogBundle(a.fullDB, filepath.Join(a.workingDirectory, data.CatalogCacheDirectoryName), path, sources, func(step, total int, message string) {
+			a.sugarLogger.Debugf("catalog import: %v/%v %v", step, total, message)
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not import catalog: %w", err)
+	}
+	if len(changes) > 0 {
+		a.onCatalogChanged(changes)
+	}
+	return path, nil
+}
+
+func (a *App) onCatalogChanged(changes []data.CatalogChange) {
+	titles := make([]CatalogUpdatedTitle, 0, len(changes))
+	for _, change := range changes {
+		title := CatalogUpdatedTitle{
+			TitleID:    change.TitleID,
+			Name:       change.Name,
+			NewUpdates: make([]CatalogVersionData, 0, len(change.NewUpdates)),
+			NewDLC:     make([]CatalogDLCData, 0, len(change.NewDLCs)),
+		}
+		for _, version := range change.NewUpdates {
+			title.NewUpdates = append(title.NewUpdates, CatalogVersionData{
+				Version:     version.Version,
+				ReleaseDate: version.ReleaseDate,
+			})
+		}
+		for _, dlc := range change.NewDLCs {
+			title.NewDLC = append(title.NewDLC, CatalogDLCData{
+				Name:        dlc.Name,
+				TitleID:     dlc.ID,
+				Banner:      dlc.BannerURL,
+				Region:      dlc.Region,
+				Version:     dlc.Version,
+				Description: dlc.Description,
+			})
+		}
+		titles = append(titles, title)
+	}
+
+	a.sugarLogger.Infof("catalog updated, new content for %d titles", len(titles))
+	eventMessage := EventMessage{
+		Type: string(EventTypeCatalogUpdated),
+		Data: EventCatalogUpdatedPayload{
+			Titles: titles,
+		},
+	}
+	runtime.EventsEmit(a.ctx, string(EventTypeCatalogUpdated), eventMessage)
+}
+
 func (a *App) RequestStartupProgress() {
 	runtime.EventsEmit(a.ctx, string(EventTypeStartupProgress), a.recentStartupEvent)
 }
@@ -189,10 +333,34 @@ func (a *App) LoadCatalog(filters CatalogFilters) (CatalogPage, error) {
 
 	var result []CatalogSwitchGame
 	catalogFilters := &storage.CatalogFilters{
-		SortBy: filters.SortBy,
-		Name:   filters.Name,
-		ID:     filters.ID,
-		Region: filters.Region,
+		SortBy:         filters.SortBy,
+		SortDescending: filters.SortDescending,
+		Name:           filters.Name,
+		ID:             filters.ID,
+		Region:         filters.Region,
+		Publisher:      filters.Publisher,
+		Developer:      filters.Developer,
+		Category:       filters.Category,
+		Language:       filters.Language,
+		MinSize:        filters.MinSize,
+		MaxSize:        filters.MaxSize,
+		ReleasedAfter:  filters.ReleasedAfter,
+		ReleasedBefore: filters.ReleasedBefore,
+		IsDemo:         filters.IsDemo,
+		Ownership:      filters.Ownership,
+	}
+	if filters.Ownership != "" {
+		entries, err := a.libraryManager.GetEntries()
+		if err != nil {
+			return CatalogPage{}, fmt.Errorf("could not get file entries from library: %w", err)
+		}
+		config := a.configProvider.GetCurrentConfig()
+		ownership, err := process.ScanLibraryOwnership(entries, a.fullDB, config.IgnoreDLCTitleIDs)
+		if err != nil {
+			return CatalogPage{}, fmt.Errorf("could not scan library: %w", err)
+		}
+		catalogFilters.OwnedTitles = ownership.Owned
+		catalogFilters.IncompleteTitles = ownership.Incomplete
 	}
 	page, err := a.fullDB.GetCatalogEntries(catalogFilters, filters.Limit, filters.Cursor)
 	if err != nil {
@@ -232,6 +400,11 @@ func (a *App) LoadCatalog(filters CatalogFilters) (CatalogPage, error) {
 				Intro:       entry.Intro,
 				ReleaseDate: entry.ReleaseDate,
 				Publisher:   entry.Publisher,
+				Developer:   entry.Developer,
+				Category:    entry.Category,
+				Languages:   entry.Languages,
+				Size:        entry.Size,
+				IsDemo:      entry.IsDemo,
 				Screenshots: entry.Screenshots,
 			},
 			DLCs:     dlcs,
@@ -247,6 +420,23 @@ func (a *App) LoadCatalog(filters CatalogFilters) (CatalogPage, error) {
 	}, nil
 }
 
+// SearchLibrary returns base title IDs of library titles matching the query, best matches first.
+func (a *App) SearchLibrary(query string) ([]string, error) {
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	results, err := data.SearchLibrary(entries, a.fullDB, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not search library: %w", err)
+	}
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.TitleID)
+	}
+	return ids, nil
+}
+
 func (a *App) LoadLibraryFiles() ([]LibraryFileEntry, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
@@ -268,6 +458,487 @@ func (a *App) LoadLibraryFiles() ([]LibraryFileEntry, error) {
 	return res, nil
 }
 
+func (a *App) LoadScanIssues() ([]LibraryScanIssue, error) {
+	issues, err := a.libraryManager.GetScanIssues()
+	if err != nil {
+		return nil, fmt.Errorf("could not get scan issues: %w", err)
+	}
+	result := make([]LibraryScanIssue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, LibraryScanIssue{
+			FilePath: issue.FilePath,
+			Reason:   issue.Reason,
+			Errors:   issue.ErrorChain(),
+			SeenAt:   issue.SeenAt.Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
+
+func (a *App) LoadMissingUpdates() ([]LibraryMissingUpdate, error) {
+	missing, err := a.scanForMissingUpdates()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]LibraryMissingUpdate, 0, len(missing))
+	for _, m := range missing {
+		result = append(result, LibraryMissingUpdate{
+			TitleID:              m.TitleID,
+			UpdateID:             m.UpdateID,
+			Name:                 m.Name,
+			LocalVersion:         m.LocalVersion,
+			LocalReadableVersion: m.LocalReadableVersion,
+			LatestVersion:        m.LatestVersion,
+			LatestReleaseDate:    m.LatestReleaseDate,
+		})
+	}
+	return result, nil
+}
+
+// ExportMissingUpdates asks user for a destination file and writes missing updates report there.
+// Returns path of the written file or empty string if user cancelled the dialog.
+func (a *App) ExportMissingUpdates(format ExportFormat) (string, error) {
+	var write func(f *os.File, updates []process.MissingUpdate) error
+	switch format {
+	case ExportFormatCSV:
+		write = func(f *os.File, updates []process.MissingUpdate) error {
+			return process.WriteMissingUpdatesCSV(f, updates)
+		}
+	case ExportFormatJSON:
+		write = func(f *os.File, updates []process.MissingUpdate) error {
+			return process.WriteMissingUpdatesJSON(f, updates)
+		}
+	default:
+		return "", fmt.Errorf("unsupported export format: %v", format)
+	}
+
+	missing, err := a.scanForMissingUpdates()
+	if err != nil {
+		return "", err
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: "missing_updates." + string(format),
+		Title:           "Export missing updates",
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not select export file: %w", err)
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("could not create export file: %w", err)
+	}
+	defer f.Close()
+
+	err = write(f, missing)
+	if err != nil {
+		return "", fmt.Errorf("could not write export file: %w", err)
+	}
+	return path, nil
+}
+
+func (a *App) scanForMissingUpdates() ([]process.MissingUpdate, error) {
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	missing, err := process.ScanForMissingUpdates(entries, a.fullDB)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan for missing updates: %w", err)
+	}
+	return missing, nil
+}
+
+func (a *App) LoadMissingDLC() ([]LibraryMissingDLCTitle, error) {
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	config := a.configProvider.GetCurrentConfig()
+	missing, err := process.ScanForMissingDLC(entries, a.fullDB, config.IgnoreDLCTitleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not scan for missing DLC: %w", err)
+	}
+
+	result := make([]LibraryMissingDLCTitle, 0, len(missing))
+	for _, m := range missing {
+		title := LibraryMissingDLCTitle{
+			TitleID:    m.TitleID,
+			Name:       m.Name,
+			OwnedDLC:   m.OwnedDLC,
+			MissingDLC: make([]CatalogDLCData, 0, len(m.MissingDLC)),
+		}
+		for _, dlc := range m.MissingDLC {
+			title.MissingDLC = append(title.MissingDLC, CatalogDLCData{
+				Name:        dlc.Name,
+				TitleID:     dlc.ID,
+				Banner:      dlc.BannerURL,
+				Region:      dlc.Region,
+				Version:     dlc.Version,
+				Description: dlc.Description,
+			})
+		}
+		result = append(result, title)
+	}
+	return result, nil
+}
+
+func (a *App) LoadLibraryCompletion() (LibraryCompletion, error) {
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return LibraryCompletion{}, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	config := a.configProvider.GetCurrentConfig()
+	completion, err := process.CalculateLibraryCompletion(entries, a.fullDB, config.IgnoreDLCTitleIDs)
+	if err != nil {
+		return LibraryCompletion{}, fmt.Errorf("could not calculate library completion: %w", err)
+	}
+
+	stat := func(s process.CompletionStat) LibraryCompletionStat {
+		return LibraryCompletionStat{
+			Owned:      s.Owned,
+			Total:      s.Total,
+			Percentage: s.Percentage(),
+		}
+	}
+	return LibraryCompletion{
+		Titles:  stat(completion.Titles),
+		DLC:     stat(completion.DLC),
+		Updates: stat(completion.Updates),
+	}, nil
+}
+
+// PlanOrganizeLibrary prepares organize plan using options from settings without changing anything on disk.
+// The plan is kept, so it can be executed after preview with OrganizeLibrary.
+func (a *App) PlanOrganizeLibrary() (LibraryOrganizePlan, error) {
+	a.organizeMutex.Lock()
+	defer a.organizeMutex.Unlock()
+
+	config := a.configProvider.GetCurrentConfig()
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return LibraryOrganizePlan{}, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	plan, err := process.PlanOrganizeLibrary(entries, a.fullDB, config.ScanDirectories, config.OrganizeOptions.ProcessOptions())
+	if err != nil {
+		return LibraryOrganizePlan{}, fmt.Errorf("could not plan library organize: %w", err)
+	}
+	a.organizePlan = &plan
+
+	result := LibraryOrganizePlan{
+		Operations: make([]LibraryOrganizeOperation, 0, len(plan.Operations)),
+		HasIssues:  plan.HasIssues(),
+	}
+	for _, operation := range plan.Operations {
+		issues := make([]process.OrganizeIssue, 0, len(operation.Issues))
+		issues = append(issues, operation.Issues...)
+		result.Operations = append(result.Operations, LibraryOrganizeOperation{
+			Type:   operation.Type,
+			From:   operation.From,
+			To:     operation.To,
+			Issues: issues,
+		})
+	}
+	return result, nil
+}
+
+// OrganizeLibrary executes plan prepared by PlanOrganizeLibrary, skipping operations with issues.
+// Old update files are moved to quarantine afterwards if enabled in settings.
+// Progress is reported with organizeProgress events.
+func (a *App) OrganizeLibrary() (LibraryOrganizeResult, error) {
+	a.organizeMutex.Lock()
+	defer a.organizeMutex.Unlock()
+
+	if a.organizePlan == nil {
+		return LibraryOrganizeResult{}, errors.New("organize plan was not prepared")
+	}
+	plan := *a.organizePlan
+	a.organizePlan = nil
+
+	result, err := process.ExecuteOrganizePlan(plan, filepath.Join(a.workingDirectory, process.OrganizeJournalFileName), a.reportOrganizeProgress)
+	if err != nil {
+		return LibraryOrganizeResult{}, fmt.Errorf("could not organize library: %w", err)
+	}
+	organizeResult, err := a.applyOrganizeResult(result)
+	if err != nil {
+		return LibraryOrganizeResult{}, err
+	}
+
+	config := a.configProvider.GetCurrentConfig()
+	if !config.OrganizeOptions.DeleteOldUpdateFiles {
+		return organizeResult, nil
+	}
+	cleanupResult, err := a.cleanupLibrary(func(candidate process.CleanupCandidate) bool {
+		return candidate.Reason == process.CleanupReasonOldUpdate
+	})
+	if err != nil {
+		return LibraryOrganizeResult{}, err
+	}
+	organizeResult.Quarantined = len(cleanupResult.Entries)
+	organizeResult.Errors = append(organizeResult.Errors, cleanupResult.Errors...)
+	return organizeResult, nil
+}
+
+// UndoOrganizeLibrary reverts the most recent OrganizeLibrary run.
+func (a *App) UndoOrganizeLibrary() (LibraryOrganizeResult, error) {
+	a.organizeMutex.Lock()
+	defer a.organizeMutex.Unlock()
+
+	a.organizePlan = nil
+	result, err := process.UndoOrganize(filepath.Join(a.workingDirectory, process.OrganizeJournalFileName), a.reportOrganizeProgress)
+	if err != nil {
+		return LibraryOrganizeResult{}, fmt.Errorf("could not undo library organize: %w", err)
+	}
+	return a.applyOrganizeResult(result)
+}
+
+func (a *App) reportOrganizeProgress(step, total int, message string) {
+	a.sugarLogger.Debugf("organize progress: %v/%v %v", step, total, message)
+	eventMessage := EventMessage{
+		Type: string(EventTypeOrganizeProgress),
+		Data: EventOrganizeProgressPayload{
+			Completed: step == total,
+			Running:   step != total,
+			Message:   message,
+			Current:   step,
+			Total:     total,
+		},
+	}
+	runtime.EventsEmit(a.ctx, string(EventTypeOrganizeProgress), eventMessage)
+}
+
+// applyOrganizeResult updates library with moved files and notifies frontend about the change.
+func (a *App) applyOrganizeResult(result process.OrganizeResult) (LibraryOrganizeResult, error) {
+	err := a.libraryManager.MoveEntries(result.Moves)
+	if err != nil {
+		return LibraryOrganizeResult{}, fmt.Errorf("could not update library: %w", err)
+	}
+
+	organizeResult := LibraryOrganizeResult{
+		Moved:  len(result.Moves),
+		Errors: make([]LibraryOrganizeError, 0, len(result.Errors)),
+	}
+	for filePath, err := range result.Errors {
+		a.sugarLogger.Warnf("could not organize file %v: %v", filePath, err)
+		organizeResult.Errors = append(organizeResult.Errors, LibraryOrganizeError{
+			FilePath: filePath,
+			Error:    err.Error(),
+		})
+	}
+	sort.Slice(organizeResult.Errors, func(i, j int) bool {
+		return organizeResult.Errors[i].FilePath < organizeResult.Errors[j].FilePath
+	})
+
+	if len(result.Moves) > 0 {
+		paths := make([]string, 0, len(result.Moves))
+		for _, to := range result.Moves {
+			paths = append(paths, to)
+		}
+		slices.Sort(paths)
+		a.onLibraryChanged(paths)
+	}
+	return organizeResult, nil
+}
+
+// LoadCleanupCandidates returns old update and duplicate files that can be moved to quarantine.
+func (a *App) LoadCleanupCandidates() ([]LibraryCleanupCandidate, error) {
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	candidates := process.FindCleanupCandidates(entries)
+	result := make([]LibraryCleanupCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, LibraryCleanupCandidate{
+			FilePath:     candidate.FilePath,
+			Reason:       candidate.Reason,
+			TitleID:      candidate.TitleID,
+			Version:      candidate.Version,
+			KeptFilePath: candidate.KeptFilePath,
+		})
+	}
+	return result, nil
+}
+
+// CleanupLibrary moves all the old update and duplicate files to quarantine.
+func (a *App) CleanupLibrary() (LibraryCleanupResult, error) {
+	a.organizeMutex.Lock()
+	defer a.organizeMutex.Unlock()
+
+	return a.cleanupLibrary(func(candidate process.CleanupCandidate) bool {
+		return true
+	})
+}
+
+func (a *App) LoadQuarantine() ([]LibraryQuarantineEntry, error) {
+	config := a.configProvider.GetCurrentConfig()
+	entries, err := process.ListQuarantine(config.ScanDirectories)
+	if err != nil {
+		return nil, fmt.Errorf("could not list quarantine: %w", err)
+	}
+	result := make([]LibraryQuarantineEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, newLibraryQuarantineEntry(entry))
+	}
+	return result, nil
+}
+
+// RestoreQuarantined moves quarantined files identified by their quarantine paths back into the library.
+func (a *App) RestoreQuarantined(quarantinePaths []string) (LibraryCleanupResult, error) {
+	a.organizeMutex.Lock()
+	defer a.organizeMutex.Unlock()
+
+	config := a.configProvider.GetCurrentConfig()
+	result, err := process.RestoreQuarantined(quarantinePaths, config.ScanDirectories)
+	if err != nil {
+		return LibraryCleanupResult{}, fmt.Errorf("could not restore quarantined files: %w", err)
+	}
+	return a.applyCleanupResult(result), nil
+}
+
+func (a *App) cleanupLibrary(filter func(candidate process.CleanupCandidate) bool) (LibraryCleanupResult, error) {
+	config := a.configProvider.GetCurrentConfig()
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return LibraryCleanupResult{}, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+
+	var candidates []process.CleanupCandidate
+	for _, candidate := range process.FindCleanupCandidates(entries) {
+		if filter(candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	result, err := process.QuarantineFiles(candidates, config.ScanDirectories)
+	if err != nil {
+		return LibraryCleanupResult{}, fmt.Errorf("could not quarantine files: %w", err)
+	}
+	return a.applyCleanupResult(result), nil
+}
+
+// applyCleanupResult updates library entries of quarantined or restored files.
+func (a *App) applyCleanupResult(result process.CleanupResult) LibraryCleanupResult {
+	cleanupResult := LibraryCleanupResult{
+		Entries: make([]LibraryQuarantineEntry, 0, len(result.Entries)),
+		Errors:  make([]LibraryOrganizeError, 0, len(result.Errors)),
+	}
+	paths := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		err := a.libraryManager.RescanPath(entry.OriginalPath)
+		if err != nil {
+			a.sugarLogger.Warnf("could not update library entry %v: %v", entry.OriginalPath, err)
+		}
+		cleanupResult.Entries = append(cleanupResult.Entries, newLibraryQuarantineEntry(entry))
+		paths = append(paths, entry.OriginalPath)
+	}
+	for filePath, err := range result.Errors {
+		a.sugarLogger.Warnf("could not quarantine file %v: %v", filePath, err)
+		cleanupResult.Errors = append(cleanupResult.Errors, LibraryOrganizeError{
+			FilePath: filePath,
+			Error:    err.Error(),
+		})
+	}
+	sort.Slice(cleanupResult.Errors, func(i, j int) bool {
+		return cleanupResult.Errors[i].FilePath < cleanupResult.Errors[j].FilePath
+	})
+
+	if len(paths) > 0 {
+		slices.Sort(paths)
+		a.onLibraryChanged(paths)
+	}
+	return cleanupResult
+}
+
+func newLibraryQuarantineEntry(entry process.QuarantineEntry) LibraryQuarantineEntry {
+	return LibraryQuarantineEntry{
+		OriginalPath:   entry.OriginalPath,
+		QuarantinePath: entry.QuarantinePath,
+		Reason:         entry.Reason,
+		TitleID:        entry.TitleID,
+		QuarantinedAt:  entry.QuarantinedAt.Format(time.RFC3339),
+	}
+}
+
+// LoadConsoles lists consoles that reported their installed titles to the NUT server.
+func (a *App) LoadConsoles() ([]Console, error) {
+	reports, states, err := a.scanConsoleInstalls()
+	if err != nil {
+		return nil, err
+	}
+	behind := make(map[string]int)
+	for _, state := range states {
+		for _, console := range state.Consoles {
+			if console.IsBehind {
+				behind[console.ConsoleID]++
+			}
+		}
+	}
+
+	result := make([]Console, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, Console{
+			ConsoleID:   report.ConsoleID,
+			User:        report.User,
+			Client:      report.Client,
+			ReportedAt:  report.ReportedAt.Format(time.RFC3339),
+			TitleCount:  len(report.Titles),
+			BehindCount: behind[report.ConsoleID],
+		})
+	}
+	return result, nil
+}
+
+// LoadConsoleInstalls lists library titles installed on consoles with installed versions.
+func (a *App) LoadConsoleInstalls() ([]LibraryTitleInstalls, error) {
+	_, states, err := a.scanConsoleInstalls()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]LibraryTitleInstalls, 0, len(states))
+	for _, state := range states {
+		title := LibraryTitleInstalls{
+			TitleID:      state.TitleID,
+			Name:         state.Name,
+			LocalVersion: state.LocalVersion,
+			Consoles:     make([]ConsoleTitleInstall, 0, len(state.Consoles)),
+		}
+		for _, console := range state.Consoles {
+			title.Consoles = append(title.Consoles, ConsoleTitleInstall{
+				ConsoleID:        console.ConsoleID,
+				InstalledVersion: console.InstalledVersion,
+				IsBehind:         console.IsBehind,
+			})
+		}
+		result = append(result, title)
+	}
+	return result, nil
+}
+
+// DeleteConsole forgets installed titles reported by the console.
+func (a *App) DeleteConsole(consoleID string) error {
+	err := a.fullDB.DeleteConsoleReport(consoleID)
+	if err != nil {
+		return fmt.Errorf("could not delete console: %w", err)
+	}
+	return nil
+}
+
+func (a *App) scanConsoleInstalls() ([]storage.ConsoleReport, []process.TitleInstallState, error) {
+	reports, err := a.fullDB.GetConsoleReports()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get console reports: %w", err)
+	}
+	entries, err := a.libraryManager.GetEntries()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get file entries from library: %w", err)
+	}
+	return reports, process.ScanConsoleInstalls(entries, reports, a.fullDB), nil
+}
+
 func (a *App) LoadLibraryGames() ([]LibrarySwitchGame, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
@@ -375,6 +1046,11 @@ func (a *App) LoadLibraryGames() ([]LibrarySwitchGame, error) {
 			Description: catalogData.Description,
 			Intro:       catalogData.Intro,
 			Publisher:   catalogData.Publisher,
+			Developer:   catalogData.Developer,
+			Category:    catalogData.Category,
+			Languages:   catalogData.Languages,
+			Size:        catalogData.Size,
+			IsDemo:      catalogData.IsDemo,
 			Screenshots: catalogData.Screenshots,
 		}
 
@@ -448,20 +1124,6 @@ func (a *App) LoadLibraryGames() ([]LibrarySwitchGame, error) {
 //	}
 //}
 
-//func (a *App) OrganizeLibrary() {
-//	//folderToScan := settings.ReadSettings(g.baseFolder).AppDataDirectory
-//	//options := settings.ReadSettings(g.baseFolder).OrganizeOptions
-//	//if !process.IsOptionsValid(options) {
-//	//	zap.S().Error("the organize options in settings.json are not valid, please check that the template contains file/folder name")
-//	//	g.state.window.SendMessage(Message{Name: "error", Payload: "the organize options in settings.json are not valid, please check that the template contains file/folder name"}, func(m *astilectron.EventMessage) {})
-//	//	return
-//	//}
-//	//process.OrganizeByFolders(folderToScan, g.state.localDB, g.state.switchDB, g)
-//	//if settings.ReadSettings(g.baseFolder).OrganizeOptions.DeleteOldUpdateFiles {
-//	//	process.DeleteOldUpdates(g.baseFolder, g.state.localDB, g)
-//	//}
-//}
-
 //func (a *App) CheckUpdate() (string, error) {
 //	//recentVersion, isUpdateAvailable, err := settings.CheckForUpdates()
 //	//if err != nil {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/process"
 	"github.com/FrozenPear42/switch-library-manager/storage"
 )
 
@@ -21,6 +22,11 @@ type CatalogGameData struct {
 	Description string   `json:"description"`
 	Intro       string   `json:"intro"`
 	Publisher   string   `json:"publisher"`
+	Developer   string   `json:"developer"`
+	Category    []string `json:"category"`
+	Languages   []string `json:"languages"`
+	Size        int      `json:"size"`
+	IsDemo      bool     `json:"isDemo"`
 	Screenshots []string `json:"screenshots"`
 }
 
@@ -47,17 +53,28 @@ type CatalogSwitchGame struct {
 type CatalogPage struct {
 	Games      []CatalogSwitchGame `json:"games"`
 	TotalGames int                 `json:"totalTitles"`
-	NextCursor int                 `json:"nextCursor"`
+	NextCursor string              `json:"nextCursor"`
 	IsLastPage bool                `json:"isLastPage"`
 }
 
 type CatalogFilters struct {
-	SortBy storage.CatalogFiltersSortBy `json:"sortBy"`
-	Name   *string                      `json:"name"`
-	ID     *string                      `json:"id"`
-	Region []string                     `json:"region"`
-	Cursor int                          `json:"cursor"`
-	Limit  int                          `json:"limit"`
+	SortBy         storage.CatalogFiltersSortBy `json:"sortBy"`
+	SortDescending bool                         `json:"sortDescending"`
+	Name           *string                      `json:"name"`
+	ID             *string                      `json:"id"`
+	Region         []string                     `json:"region"`
+	Publisher      *string                      `json:"publisher"`
+	Developer      *string                      `json:"developer"`
+	Category       []string                     `json:"category"`
+	Language       []string                     `json:"language"`
+	MinSize        *int                         `json:"minSize"`
+	MaxSize        *int                         `json:"maxSize"`
+	ReleasedAfter  *string                      `json:"releasedAfter"`
+	ReleasedBefore *string                      `json:"releasedBefore"`
+	IsDemo         *bool                        `json:"isDemo"`
+	Ownership      storage.CatalogOwnership     `json:"ownership"`
+	Cursor         string                       `json:"cursor"`
+	Limit          int                          `json:"limit"`
 }
 
 // Library
@@ -68,6 +85,96 @@ type LibraryFileEntry struct {
 	FileSize int    `json:"fileSize"`
 }
 
+type LibraryScanIssue struct {
+	FilePath string               `json:"filePath"`
+	Reason   data.ScanIssueReason `json:"reason"`
+	Errors   []string             `json:"errors"`
+	// SeenAt is time of the scan in RFC 3339 format
+	SeenAt string `json:"seenAt"`
+}
+
+type LibraryMissingUpdate struct {
+	TitleID              string `json:"titleID"`
+	UpdateID             string `json:"updateID"`
+	Name                 string `json:"name"`
+	LocalVersion         int    `json:"localVersion"`
+	LocalReadableVersion string `json:"localReadableVersion"`
+	LatestVersion        int    `json:"latestVersion"`
+	LatestReleaseDate    string `json:"latestReleaseDate"`
+}
+
+type LibraryMissingDLCTitle struct {
+	TitleID    string           `json:"titleID"`
+	Name       string           `json:"name"`
+	OwnedDLC   int              `json:"ownedDLC"`
+	MissingDLC []CatalogDLCData `json:"missingDLC"`
+}
+
+type LibraryCompletionStat struct {
+	Owned      int     `json:"owned"`
+	Total      int     `json:"total"`
+	Percentage float64 `json:"percentage"`
+}
+
+type LibraryCompletion struct {
+	Titles  LibraryCompletionStat `json:"titles"`
+	DLC     LibraryCompletionStat `json:"dlc"`
+	Updates LibraryCompletionStat `json:"updates"`
+}
+
+type LibraryOrganizePlan struct {
+	Operations []LibraryOrganizeOperation `json:"operations"`
+	HasIssues  bool                       `json:"hasIssues"`
+}
+
+type LibraryOrganizeOperation struct {
+	Type   process.OrganizeOperationType `json:"type"`
+	From   string                        `json:"from"`
+	To     string                        `json:"to"`
+	Issues []process.OrganizeIssue       `json:"issues"`
+}
+
+type LibraryOrganizeResult struct {
+	Moved int `json:"moved"`
+	// Quarantined is number of old update files moved to quarantine after organize
+	Quarantined int                    `json:"quarantined"`
+	Errors      []LibraryOrganizeError `json:"errors"`
+}
+
+type LibraryOrganizeError struct {
+	FilePath string `json:"filePath"`
+	Error    string `json:"error"`
+}
+
+type LibraryCleanupCandidate struct {
+	FilePath     string                `json:"filePath"`
+	Reason       process.CleanupReason `json:"reason"`
+	TitleID      string                `json:"titleID"`
+	Version      int                   `json:"version"`
+	KeptFilePath string                `json:"keptFilePath"`
+}
+
+type LibraryQuarantineEntry struct {
+	OriginalPath   string                `json:"originalPath"`
+	QuarantinePath string                `json:"quarantinePath"`
+	Reason         process.CleanupReason `json:"reason"`
+	TitleID        string                `json:"titleID"`
+	// QuarantinedAt is time of the cleanup in RFC 3339 format
+	QuarantinedAt string `json:"quarantinedAt"`
+}
+
+type LibraryCleanupResult struct {
+	Entries []LibraryQuarantineEntry `json:"entries"`
+	Errors  []LibraryOrganizeError   `json:"errors"`
+}
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatJSON ExportFormat = "json"
+)
+
 type LibraryGameData struct {
 	CatalogGameData
 	InLibrary bool                  `json:"inLibrary"`
@@ -114,12 +221,42 @@ type LibrarySwitchGame struct {
 	IsRecentUpdateInLibrary bool                         `json:"isRecentUpdateInLibrary"`
 }
 
+// Consoles
+
+type Console struct {
+	ConsoleID string `json:"consoleID"`
+	User      string `json:"user"`
+	Client    string `json:"client"`
+	// ReportedAt is time of the last installed apps report in RFC 3339 format
+	ReportedAt string `json:"reportedAt"`
+	// TitleCount is number of reported installed titles
+	TitleCount int `json:"titleCount"`
+	// BehindCount is number of library titles installed on the console in an older version than available locally
+	BehindCount int `json:"behindCount"`
+}
+
+type ConsoleTitleInstall struct {
+	ConsoleID        string `json:"consoleID"`
+	InstalledVersion int    `json:"installedVersion"`
+	IsBehind         bool   `json:"isBehind"`
+}
+
+type LibraryTitleInstalls struct {
+	TitleID      string                `json:"titleID"`
+	Name         string                `json:"name"`
+	LocalVersion int                   `json:"localVersion"`
+	Consoles     []ConsoleTitleInstall `json:"consoles"`
+}
+
 // Events
 
 type EventType string
 
 const (
-	EventTypeStartupProgress EventType = "startupProgress"
+	EventTypeStartupProgress  EventType = "startupProgress"
+	EventTypeLibraryChanged   EventType = "libraryChanged"
+	EventTypeOrganizeProgress EventType = "organizeProgress"
+	EventTypeCatalogUpdated   EventType = "catalogUpdated"
 )
 
 type EventMessagePayload interface {
@@ -142,3 +279,30 @@ type EventStartupProgressPayload struct {
 	Current   int    `json:"current"`
 	Total     int    `json:"total"`
 }
+
+type EventLibraryChangedPayload struct {
+	_eventMessagePayload
+	Paths []string `json:"paths"`
+}
+
+type EventOrganizeProgressPayload struct {
+	_eventMessagePayload
+	Completed bool   `json:"completed"`
+	Running   bool   `json:"running"`
+	Message   string `json:"message"`
+	Current   int    `json:"current"`
+	Total     int    `json:"total"`
+}
+
+type EventCatalogUpdatedPayload struct {
+	_eventMessagePayload
+	Titles []CatalogUpdatedTitle `json:"titles"`
+}
+
+// CatalogUpdatedTitle lists new updates and DLC released for a title present in the library.
+type CatalogUpdatedTitle struct {
+	TitleID    string               `json:"titleID"`
+	Name       string               `json:"name"`
+	NewUpdates []CatalogVersionData `json:"newUpdates"`
+	NewDLC     []CatalogDLCData     `json:"newDLC"`
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/process"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(env *environment, out *output, args []string) error
+}
+
+var commands = []command{
+	{name: "scan", description: "scan library directories and report skipped files", run: runScan},
+	{name: "list", description: "list library files and their contents", run: runList},
+	{name: "missing-updates", description: "list titles with newer updates available", run: runMissingUpdates},
+	{name: "missing-dlc", description: "list titles with DLCs missing from the library", run: runMissingDLC},
+	{name: "completion", description: "show library completion status", run: runCompletion},
+	{name: "organize", description: "show organize plan, apply it or undo the last organize run", run: runOrganize},
+	{name: "import-catalog", description: "build the catalog from a zip bundle with titles.json and versions.json", run: runImportCatalog},
+	{name: "serve", description: "run the NUT server without the GUI until interrupted", run: runServe},
+	{name: "hash-password", description: "hash a password read from stdin for NUT server users", run: runHashPassword},
+}
+
+type scanResultDTO struct {
+	Added     int            `json:"added"`
+	Changed   int            `json:"changed"`
+	Removed   int            `json:"removed"`
+	Unchanged int            `json:"unchanged"`
+	Skipped   []scanIssueDTO `json:"skipped"`
+}
+
+type scanIssueDTO struct {
+	FilePath string   `json:"filePath"`
+	Reason   string   `json:"reason"`
+	Errors   []string `json:"errors"`
+}
+
+func runScan(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	hard := flags.Bool("hard", false, "process all the files again, ignoring stored metadata")
+	_ = flags.Parse(args)
+
+	summary, err := env.rescan(*hard)
+	if err != nil {
+		return fmt.Errorf("could not scan library: %w", err)
+	}
+	issues, err := env.libraryManager.GetScanIssues()
+	if err != nil {
+		return fmt.Errorf("could not get scan issues: %w", err)
+	}
+
+	result := scanResultDTO{
+		Added:     summary.Added,
+		Changed:   summary.Changed,
+		Removed:   summary.Removed,
+		Unchanged: summary.Unchanged,
+		Skipped:   make([]scanIssueDTO, 0, len(issues)),
+	}
+	rows := [][]string{
+		{"added", "", strconv.Itoa(summary.Added)},
+		{"changed", "", strconv.Itoa(summary.Changed)},
+		{"removed", "", strconv.Itoa(summary.Removed)},
+		{"unchanged", "", strconv.Itoa(summary.Unchanged)},
+	}
+	for _, issue := range issues {
+		errs := issue.ErrorChain()
+		result.Skipped = append(result.Skipped, scanIssueDTO{
+			FilePath: issue.FilePath,
+			Reason:   string(issue.Reason),
+			Errors:   errs,
+		})
+		rows = append(rows, []string{"skipped", issue.FilePath, string(issue.Reason)})
+	}
+	return out.render(result, []string{"STATUS", "FILE", "DETAILS"}, rows)
+}
+
+type listEntryDTO struct {
+	FilePath string `json:"filePath"`
+	Type     string `json:"type"`
+	TitleID  string `json:"titleID"`
+	Version  int    `json:"version"`
+}
+
+func runList(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	entries, err := env.libraryManager.GetEntries()
+	if err != nil {
+		return fmt.Errorf("could not get library entries: %w", err)
+	}
+
+	result := make([]listEntryDTO, 0, len(entries))
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		for _, game := range entry.BaseGames {
+			result = append(result, listEntryDTO{FilePath: entry.FilePath, Type: "base", TitleID: game.ID, Version: game.Version})
+		}
+		for _, update := range entry.Updates {
+			result = append(result, listEntryDTO{FilePath: entry.FilePath, Type: "update", TitleID: update.ID, Version: update.Version})
+		}
+		for _, dlc := range entry.DLCs {
+			result = append(result, listEntryDTO{FilePath: entry.FilePath, Type: "dlc", TitleID: dlc.ID, Version: dlc.Version})
+		}
+	}
+
+	rows := make([][]string, 0, len(result))
+	for _, e := range result {
+		rows = append(rows, []string{e.TitleID, e.Type, strconv.Itoa(e.Version), filepath.Base(e.FilePath)})
+	}
+	return out.render(result, []string{"TITLE ID", "TYPE", "VERSION", "FILE"}, rows)
+}
+
+func runMissingUpdates(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("missing-updates", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	err := env.buildCatalog()
+	if err != nil {
+		return err
+	}
+	entries, err := env.libraryManager.GetEntries()
+	if err != nil {
+		return fmt.Errorf("could not get library entries: %w", err)
+	}
+	missing, err := process.ScanForMissingUpdates(entries, env.db)
+	if err != nil {
+		return fmt.Errorf("could not scan for missing updates: %w", err)
+	}
+
+	if missing == nil {
+		missing = []process.MissingUpdate{}
+	}
+	rows := make([][]string, 0, len(missing))
+	for _, m := range missing {
+		rows = append(rows, []string{
+			m.TitleID,
+			m.Name,
+			strconv.Itoa(m.LocalVersion),
+			m.LocalReadableVersion,
+			strconv.Itoa(m.LatestVersion),
+			m.LatestReleaseDate,
+		})
+	}
+	return out.render(missing, []string{"TITLE ID", "TITLE", "LOCAL VERSION", "LOCAL READABLE", "LATEST VERSION", "RELEASE DATE"}, rows)
+}
+
+type missingDLCTitleDTO struct {
+	TitleID    string          `json:"titleID"`
+	Name       string          `json:"name"`
+	OwnedDLC   int             `json:"ownedDLC"`
+	MissingDLC []missingDLCDTO `json:"missingDLC"`
+}
+
+type missingDLCDTO struct {
+	TitleID string `json:"titleID"`
+	Name    string `json:"name"`
+}
+
+func runMissingDLC(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("missing-dlc", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	err := env.buildCatalog()
+	if err != nil {
+		return err
+	}
+	entries, err := env.libraryManager.GetEntries()
+	if err != nil {
+		return fmt.Errorf("could not get library entries: %w", err)
+	}
+	missing, err := process.ScanForMissingDLC(entries, env.db, env.config.IgnoreDLCTitleIDs)
+	if err != nil {
+		return fmt.Errorf("could not scan for missing DLC: %w", err)
+	}
+
+	result := make([]missingDLCTitleDTO, 0, len(missing))
+	var rows [][]string
+	for _, m := range missing {
+		title := missingDLCTitleDTO{
+			TitleID:  m.TitleID,
+			Name:     m.Name,
+			OwnedDLC: m.OwnedDLC,
+		}
+		for _, dlc := range m.MissingDLC {
+			title.MissingDLC = append(title.MissingDLC, missingDLCDTO{TitleID: dlc.ID, Name: dlc.Name})
+			rows = append(rows, []string{m.TitleID, m.Name, dlc.ID, strings.ReplaceAll(dlc.Name, "\n", " ")})
+		}
+		result = append(result, title)
+	}
+	return out.render(result, []string{"TITLE ID", "TITLE", "DLC ID", "DLC"}, rows)
+}
+
+type completionDTO struct {
+	Titles  completionStatDTO `json:"titles"`
+	DLC     completionStatDTO `json:"dlc"`
+	Updates completionStatDTO `json:"updates"`
+}
+
+type completionStatDTO struct {
+	Owned      int     `json:"owned"`
+	Total      int     `json:"total"`
+	Percentage float64 `json:"percentage"`
+}
+
+func newCompletionStatDTO(stat process.CompletionStat) completionStatDTO {
+	return completionStatDTO{
+		Owned:      stat.Owned,
+		Total:      stat.Total,
+		Percentage: stat.Percentage(),
+	}
+}
+
+func runCompletion(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("completion", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	err := env.buildCatalog()
+	if err != nil {
+		return err
+	}
+	entries, err := env.libraryManager.GetEntries()
+	if err != nil {
+		return fmt.Errorf("could not get library entries: %w", err)
+	}
+	completion, err := process.CalculateLibraryCompletion(entries, env.db, env.config.IgnoreDLCTitleIDs)
+	if err != nil {
+		return fmt.Errorf("could not calculate library completion: %w", err)
+	}
+
+	result := completionDTO{
+		Titles:  newCompletionStatDTO(completion.Titles),
+		DLC:     newCompletionStatDTO(completion.DLC),
+		Updates: newCompletionStatDTO(completion.Updates),
+	}
+	row := func(name string, stat process.CompletionStat) []string {
+		return []string{name, strconv.Itoa(stat.Owned), strconv.Itoa(stat.Total), fmt.Sprintf("%.2f%%", stat.Percentage())}
+	}
+	rows := [][]string{
+		row("titles", completion.Titles),
+		row("dlc", completion.DLC),
+		row("updates", completion.Updates),
+	}
+	return out.render(result, []string{"CONTENT", "OWNED", "TOTAL", "COMPLETION"}, rows)
+}
+
+type importCatalogDTO struct {
+	Bundle  string `json:"bundle"`
+	Entries int    `json:"entries"`
+}
+
+func runImportCatalog(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("import-catalog", flag.ExitOnError)
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import-catalog <bundle.zip>")
+	}
+	bundle := flags.Arg(0)
+
+	sources, err := env.config.AdditionalSources()
+	if err != nil {
+		return err
+	}
+	err = data.ImportCatalogBundle(env.db, filepath.Join(env.workingDirectory, data.CatalogCacheDirectoryName), bundle, sources, func(current, total int, message string) {
+		env.logger.Debugf("catalog import: %v/%v %v", current, total, message)
+	})
+	if err != nil {
+		return fmt.Errorf("could not import catalog: %w", err)
+	}
+	page, err := env.db.GetCatalogEntries(nil, 1, "")
+	if err != nil {
+		return fmt.Errorf("could not load title catalog: %w", err)
+	}
+
+	result := importCatalogDTO{Bundle: bundle, Entries: page.TotalCount}
+	return out.render(result, []string{"BUNDLE", "ENTRIES"}, [][]string{{bundle, strconv.Itoa(page.TotalCount)}})
+}
+
+type organizeOperationDTO struct {
+	Type   string   `json:"type"`
+	From   string   `json:"from,omitempty"`
+	To     string   `json:"to,omitempty"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+type organizeResultDTO struct {
+	Moved  int               `json:"moved"`
+	Errors map[string]string `json:"errors"`
+}
+
+func runOrganize(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("organize", flag.ExitOnError)
+	apply := flags.Bool("apply", false, "execute the plan instead of just showing it")
+	undo := flags.Bool("undo", false, "revert the last organize run")
+	_ = flags.Parse(args)
+
+	journalPath := filepath.Join(env.workingDirectory, process.OrganizeJournalFileName)
+	progress := func(current, total int, message string) {
+		env.logger.Infof("organize: %v/%v %v", current, total, message)
+	}
+
+	if *undo {
+		result, err := process.UndoOrganize(journalPath, progress)
+		if err != nil {
+			return fmt.Errorf("could not undo organize: %w", err)
+		}
+		return renderOrganizeResult(env, out, result)
+	}
+
+	// catalog only provides title names, file metadata is used without it
+	err := env.buildCatalog()
+	if err != nil {
+		env.logger.Warnf("catalog is not available: %v", err)
+	}
+	_, err = env.rescan(false)
+	if err != nil {
+		return fmt.Errorf("could not scan library: %w", err)
+	}
+	entries, err := env.libraryManager.GetEntries()
+	if err != nil {
+		return fmt.Errorf("could not get library entries: %w", err)
+	}
+	plan, err := process.PlanOrganizeLibrary(entries, env.db, env.config.ScanDirectories, env.config.OrganizeOptions.ProcessOptions())
+	if err != nil {
+		return fmt.Errorf("could not plan organize: %w", err)
+	}
+
+	if *apply {
+		result, err := process.ExecuteOrganizePlan(plan, journalPath, progress)
+		if err != nil {
+			return fmt.Errorf("could not organize library: %w", err)
+		}
+		return renderOrganizeResult(env, out, result)
+	}
+
+	result := make([]organizeOperationDTO, 0, len(plan.Operations))
+	rows := make([][]string, 0, len(plan.Operations))
+	for _, operation := range plan.Operations {
+		issues := make([]string, 0, len(operation.Issues))
+		for _, issue := range operation.Issues {
+			issues = append(issues, string(issue))
+		}
+		result = append(result, organizeOperationDTO{
+			Type:   string(operation.Type),
+			From:   operation.From,
+			To:     operation.To,
+			Issues: issues,
+		})
+		rows = append(rows, []string{string(operation.Type), operation.From, operation.To, strings.Join(issues, ",")})
+	}
+	return out.render(result, []string{"OPERATION", "FROM", "TO", "ISSUES"}, rows)
+}
+
+func renderOrganizeResult(env *environment, out *output, result process.OrganizeResult) error {
+	err := env.libraryManager.MoveEntries(result.Moves)
+	if err != nil {
+		return fmt.Errorf("could not update library: %w", err)
+	}
+
+	dto := organizeResultDTO{
+		Moved:  len(result.Moves),
+		Errors: make(map[string]string, len(result.Errors)),
+	}
+	rows := [][]string{{"moved", strconv.Itoa(len(result.Moves)), ""}}
+	paths := make([]string, 0, len(result.Errors))
+	for path, err := range result.Errors {
+		dto.Errors[path] = err.Error()
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		rows = append(rows, []string{"error", path, dto.Errors[path]})
+	}
+	return out.render(dto, []string{"STATUS", "FILE", "DETAILS"}, rows)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/keys"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/FrozenPear42/switch-library-manager/utils"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path/filepath"
+)
+
+// environment holds all the services shared by CLI commands. It uses the same files as the GUI.
+type environment struct {
+	workingDirectory string
+	logger           *zap.SugaredLogger
+	config           settings.AppSettings
+	db               *storage.Database
+	libraryManager   data.LibraryManager
+}
+
+func newEnvironment(workingDirectory string, debug bool) (*environment, error) {
+	if workingDirectory == "" {
+		dir, err := utils.GetExecDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		workingDirectory = dir
+	}
+
+	logger, err := createLogger(debug)
+	if err != nil {
+		return nil, err
+	}
+	sugar := logger.Sugar()
+
+	configurationProvider, err := settings.NewConfigurationProvider(filepath.Join(workingDirectory, "settings.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize config provider: %w", err)
+	}
+	err = configurationProvider.LoadFromFile()
+	if err != nil {
+		if !errors.Is(err, settings.ErrConfigurationFileNotFound) {
+			return nil, fmt.Errorf("failed to load configuration file: %w", err)
+		}
+		sugar.Info("Configuration file not found. Using default configuration. Creating new configuration file.")
+		err = configurationProvider.SaveToFile()
+		if err != nil {
+			return nil, fmt.Errorf("could not save new configuration file: %w", err)
+		}
+	}
+	config := configurationProvider.GetCurrentConfig()
+
+	database, err := storage.NewDatabase(filepath.Join(workingDirectory, "slm_full.db"))
+	if errors.Is(err, storage.ErrDatabaseInUse) {
+		return nil, fmt.Errorf("failed to initialize database, close the application using it first: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	keyProvider := keys.NewKeyProvider()
+	err = keyProvider.LoadFromFile([]string{
+		config.ProdKeysPath,
+		filepath.Join(workingDirectory, "prod.keys"),
+		"${HOME}/.switch/prod.keys",
+	})
+	if err != nil {
+		sugar.Warnf("keys file was not found, deep scan is disabled, library will be based on file tags: %v", err)
+	}
+
+	libraryManager := data.NewLibraryManager(sugar, database, keyProvider, config.ScanDirectories, config.ScanRecursive, config.ScanWorkers)
+
+	return &environment{
+		workingDirectory: workingDirectory,
+		logger:           sugar,
+		config:           config,
+		db:               database,
+		libraryManager:   libraryManager,
+	}, nil
+}
+
+func (e *environment) Close() error {
+	_ = e.logger.Sync()
+	return e.db.Close()
+}
+
+// buildCatalog makes sure the catalog is downloaded and loaded. Previously stored catalog is used when it cannot
+// be built, e.g. when offline.
+func (e *environment) buildCatalog() error {
+	sources, err := e.config.AdditionalSources()
+	if err != nil {
+		return err
+	}
+	titlesSource, versionsSource := e.config.CatalogSources()
+	err = data.BuildCatalog(e.db, filepath.Join(e.workingDirectory, data.CatalogCacheDirectoryName), titlesSource, versionsSource, sources, func(current, total int, message string) {
+		e.logger.Debugf("catalog: %v/%v %v", current, total, message)
+	})
+	if err != nil {
+		page, pageErr := e.db.GetCatalogEntries(nil, 1, "")
+		if pageErr != nil || page.TotalCount == 0 {
+			return fmt.Errorf("could not build title catalog: %w", err)
+		}
+		e.logger.Warnf("could not refresh title catalog, using stored one: %v", err)
+		return nil
+	}
+	_, err = e.db.GetCatalogEntries(nil, 1, "")
+	if err != nil {
+		return fmt.Errorf("could not load title catalog: %w", err)
+	}
+	return nil
+}
+
+func (e *environment) rescan(hard bool) (data.RescanSummary, error) {
+	return e.libraryManager.Rescan(hard, func(current, total int, message string) {
+		e.logger.Debugf("processing: %v/%v, %v", current, total, message)
+	})
+}
+
+func createLogger(debug bool) (*zap.Logger, error) {
+	var config zap.Config
+	if debug {
+		config = zap.NewDevelopmentConfig()
+	} else {
+		config = zap.NewProductionConfig()
+		config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+		config.Encoding = "console"
+		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+
+	// stdout is reserved for command output
+	config.OutputPaths = []string{"stderr"}
+	config.ErrorOutputPaths = []string{"stderr"}
+	logger, err := config.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+	zap.ReplaceGlobals(logger)
+	return logger, nil
+}
@@ -0,0 +1,66 @@
+// Command slm-cli runs library scans and reports without the GUI, e.g. on a headless NAS.
+// It shares settings.yaml, prod.keys and the database with the GUI application.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	flags := flag.NewFlagSet("slm-cli", flag.ExitOnError)
+	workingDirectory := flags.String("workdir", "", "directory with settings.yaml, prod.keys and the database (defaults to executable directory)")
+	format := flags.String("format", string(outputFormatTable), "output format: table, json or csv")
+	debug := flags.Bool("debug", false, "enable debug logs")
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: slm-cli [flags] <command> [command flags]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(out, "  %-16s %s\n", c.name, c.description)
+		}
+		fmt.Fprintf(out, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var selected *command
+	for i := range commands {
+		if commands[i].name == flags.Arg(0) {
+			selected = &commands[i]
+		}
+	}
+	if selected == nil {
+		fmt.Fprintf(os.Stderr, "unknown command: %v\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	out, err := newOutput(*format, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	env, err := newEnvironment(*workingDirectory, *debug)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = selected.run(env, out, flags.Args()[1:])
+	closeErr := env.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if closeErr != nil {
+		fmt.Fprintln(os.Stderr, closeErr)
+		os.Exit(1)
+	}
+}
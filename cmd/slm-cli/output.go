@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type outputFormat string
+
+const (
+	outputFormatTable outputFormat = "table"
+	outputFormatJSON  outputFormat = "json"
+	outputFormatCSV   outputFormat = "csv"
+)
+
+type output struct {
+	format outputFormat
+	writer io.Writer
+}
+
+func newOutput(format string, writer io.Writer) (*output, error) {
+	switch outputFormat(format) {
+	case outputFormatTable, outputFormatJSON, outputFormatCSV:
+		return &output{format: outputFormat(format), writer: writer}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %v", format)
+	}
+}
+
+// render writes value as JSON or rows as a table or CSV, depending on selected format.
+func (o *output) render(value any, header []string, rows [][]string) error {
+	switch o.format {
+	case outputFormatJSON:
+		encoder := json.NewEncoder(o.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case outputFormatCSV:
+		w := csv.NewWriter(o.writer)
+		err := w.Write(header)
+		if err != nil {
+			return err
+		}
+		err = w.WriteAll(rows)
+		if err != nil {
+			return err
+		}
+		return w.Error()
+	}
+
+	w := tabwriter.NewWriter(o.writer, 0, 0, 2, ' ', 0)
+	_, err := fmt.Fprintln(w, strings.Join(header, "\t"))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		_, err = fmt.Fprintln(w, strings.Join(row, "\t"))
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
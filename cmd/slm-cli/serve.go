@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/nut"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	shutdownTimeout = 10 * time.Second
+)
+
+type logReporter struct {
+	logger *zap.SugaredLogger
+}
+
+func (r *logReporter) ReportProgress(filePath string, downloaded, total int64) {
+	r.logger.Debugf("file download progress: %s %d/%d", filePath, downloaded, total)
+}
+
+// runServe starts the catalog, the library manager and the NUT server only, and serves until SIGINT or SIGTERM.
+func runServe(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	host := flags.String("host", env.config.NUTSettings.Host, "NUT server host")
+	port := flags.Int("port", env.config.NUTSettings.Port, "NUT server port")
+	_ = flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := env.buildCatalog()
+	if err != nil {
+		env.logger.Warnf("catalog is not available: %v", err)
+	}
+
+	summary, err := env.rescan(false)
+	if err != nil {
+		return fmt.Errorf("could not scan library: %w", err)
+	}
+	env.logger.Infof("library scanned: %+v", summary)
+
+	if env.config.WatchDirectories {
+		watcher := data.NewLibraryWatcher(env.logger, env.libraryManager, env.config.ScanDirectories, env.config.ScanRecursive, func(paths []string) {
+			env.logger.Infof("library changed: %v", paths)
+		})
+		err = watcher.Start()
+		if err != nil {
+			return fmt.Errorf("could not start library watcher: %w", err)
+		}
+		defer watcher.Close()
+	}
+
+	updater := data.NewCatalogUpdater(env.logger, env.db, env.libraryManager, env.config.CatalogUpdateInterval, env.buildCatalog, func(changes []data.CatalogChange) {
+		for _, change := range changes {
+			env.logger.Infof("new content for %v %v: %d updates, %d DLC", change.TitleID, change.Name, len(change.NewUpdates), len(change.NewDLCs))
+		}
+	})
+	updater.Start()
+	defer updater.Close()
+
+	nutSettings := env.config.NUTSettings
+	nutSettings.Host = *host
+	nutSettings.Port = *port
+	server := nut.NewServer(nutSettings, env.config.ScanDirectories, env.libraryManager, env.db, env.db, nut.NewImageCache(filepath.Join(env.workingDirectory, nut.ImageCacheDirectoryName)), &logReporter{logger: env.logger})
+	httpServer, err := server.Listen()
+	if err != nil {
+		return fmt.Errorf("could not start NUT server: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "NUT server listening at %v\n", httpServer.Addr)
+
+	<-ctx.Done()
+	fmt.Fprintln(os.Stderr, "shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+type hashPasswordDTO struct {
+	PasswordHash string `json:"passwordHash"`
+}
+
+// runHashPassword prints hash of a password read from stdin, to be put in NUT user settings.
+func runHashPassword(env *environment, out *output, args []string) error {
+	flags := flag.NewFlagSet("hash-password", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not read password: %w", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return errors.New("password is empty")
+	}
+
+	hash, err := nut.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("could not hash password: %w", err)
+	}
+	return out.render(hashPasswordDTO{PasswordHash: hash}, []string{"PASSWORD HASH"}, [][]string{{hash}})
+}
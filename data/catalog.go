@@ -1,6 +1,7 @@
 package data
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,7 +11,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -39,6 +42,14 @@ type titlesJsonEntry struct {
 	Languages   []string    `json:"languages"`
 }
 
+// languages returns languages of the title, older files have a single language only.
+func (e titlesJsonEntry) languages() []string {
+	if len(e.Languages) == 0 && e.Language != "" {
+		return []string{e.Language}
+	}
+	return e.Languages
+}
+
 type titlesJson map[string]titlesJsonEntry
 
 type versionsJsonEntry map[string]string
@@ -46,16 +57,64 @@ type versionsJsonEntry map[string]string
 type versionsJson map[string]versionsJsonEntry
 
 const (
-	dialTimeout = 3 * time.Second
+	// CatalogCacheDirectoryName is name of the folder inside working directory holding downloaded catalog files
+	CatalogCacheDirectoryName = "catalog"
+
+	dialTimeout           = 3 * time.Second
+	titlesCacheFileName   = "titles.json"
+	versionsCacheFileName = "versions.json"
+	downloadFileSuffix    = ".download"
+	// catalogFormatVersion has to be bumped when data stored in catalog entries changes
+	catalogFormatVersion = 1
 )
 
 var (
-	ErrNoUpdateAvailable = errors.New("no update available")
+	ErrNoUpdateAvailable    = errors.New("no update available")
+	ErrInvalidCatalogBundle = errors.New("invalid catalog bundle")
 )
 
-func BuildCatalog(db storage.SwitchDatabaseCatalog, titlesURL, versionsURL string, callback ProgressCallback) error {
-	// TODO: all or nothing - if only one download fails redownload the other one
+// catalogFile is a catalog data file that was either downloaded or is reused from the local cache as it did not change.
+type catalogFile struct {
+	cachePath    string
+	downloadPath string
+	etag         string
+}
+
+func (f *catalogFile) changed() bool {
+	return f.downloadPath != ""
+}
+
+func (f *catalogFile) path() string {
+	if f.changed() {
+		return f.downloadPath
+	}
+	return f.cachePath
+}
 
+// commit replaces the cached copy with the downloaded file.
+func (f *catalogFile) commit() error {
+	if !f.changed() {
+		return nil
+	}
+	err := os.Rename(f.downloadPath, f.cachePath)
+	if err != nil {
+		return fmt.Errorf("could not update cached file: %w", err)
+	}
+	f.downloadPath = ""
+	return nil
+}
+
+// discard removes the downloaded file if it was not committed.
+func (f *catalogFile) discard() {
+	if f.changed() {
+		_ = os.Remove(f.downloadPath)
+	}
+}
+
+// BuildCatalog refreshes the catalog using conditional requests. Files that did not change are read from the
+// cache directory. The catalog is replaced only if both files parse correctly. Besides HTTP URLs both sources can be
+// file:// URLs or local file paths. Additional sources are merged into the main catalog according to their priority.
+func BuildCatalog(db storage.SwitchDatabaseCatalog, cacheDirectory, titlesURL, versionsURL string, sources []PrioritizedCatalogSource, callback ProgressCallback) error {
 	totalSteps := 7
 
 	callback(1, totalSteps, "Preparing")
@@ -63,76 +122,196 @@ func BuildCatalog(db storage.SwitchDatabaseCatalog, titlesURL, versionsURL strin
 	if err != nil {
 		return fmt.Errorf("failed to fetch catalog metadata: %w", err)
 	}
+	err = os.MkdirAll(cacheDirectory, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog cache dir: %w", err)
+	}
+
+	callback(2, totalSteps, "Downloading titles data...")
+	titles, err := fetchCatalogFile(titlesURL, filepath.Join(cacheDirectory, titlesCacheFileName), metadata.TitlesETag)
+	if err != nil {
+		return fmt.Errorf("failed to download switch titles: %w", err)
+	}
+	defer titles.discard()
+
+	callback(3, totalSteps, "Downloading versions data...")
+	versions, err := fetchCatalogFile(versionsURL, filepath.Join(cacheDirectory, versionsCacheFileName), metadata.VersionsETag)
+	if err != nil {
+		return fmt.Errorf("failed to download switch versions: %w", err)
+	}
+	defer versions.discard()
+
+	callback(4, totalSteps, "Loading additional sources...")
+	loadedSources, fingerprint := loadCatalogSources(sources)
 
-	// TODO: temp measure
-	if metadata.TitlesETag != "" || metadata.VersionsETag != "" {
-		callback(2, 2, "Done!")
+	if !titles.changed() && !versions.changed() && fingerprint == metadata.SourcesFingerprint && metadata.FormatVersion == catalogFormatVersion {
+		zap.S().Infof("catalog is up to date")
+		callback(totalSteps, totalSteps, "Done...")
 		return nil
 	}
 
-	tmpDir, err := os.MkdirTemp("", "slm")
+	callback(5, totalSteps, "Processing data...")
+	mainSource := &tinfoilCatalogSource{name: "main", titlesLocation: titles.path(), versionsLocation: versions.path()}
+	mainEntries, err := mainSource.Load()
 	if err != nil {
-		return fmt.Errorf("failed to create tmp dir: %w", err)
+		return fmt.Errorf("failed to parse catalog data: %w", err)
 	}
+	entries := mergeCatalogSources(mainEntries, loadedSources)
 
-	zap.S().Infof("TMP dir: %v", tmpDir)
+	callback(6, totalSteps, "Updating local DB...")
+	// cache is updated first, so a failed DB update results in a full download next time
+	err = titles.commit()
+	if err != nil {
+		return err
+	}
+	err = versions.commit()
+	if err != nil {
+		return err
+	}
+	err = db.ReplaceCatalog(entries, storage.CatalogMetadata{
+		FormatVersion:      catalogFormatVersion,
+		VersionsETag:       versions.etag,
+		TitlesETag:         titles.etag,
+		SourcesFingerprint: fingerprint,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update database: %w", err)
+	}
+	callback(7, totalSteps, "Done...")
+	return nil
+}
 
-	callback(2, totalSteps, "Downloading titles data...")
-	titlesPath := filepath.Join(tmpDir, "titles.json")
-	titlesEtag := metadata.TitlesETag
+// fetchCatalogFile downloads a file unless it matches the etag and a cached copy is present.
+// Source is either an HTTP URL, a file:// URL or a local file path.
+func fetchCatalogFile(source, cachePath, etag string) (*catalogFile, error) {
+	if _, err := os.Stat(cachePath); err != nil {
+		// nothing to fall back to
+		etag = ""
+	}
+	downloadPath := cachePath + downloadFileSuffix
+	var newEtag string
+	var err error
+	if localPath, ok := localCatalogPath(source); ok {
+		newEtag, err = copyFileWithEtag(localPath, downloadPath, etag)
+	} else {
+		newEtag, err = downloadFileWithEtag(source, downloadPath, etag)
+	}
+	if errors.Is(err, ErrNoUpdateAvailable) {
+		return &catalogFile{cachePath: cachePath, etag: etag}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &catalogFile{cachePath: cachePath, downloadPath: downloadPath, etag: newEtag}, nil
+}
 
-	titlesFile, newTitlesEtag, err := downloadFileWithEtag(titlesURL, titlesPath, titlesEtag)
-	if err != nil && !errors.Is(err, ErrNoUpdateAvailable) {
-		return fmt.Errorf("failed to download switch titles: %w", err)
+// ImportCatalogBundle builds the catalog from a zip archive containing titles.json and versions.json files.
+func ImportCatalogBundle(db storage.SwitchDatabaseCatalog, cacheDirectory, bundlePath string, sources []PrioritizedCatalogSource, callback ProgressCallback) error {
+	archive, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCatalogBundle, err)
+	}
+	defer archive.Close()
+
+	tmpDir, err := os.MkdirTemp("", "slm-catalog")
+	if err != nil {
+		return fmt.Errorf("failed to create tmp dir: %w", err)
 	}
-	defer func() {
-		if titlesFile != nil {
-			titlesFile.Close()
-			os.Remove(titlesPath)
+	defer os.RemoveAll(tmpDir)
+
+	extracted := make(map[string]string)
+	for _, file := range archive.File {
+		name := path.Base(file.Name)
+		if file.FileInfo().IsDir() || (name != titlesCacheFileName && name != versionsCacheFileName) {
+			continue
 		}
-	}()
+		extractedPath := filepath.Join(tmpDir, name)
+		err = extractZipFile(file, extractedPath)
+		if err != nil {
+			return err
+		}
+		extracted[name] = extractedPath
+	}
+	titlesPath, ok := extracted[titlesCacheFileName]
+	if !ok {
+		return fmt.Errorf("%w: missing %v", ErrInvalidCatalogBundle, titlesCacheFileName)
+	}
+	versionsPath, ok := extracted[versionsCacheFileName]
+	if !ok {
+		return fmt.Errorf("%w: missing %v", ErrInvalidCatalogBundle, versionsCacheFileName)
+	}
+	return BuildCatalog(db, cacheDirectory, titlesPath, versionsPath, sources, callback)
+}
 
-	callback(3, totalSteps, "Downloading versions data...")
-	versionsPath := filepath.Join(tmpDir, "versions.json")
-	versionsEtag := metadata.VersionsETag
+func extractZipFile(file *zip.File, destination string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("could not open %v: %w", file.Name, err)
+	}
+	defer reader.Close()
+	f, err := os.Create(destination)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	defer f.Close()
+	_, err = io.Copy(f, reader)
+	if err != nil {
+		return fmt.Errorf("could not extract %v: %w", file.Name, err)
+	}
+	return nil
+}
 
-	versionsFile, newVersionsEtag, err := downloadFileWithEtag(versionsURL, versionsPath, versionsEtag)
-	if err != nil && !errors.Is(err, ErrNoUpdateAvailable) {
-		return fmt.Errorf("failed to download switch titles: %w", err)
+// localCatalogPath returns the file path for file:// URLs and plain paths, false for HTTP URLs.
+func localCatalogPath(source string) (string, bool) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return source, true
 	}
-	defer func() {
-		if versionsFile != nil {
-			versionsFile.Close()
-			os.Remove(versionsPath)
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return "", false
+	case "file":
+		localPath := u.Path
+		// file:///C:/catalog/titles.json
+		if len(localPath) > 0 && localPath[0] == '/' && filepath.VolumeName(localPath[1:]) != "" {
+			localPath = localPath[1:]
 		}
-	}()
+		return filepath.FromSlash(localPath), true
+	}
+	return source, true
+}
 
-	callback(4, totalSteps, "Processing data...")
-	entries, err := parseCatalogFiles(titlesFile, versionsFile)
+// copyFileWithEtag copies a local file to path unless it did not change, returns new etag based on size and
+// modification time. ErrNoUpdateAvailable is returned if the file did not change.
+func copyFileWithEtag(source string, path string, etag string) (string, error) {
+	info, err := os.Stat(source)
 	if err != nil {
-		return fmt.Errorf("failed to parse catalog data: %w", err)
+		return "", fmt.Errorf("could not read file: %w", err)
+	}
+	newEtag := fmt.Sprintf("file-%x-%x", info.Size(), info.ModTime().UnixNano())
+	if newEtag == etag {
+		return "", ErrNoUpdateAvailable
 	}
-	zap.S().Infof("Processed data: %v", tmpDir)
 
-	callback(5, totalSteps, "Updating local DB...")
-	err = db.ClearCatalog()
+	in, err := os.Open(source)
 	if err != nil {
-		return fmt.Errorf("failed to clear catalog: %w", err)
+		return "", fmt.Errorf("could not open file: %w", err)
 	}
-	err = db.AddCatalogEntries(entries)
+	defer in.Close()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to update database: %w", err)
+		return "", fmt.Errorf("could not open file: %w", err)
+	}
+	_, err = io.Copy(f, in)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
 	}
-	callback(6, totalSteps, "Finishing up...")
-	err = db.UpdateCatalogMetadata(storage.CatalogMetadata{
-		VersionsETag: newVersionsEtag,
-		TitlesETag:   newTitlesEtag,
-	})
 	if err != nil {
-		return fmt.Errorf("failed to update catalog metadata: %w", err)
+		_ = os.Remove(path)
+		return "", fmt.Errorf("could not copy file: %w", err)
 	}
-	callback(7, totalSteps, "Done...")
-	return nil
+	return newEtag, nil
 }
 
 func parseCatalogFiles(titlesFile, versionsFile io.Reader) (map[string]storage.CatalogEntry, error) {
@@ -180,6 +359,10 @@ func parseCatalogFiles(titlesFile, versionsFile io.Reader) (map[string]storage.C
 				Key:         data.Key,
 				ReleaseDate: parseReleaseDate(data.ReleaseDate),
 				Publisher:   data.Publisher,
+				Developer:   data.Developer,
+				Category:    data.Category,
+				Languages:   data.languages(),
+				Size:        data.Size,
 				IsDemo:      data.IsDemo,
 				Screenshots: data.Screenshots,
 			}
@@ -224,27 +407,29 @@ func parseCatalogFiles(titlesFile, versionsFile io.Reader) (map[string]storage.C
 					Key:         data.Key,
 					ReleaseDate: parseReleaseDate(data.ReleaseDate),
 					Publisher:   data.Publisher,
+					Developer:   data.Developer,
+					Category:    data.Category,
+					Languages:   data.languages(),
+					Size:        data.Size,
 					Screenshots: data.Screenshots,
 				},
 			})
 		}
 		entries[mainTitleId] = entry
 	}
-	for k, v := range entries {
-		if v.ID == "" || v.Name == "" {
-			delete(entries, k)
-		}
-	}
 	return entries, nil
 }
 
-// downloadFileWithEtag  downloads a file from a given url using etag header, returns (file, new etag, error). Returned file has to ble closed by caller.
-func downloadFileWithEtag(url string, path string, etag string) (*os.File, string, error) {
+// downloadFileWithEtag downloads a file from a given url to path using etag header, returns new etag.
+// ErrNoUpdateAvailable is returned if the file did not change.
+func downloadFileWithEtag(url string, path string, etag string) (string, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, "", err
+		return "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
 	}
-	req.Header.Set("If-None-Match", etag)
 
 	client := http.Client{
 		Transport: &http.Transport{
@@ -255,36 +440,32 @@ func downloadFileWithEtag(url string, path string, etag string) (*os.File, strin
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	newEtag := resp.Header.Get("Etag")
-
+	if resp.StatusCode == http.StatusNotModified {
+		return "", ErrNoUpdateAvailable
+	}
 	if resp.StatusCode != http.StatusOK {
-		// FIXME: magic, fix it
-		if resp.StatusCode < 400 {
-			return nil, "", ErrNoUpdateAvailable
-		}
-		return nil, "", fmt.Errorf("got a non 200 response - %v", resp.Status)
+		return "", fmt.Errorf("got a non 200 response - %v", resp.Status)
 	}
 
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
 	if err != nil {
-		return nil, "", fmt.Errorf("could not open file: %w", err)
+		return "", fmt.Errorf("could not open file: %w", err)
 	}
-
 	_, err = io.Copy(f, resp.Body)
-	if err != nil {
-		return nil, "", fmt.Errorf("could not download (copy): %w", err)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
 	}
-
-	_, err = f.Seek(0, 0)
 	if err != nil {
-		return nil, "", fmt.Errorf("could not download (seek): %w", err)
+		_ = os.Remove(path)
+		return "", fmt.Errorf("could not download (copy): %w", err)
 	}
 
-	return f, newEtag, nil
+	return resp.Header.Get("Etag"), nil
 }
 
 func parseReleaseDate(date int) string {
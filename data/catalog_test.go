@@ -0,0 +1,256 @@
+package data
+
+import (
+	"archive/zip"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const (
+	testCatalogTitlesPath   = "testdata/catalog/titles.json"
+	testCatalogVersionsPath = "testdata/catalog/versions.json"
+
+	testTitlesJson = `{
+		"0100000000010000": {"id": "0100000000010000", "name": "Game", "version": 0, "region": "US", "releaseDate": 20200101},
+		"0100000000010800": {"id": "0100000000010800", "version": 65536}
+	}`
+	testVersionsJson        = `{"0100000000010000": {"65536": "2020-02-01"}}`
+	testUpdatedVersionsJson = `{"0100000000010000": {"65536": "2020-02-01", "131072": "2020-03-01"}}`
+)
+
+type catalogTestFile struct {
+	content string
+	etag    string
+}
+
+// catalogTestServer serves titles and versions files honoring If-None-Match and counts full responses.
+type catalogTestServer struct {
+	mutex     sync.Mutex
+	files     map[string]catalogTestFile
+	downloads map[string]int
+}
+
+func newCatalogTestServer() *catalogTestServer {
+	return &catalogTestServer{
+		files: map[string]catalogTestFile{
+			"/titles.json":   {content: testTitlesJson, etag: `"t1"`},
+			"/versions.json": {content: testVersionsJson, etag: `"v1"`},
+		},
+		downloads: make(map[string]int),
+	}
+}
+
+func (s *catalogTestServer) set(path string, file catalogTestFile) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.files[path] = file
+}
+
+func (s *catalogTestServer) resetDownloads() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	downloads := s.downloads
+	s.downloads = make(map[string]int)
+	return downloads
+}
+
+func (s *catalogTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	file, ok := s.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("If-None-Match") == file.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.downloads[r.URL.Path]++
+	w.Header().Set("Etag", file.etag)
+	_, _ = w.Write([]byte(file.content))
+}
+
+func TestBuildCatalog(t *testing.T) {
+	handler := newCatalogTestServer()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	cacheDirectory := filepath.Join(t.TempDir(), CatalogCacheDirectoryName)
+	build := func() error {
+		return BuildCatalog(db, cacheDirectory, server.URL+"/titles.json", server.URL+"/versions.json", nil, func(current, total int, message string) {})
+	}
+	catalogVersions := func() []storage.CatalogEntryVersion {
+		page, err := db.GetCatalogEntries(nil, 0, "")
+		assert.Nil(t, err)
+		if !assert.Len(t, page.Data, 1) {
+			return nil
+		}
+		return page.Data[0].Versions
+	}
+
+	// first run downloads everything
+	assert.Nil(t, build())
+	assert.Equal(t, map[string]int{"/titles.json": 1, "/versions.json": 1}, handler.resetDownloads())
+	assert.Len(t, catalogVersions(), 1)
+	metadata, err := db.GetCatalogMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, storage.CatalogMetadata{FormatVersion: catalogFormatVersion, TitlesETag: `"t1"`, VersionsETag: `"v1"`}, metadata)
+	assert.FileExists(t, filepath.Join(cacheDirectory, titlesCacheFileName))
+	assert.FileExists(t, filepath.Join(cacheDirectory, versionsCacheFileName))
+
+	// nothing changed
+	assert.Nil(t, build())
+	assert.Empty(t, handler.resetDownloads())
+	assert.Len(t, catalogVersions(), 1)
+
+	// catalog stored in an older format is rebuilt from cache
+	metadata.FormatVersion = 0
+	assert.Nil(t, db.UpdateCatalogMetadata(metadata))
+	assert.Nil(t, build())
+	assert.Empty(t, handler.resetDownloads())
+	metadata, err = db.GetCatalogMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, catalogFormatVersion, metadata.FormatVersion)
+
+	// only versions changed, titles are read from cache
+	handler.set("/versions.json", catalogTestFile{content: testUpdatedVersionsJson, etag: `"v2"`})
+	assert.Nil(t, build())
+	assert.Equal(t, map[string]int{"/versions.json": 1}, handler.resetDownloads())
+	assert.Len(t, catalogVersions(), 2)
+	metadata, err = db.GetCatalogMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, storage.CatalogMetadata{FormatVersion: catalogFormatVersion, TitlesETag: `"t1"`, VersionsETag: `"v2"`}, metadata)
+
+	// broken titles leave catalog, metadata and cache untouched
+	handler.set("/titles.json", catalogTestFile{content: `{"broken`, etag: `"t2"`})
+	assert.NotNil(t, build())
+	assert.Len(t, catalogVersions(), 2)
+	metadata, err = db.GetCatalogMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, `"t1"`, metadata.TitlesETag)
+	cached, err := os.ReadFile(filepath.Join(cacheDirectory, titlesCacheFileName))
+	assert.Nil(t, err)
+	assert.Equal(t, testTitlesJson, string(cached))
+	assert.NoFileExists(t, filepath.Join(cacheDirectory, titlesCacheFileName+downloadFileSuffix))
+	handler.resetDownloads()
+
+	// missing cached copy forces a full download
+	handler.set("/titles.json", catalogTestFile{content: testTitlesJson, etag: `"t1"`})
+	assert.Nil(t, os.Remove(filepath.Join(cacheDirectory, versionsCacheFileName)))
+	assert.Nil(t, build())
+	assert.Equal(t, map[string]int{"/versions.json": 1}, handler.resetDownloads())
+	assert.Len(t, catalogVersions(), 2)
+}
+
+func TestBuildCatalogDownloadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = BuildCatalog(db, t.TempDir(), server.URL+"/titles.json", server.URL+"/versions.json", nil, func(current, total int, message string) {})
+	assert.NotNil(t, err)
+	metadata, err := db.GetCatalogMetadata()
+	assert.Nil(t, err)
+	assert.Equal(t, storage.CatalogMetadata{}, metadata)
+}
+
+func assertTestCatalog(t *testing.T, db storage.SwitchDatabaseCatalog) {
+	page, err := db.GetCatalogEntries(nil, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page.TotalCount)
+
+	entry, err := db.GetCatalogEntryByIDPrefix("010000000001")
+	assert.Nil(t, err)
+	assert.Equal(t, "Test Game", entry.Name)
+	assert.Equal(t, "2020-01-01", entry.ReleaseDate)
+	assert.Len(t, entry.Versions, 2)
+	assert.Equal(t, 131072, entry.RecentUpdate.Version)
+	assert.Len(t, entry.DLCs, 1)
+}
+
+func TestBuildCatalogFromFiles(t *testing.T) {
+	titlesPath, err := filepath.Abs(testCatalogTitlesPath)
+	assert.Nil(t, err)
+	versionsPath, err := filepath.Abs(testCatalogVersionsPath)
+	assert.Nil(t, err)
+	versionsURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(versionsPath)}).String()
+	if filepath.VolumeName(versionsPath) != "" {
+		versionsURL = "file:///" + filepath.ToSlash(versionsPath)
+	}
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	cacheDirectory := t.TempDir()
+
+	var steps int
+	build := func() error {
+		steps = 0
+		return BuildCatalog(db, cacheDirectory, titlesPath, versionsURL, nil, func(current, total int, message string) {
+			steps++
+		})
+	}
+
+	assert.Nil(t, build())
+	assertTestCatalog(t, db)
+	metadata, err := db.GetCatalogMetadata()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, metadata.TitlesETag)
+	assert.NotEmpty(t, metadata.VersionsETag)
+
+	// unchanged files are not processed again
+	assert.Nil(t, build())
+	assert.Equal(t, 5, steps)
+
+	err = BuildCatalog(db, cacheDirectory, filepath.Join(t.TempDir(), "missing.json"), versionsURL, nil, func(current, total int, message string) {})
+	assert.NotNil(t, err)
+	assertTestCatalog(t, db)
+}
+
+func TestImportCatalogBundle(t *testing.T) {
+	bundlePath := filepath.Join(t.TempDir(), "catalog.zip")
+	f, err := os.Create(bundlePath)
+	assert.Nil(t, err)
+	archive := zip.NewWriter(f)
+	for name, source := range map[string]string{
+		"catalog/titles.json":   testCatalogTitlesPath,
+		"catalog/versions.json": testCatalogVersionsPath,
+	} {
+		content, err := os.ReadFile(source)
+		assert.Nil(t, err)
+		w, err := archive.Create(name)
+		assert.Nil(t, err)
+		_, err = w.Write(content)
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, archive.Close())
+	assert.Nil(t, f.Close())
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = ImportCatalogBundle(db, t.TempDir(), bundlePath, nil, func(current, total int, message string) {})
+	assert.Nil(t, err)
+	assertTestCatalog(t, db)
+
+	err = ImportCatalogBundle(db, t.TempDir(), testCatalogTitlesPath, nil, func(current, total int, message string) {})
+	assert.ErrorIs(t, err, ErrInvalidCatalogBundle)
+}
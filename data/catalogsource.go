@@ -0,0 +1,458 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type CatalogSourceType string
+
+const (
+	// CatalogSourceTypeTinfoil reads titles.json and versions.json files in tinfoil.media schema
+	CatalogSourceTypeTinfoil CatalogSourceType = "tinfoil"
+	// CatalogSourceTypeJSON reads a JSON array of catalogSourceEntry
+	CatalogSourceTypeJSON CatalogSourceType = "json"
+	// CatalogSourceTypeCSV reads a CSV file with a header naming catalogSourceEntry fields
+	CatalogSourceTypeCSV CatalogSourceType = "csv"
+)
+
+var (
+	ErrUnknownCatalogSourceType = errors.New("unknown catalog source type")
+)
+
+// CatalogSource provides catalog entries keyed by title ID prefix. Entries may be partial, only non-empty fields
+// are merged into the catalog.
+type CatalogSource interface {
+	Name() string
+	Load() (map[string]storage.CatalogEntry, error)
+}
+
+// PrioritizedCatalogSource is an additional catalog source. For every field the value of the source with the highest
+// priority wins, the main catalog has priority 0, so negative priorities only fill in missing data.
+type PrioritizedCatalogSource struct {
+	CatalogSource
+	Priority int
+}
+
+// NewCatalogSource creates a source of the given type. Locations are HTTP URLs, file:// URLs or local paths,
+// versionsLocation is used by tinfoil sources only.
+func NewCatalogSource(sourceType CatalogSourceType, name, location, versionsLocation string) (CatalogSource, error) {
+	switch sourceType {
+	case CatalogSourceTypeTinfoil:
+		return &tinfoilCatalogSource{name: name, titlesLocation: location, versionsLocation: versionsLocation}, nil
+	case CatalogSourceTypeJSON:
+		return &jsonCatalogSource{name: name, location: location}, nil
+	case CatalogSourceTypeCSV:
+		return &csvCatalogSource{name: name, location: location}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownCatalogSourceType, sourceType)
+}
+
+type tinfoilCatalogSource struct {
+	name             string
+	titlesLocation   string
+	versionsLocation string
+}
+
+func (s *tinfoilCatalogSource) Name() string {
+	return s.name
+}
+
+func (s *tinfoilCatalogSource) Load() (map[string]storage.CatalogEntry, error) {
+	titles, err := openCatalogLocation(s.titlesLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer titles.Close()
+	versions, err := openCatalogLocation(s.versionsLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer versions.Close()
+	return parseCatalogFiles(titles, versions)
+}
+
+// catalogSourceEntry is a single title, update or DLC in JSON and CSV sources. Versions map update versions
+// to release dates. CSV sources separate category and languages values with "|".
+type catalogSourceEntry struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Region      string            `json:"region"`
+	Publisher   string            `json:"publisher"`
+	Developer   string            `json:"developer"`
+	Category    []string          `json:"category"`
+	Languages   []string          `json:"languages"`
+	Size        int               `json:"size"`
+	ReleaseDate string            `json:"releaseDate"`
+	Description string            `json:"description"`
+	Intro       string            `json:"intro"`
+	IconURL     string            `json:"iconUrl"`
+	BannerURL   string            `json:"bannerUrl"`
+	Screenshots []string          `json:"screenshots"`
+	IsDemo      bool              `json:"isDemo"`
+	Versions    map[string]string `json:"versions"`
+}
+
+type jsonCatalogSource struct {
+	name     string
+	location string
+}
+
+func (s *jsonCatalogSource) Name() string {
+	return s.name
+}
+
+func (s *jsonCatalogSource) Load() (map[string]storage.CatalogEntry, error) {
+	f, err := openCatalogLocation(s.location)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sourceEntries []catalogSourceEntry
+	err = json.NewDecoder(f).Decode(&sourceEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %w", s.name, err)
+	}
+	return catalogEntriesFromSource(sourceEntries), nil
+}
+
+type csvCatalogSource struct {
+	name     string
+	location string
+}
+
+func (s *csvCatalogSource) Name() string {
+	return s.name
+}
+
+func (s *csvCatalogSource) Load() (map[string]storage.CatalogEntry, error) {
+	f, err := openCatalogLocation(s.location)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %w", s.name, err)
+	}
+	if len(records) == 0 {
+		return map[string]storage.CatalogEntry{}, nil
+	}
+
+	header := records[0]
+	var sourceEntries []catalogSourceEntry
+	for _, record := range records[1:] {
+		var entry catalogSourceEntry
+		for idx, value := range record {
+			if idx >= len(header) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(header[idx])) {
+			case "id":
+				entry.ID = value
+			case "name":
+				entry.Name = value
+			case "version":
+				entry.Version = value
+			case "region":
+				entry.Region = value
+			case "publisher":
+				entry.Publisher = value
+			case "developer":
+				entry.Developer = value
+			case "category":
+				entry.Category = splitCatalogList(value)
+			case "languages":
+				entry.Languages = splitCatalogList(value)
+			case "size":
+				entry.Size, _ = strconv.Atoi(value)
+			case "releasedate":
+				entry.ReleaseDate = value
+			case "description":
+				entry.Description = value
+			case "intro":
+				entry.Intro = value
+			case "iconurl":
+				entry.IconURL = value
+			case "bannerurl":
+				entry.BannerURL = value
+			case "isdemo":
+				entry.IsDemo, _ = strconv.ParseBool(value)
+			}
+		}
+		sourceEntries = append(sourceEntries, entry)
+	}
+	return catalogEntriesFromSource(sourceEntries), nil
+}
+
+func splitCatalogList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func catalogEntriesFromSource(sourceEntries []catalogSourceEntry) map[string]storage.CatalogEntry {
+	entries := make(map[string]storage.CatalogEntry)
+	for _, sourceEntry := range sourceEntries {
+		id := strings.ToUpper(sourceEntry.ID)
+		if len(id) != 16 {
+			continue
+		}
+		prefix := id[:len(id)-4]
+		entry := entries[prefix]
+		data := storage.CatalogEntryData{
+			ID:          id,
+			Name:        sourceEntry.Name,
+			Version:     sourceEntry.Version,
+			BannerURL:   sourceEntry.BannerURL,
+			IconURL:     sourceEntry.IconURL,
+			Description: sourceEntry.Description,
+			Intro:       sourceEntry.Intro,
+			Region:      sourceEntry.Region,
+			ReleaseDate: sourceEntry.ReleaseDate,
+			Publisher:   sourceEntry.Publisher,
+			Developer:   sourceEntry.Developer,
+			Category:    sourceEntry.Category,
+			Languages:   sourceEntry.Languages,
+			Size:        sourceEntry.Size,
+			IsDemo:      sourceEntry.IsDemo,
+			Screenshots: sourceEntry.Screenshots,
+		}
+
+		if strings.HasSuffix(id, "000") {
+			entry.CatalogEntryData = data
+			for versionNumber, releaseDate := range sourceEntry.Versions {
+				version, err := strconv.Atoi(versionNumber)
+				if err != nil {
+					continue
+				}
+				entry.Versions = append(entry.Versions, storage.CatalogEntryVersion{Version: version, ReleaseDate: releaseDate})
+			}
+			sort.Slice(entry.Versions, func(i, j int) bool {
+				return entry.Versions[i].Version < entry.Versions[j].Version
+			})
+		} else if strings.HasSuffix(id, "800") {
+			version, err := strconv.Atoi(sourceEntry.Version)
+			if err != nil {
+				continue
+			}
+			entry.RecentUpdate = storage.CatalogEntryRecentUpdate{ID: id, Version: version}
+		} else {
+			entry.DLCs = append(entry.DLCs, storage.CatalogEntryDLC{CatalogEntryData: data})
+		}
+		entries[prefix] = entry
+	}
+	return entries
+}
+
+// openCatalogLocation opens an HTTP URL, a file:// URL or a local file.
+func openCatalogLocation(location string) (io.ReadCloser, error) {
+	if localPath, ok := localCatalogPath(location); ok {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not open file: %w", err)
+		}
+		return f, nil
+	}
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: dialTimeout,
+			}).DialContext,
+		},
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("got a non 200 response - %v", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+type loadedCatalogSource struct {
+	priority int
+	entries  map[string]storage.CatalogEntry
+}
+
+// loadCatalogSources loads additional sources ordered by priority. Sources that fail to load are skipped.
+// Returned fingerprint changes whenever data of any source changes.
+func loadCatalogSources(sources []PrioritizedCatalogSource) ([]loadedCatalogSource, string) {
+	sorted := append([]PrioritizedCatalogSource(nil), sources...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	if len(sorted) == 0 {
+		return nil, ""
+	}
+	hash := sha256.New()
+	var loaded []loadedCatalogSource
+	for _, source := range sorted {
+		entries, err := source.Load()
+		if err != nil {
+			zap.S().Warnf("skipping catalog source %v: %v", source.Name(), err)
+			continue
+		}
+		// map keys are sorted by encoding/json, so the fingerprint is stable
+		content, err := json.Marshal(entries)
+		if err != nil {
+			zap.S().Warnf("skipping catalog source %v: %v", source.Name(), err)
+			continue
+		}
+		_, _ = fmt.Fprintf(hash, "%v:%d:", source.Name(), source.Priority)
+		hash.Write(content)
+		loaded = append(loaded, loadedCatalogSource{priority: source.Priority, entries: entries})
+	}
+	return loaded, hex.EncodeToString(hash.Sum(nil))
+}
+
+// mergeCatalogSources merges additional sources into the main catalog entries. Entries without ID or name
+// after the merge are dropped.
+func mergeCatalogSources(mainEntries map[string]storage.CatalogEntry, sources []loadedCatalogSource) map[string]storage.CatalogEntry {
+	var lower, higher []loadedCatalogSource
+	for _, source := range sources {
+		if source.priority < 0 {
+			lower = append(lower, source)
+		} else {
+			higher = append(higher, source)
+		}
+	}
+
+	result := make(map[string]storage.CatalogEntry, len(mainEntries))
+	for _, source := range lower {
+		for key, entry := range source.entries {
+			result[key] = mergeCatalogEntry(result[key], entry)
+		}
+	}
+	for key, entry := range mainEntries {
+		result[key] = mergeCatalogEntry(result[key], entry)
+	}
+	for _, source := range higher {
+		for key, entry := range source.entries {
+			result[key] = mergeCatalogEntry(result[key], entry)
+		}
+	}
+
+	// titles with only DLC or update records are kept, their IDs are needed for library lookups
+	for key, entry := range result {
+		if entry.ID == "" && entry.Name == "" && len(entry.DLCs) == 0 && entry.RecentUpdate.ID == "" {
+			delete(result, key)
+		}
+	}
+	return result
+}
+
+// mergeCatalogEntry overrides base with non-empty fields of override. Versions and DLCs are merged by version and ID,
+// recent update with the highest version is kept.
+func mergeCatalogEntry(base, override storage.CatalogEntry) storage.CatalogEntry {
+	result := base
+	result.CatalogEntryData = mergeCatalogEntryData(base.CatalogEntryData, override.CatalogEntryData)
+
+	if override.RecentUpdate.Version >= base.RecentUpdate.Version && override.RecentUpdate.ID != "" {
+		result.RecentUpdate = override.RecentUpdate
+	}
+
+	versions := make(map[int]storage.CatalogEntryVersion, len(base.Versions)+len(override.Versions))
+	for _, version := range base.Versions {
+		versions[version.Version] = version
+	}
+	for _, version := range override.Versions {
+		if existing, ok := versions[version.Version]; ok && version.ReleaseDate == "" {
+			version.ReleaseDate = existing.ReleaseDate
+		}
+		versions[version.Version] = version
+	}
+	result.Versions = nil
+	for _, version := range versions {
+		result.Versions = append(result.Versions, version)
+	}
+	sort.Slice(result.Versions, func(i, j int) bool {
+		return result.Versions[i].Version < result.Versions[j].Version
+	})
+
+	result.DLCs = append([]storage.CatalogEntryDLC(nil), base.DLCs...)
+	for _, dlc := range override.DLCs {
+		idx := -1
+		for i := range result.DLCs {
+			if strings.EqualFold(result.DLCs[i].ID, dlc.ID) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			result.DLCs = append(result.DLCs, dlc)
+			continue
+		}
+		result.DLCs[idx].CatalogEntryData = mergeCatalogEntryData(result.DLCs[idx].CatalogEntryData, dlc.CatalogEntryData)
+	}
+	return result
+}
+
+func mergeCatalogEntryData(base, override storage.CatalogEntryData) storage.CatalogEntryData {
+	pick := func(baseValue, overrideValue string) string {
+		if overrideValue != "" {
+			return overrideValue
+		}
+		return baseValue
+	}
+	result := storage.CatalogEntryData{
+		ID:          pick(base.ID, override.ID),
+		Name:        pick(base.Name, override.Name),
+		Version:     pick(base.Version, override.Version),
+		BannerURL:   pick(base.BannerURL, override.BannerURL),
+		IconURL:     pick(base.IconURL, override.IconURL),
+		Description: pick(base.Description, override.Description),
+		Intro:       pick(base.Intro, override.Intro),
+		Region:      pick(base.Region, override.Region),
+		Key:         pick(base.Key, override.Key),
+		ReleaseDate: pick(base.ReleaseDate, override.ReleaseDate),
+		Publisher:   pick(base.Publisher, override.Publisher),
+		Developer:   pick(base.Developer, override.Developer),
+		Category:    base.Category,
+		Languages:   base.Languages,
+		Size:        base.Size,
+		IsDemo:      base.IsDemo || override.IsDemo,
+		Screenshots: base.Screenshots,
+	}
+	if len(override.Screenshots) > 0 {
+		result.Screenshots = override.Screenshots
+	}
+	if len(override.Category) > 0 {
+		result.Category = override.Category
+	}
+	if len(override.Languages) > 0 {
+		result.Languages = override.Languages
+	}
+	if override.Size > 0 {
+		result.Size = override.Size
+	}
+	return result
+}
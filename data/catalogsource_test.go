@@ -0,0 +1,158 @@
+package data
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestCatalogSource(t *testing.T, sourceType CatalogSourceType, location string, priority int) PrioritizedCatalogSource {
+	source, err := NewCatalogSource(sourceType, string(sourceType), location, "")
+	assert.Nil(t, err)
+	return PrioritizedCatalogSource{CatalogSource: source, Priority: priority}
+}
+
+func TestCatalogSources(t *testing.T) {
+	jsonSource := newTestCatalogSource(t, CatalogSourceTypeJSON, "testdata/catalog/override.json", 1)
+	entries, err := jsonSource.Load()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Test Game Deluxe", entries["010000000001"].Name)
+	assert.Len(t, entries["010000000001"].Versions, 2)
+	assert.Len(t, entries["010000000001"].DLCs, 1)
+
+	csvSource := newTestCatalogSource(t, CatalogSourceTypeCSV, "testdata/catalog/homebrew.csv", -1)
+	entries, err = csvSource.Load()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, storage.CatalogEntryData{
+		ID:          "0100000000030000",
+		Name:        "Cartridge Only Game",
+		Region:      "US",
+		Publisher:   "Homebrew Team",
+		ReleaseDate: "2022-05-01",
+		Description: "Not on eShop",
+		Developer:   "Homebrew Team",
+		Category:    []string{"Puzzle", "Arcade"},
+		Languages:   []string{"en", "ja"},
+		Size:        52428800,
+	}, entries["010000000003"].CatalogEntryData)
+
+	tinfoilSource, err := NewCatalogSource(CatalogSourceTypeTinfoil, "mirror", testCatalogTitlesPath, testCatalogVersionsPath)
+	assert.Nil(t, err)
+	entries, err = tinfoilSource.Load()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	_, err = NewCatalogSource("xml", "unknown", "catalog.xml", "")
+	assert.ErrorIs(t, err, ErrUnknownCatalogSourceType)
+}
+
+func TestBuildCatalogMergesSources(t *testing.T) {
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	overridePath := filepath.Join(t.TempDir(), "override.json")
+	content, err := os.ReadFile("testdata/catalog/override.json")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(overridePath, content, 0644))
+
+	sources := []PrioritizedCatalogSource{
+		newTestCatalogSource(t, CatalogSourceTypeJSON, overridePath, 1),
+		newTestCatalogSource(t, CatalogSourceTypeCSV, "testdata/catalog/homebrew.csv", -1),
+	}
+	cacheDirectory := t.TempDir()
+	var steps int
+	build := func() error {
+		steps = 0
+		return BuildCatalog(db, cacheDirectory, testCatalogTitlesPath, testCatalogVersionsPath, sources, func(current, total int, message string) {
+			steps++
+		})
+	}
+	assert.Nil(t, build())
+
+	page, err := db.GetCatalogEntries(nil, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, page.TotalCount)
+
+	// higher priority overrides, lower priority fills in missing fields only
+	entry, err := db.GetCatalogEntryByIDPrefix("010000000001")
+	assert.Nil(t, err)
+	assert.Equal(t, "Test Game Deluxe", entry.Name)
+	assert.Equal(t, "US", entry.Region)
+	assert.Equal(t, "Test Publisher", entry.Publisher)
+	assert.Equal(t, "2020-01-01", entry.ReleaseDate)
+	assert.Equal(t, "Filled from CSV", entry.Description)
+	assert.Equal(t, "Test Studio", entry.Developer)
+	assert.Equal(t, []string{"Action", "RPG"}, entry.Category)
+	assert.Equal(t, []string{"en"}, entry.Languages)
+	assert.Equal(t, 1073741824, entry.Size)
+	assert.Equal(t, []storage.CatalogEntryVersion{
+		{Version: 65536, ReleaseDate: "2020-02-01"},
+		{Version: 131072, ReleaseDate: "2020-03-02"},
+		{Version: 196608, ReleaseDate: "2020-04-01"},
+	}, entry.Versions)
+	assert.Equal(t, 131072, entry.RecentUpdate.Version)
+	if assert.Len(t, entry.DLCs, 1) {
+		assert.Equal(t, "Test Game - Extra Content (Renamed)", entry.DLCs[0].Name)
+		assert.Equal(t, "US", entry.DLCs[0].Region)
+	}
+
+	entry, err = db.GetCatalogEntryByIDPrefix("010000000003")
+	assert.Nil(t, err)
+	assert.Equal(t, "Cartridge Only Game", entry.Name)
+
+	// nothing changed
+	assert.Nil(t, build())
+	assert.Equal(t, 5, steps)
+
+	// changed source rebuilds the catalog
+	assert.Nil(t, os.WriteFile(overridePath, []byte(`[{"id": "0100000000010000", "name": "Renamed Again"}]`), 0644))
+	assert.Nil(t, build())
+	assert.Equal(t, 7, steps)
+	entry, err = db.GetCatalogEntryByIDPrefix("010000000001")
+	assert.Nil(t, err)
+	assert.Equal(t, "Renamed Again", entry.Name)
+}
+
+func TestBuildCatalogKeepsTitlesWithoutBaseData(t *testing.T) {
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	directory := t.TempDir()
+	titlesPath := filepath.Join(directory, "titles.json")
+	versionsPath := filepath.Join(directory, "versions.json")
+	assert.Nil(t, os.WriteFile(titlesPath, []byte(`{
+		"0100000000010000": {"id": "0100000000010000", "name": "Game", "version": 0},
+		"0100000000051001": {"id": "0100000000051001", "name": "DLC Only", "version": 0}
+	}`), 0644))
+	assert.Nil(t, os.WriteFile(versionsPath, []byte(`{"0100000000060000": {"65536": "2020-02-01"}}`), 0644))
+
+	err = BuildCatalog(db, t.TempDir(), titlesPath, versionsPath, nil, func(current, total int, message string) {})
+	assert.Nil(t, err)
+
+	entry, ok, err := db.GetCatalogEntryByID("0100000000051001")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	if assert.Len(t, entry.DLCs, 1) {
+		assert.Equal(t, "DLC Only", entry.DLCs[0].Name)
+	}
+	entry, err = db.GetCatalogEntryByIDPrefix("010000000005")
+	assert.Nil(t, err)
+	assert.Len(t, entry.DLCs, 1)
+
+	// titles without base data are not listed
+	page, err := db.GetCatalogEntries(nil, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page.TotalCount)
+
+	// versions without any title data are not stored
+	merged := mergeCatalogSources(map[string]storage.CatalogEntry{
+		"010000000006": {Versions: []storage.CatalogEntryVersion{{Version: 65536}}},
+	}, nil)
+	assert.Empty(t, merged)
+}
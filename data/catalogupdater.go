@@ -0,0 +1,219 @@
+package data
+
+import (
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CatalogChange lists content that appeared in the catalog for a single title.
+type CatalogChange struct {
+	TitleID    string
+	Name       string
+	NewUpdates []storage.CatalogEntryVersion
+	NewDLCs    []storage.CatalogEntryDLC
+}
+
+// CatalogChangedCallback is called with new content of titles present in the library after a catalog update.
+type CatalogChangedCallback func(changes []CatalogChange)
+
+// CatalogBuilder refreshes catalog data, usually with BuildCatalog.
+type CatalogBuilder func() error
+
+// CatalogUpdater periodically refreshes the catalog and reports new updates and DLC for titles in the library.
+type CatalogUpdater struct {
+	logger   *zap.SugaredLogger
+	db       storage.SwitchDatabaseCatalog
+	manager  LibraryManager
+	build    CatalogBuilder
+	interval time.Duration
+	onChange CatalogChangedCallback
+
+	mutex       sync.Mutex
+	updateMutex sync.Mutex
+	done        chan struct{}
+}
+
+func NewCatalogUpdater(logger *zap.SugaredLogger, db storage.SwitchDatabaseCatalog, manager LibraryManager, interval time.Duration, build CatalogBuilder, onChange CatalogChangedCallback) *CatalogUpdater {
+	return &CatalogUpdater{
+		logger:   logger,
+		db:       db,
+		manager:  manager,
+		build:    build,
+		interval: interval,
+		onChange: onChange,
+	}
+}
+
+// Start runs updates in background every interval until Close is called. Non-positive interval disables updates.
+func (u *CatalogUpdater) Start() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.interval <= 0 || u.done != nil {
+		return
+	}
+	u.done = make(chan struct{})
+	go u.run(u.done)
+}
+
+func (u *CatalogUpdater) Close() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.done == nil {
+		return
+	}
+	close(u.done)
+	u.done = nil
+}
+
+func (u *CatalogUpdater) run(done chan struct{}) {
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			changes, err := u.Update()
+			if err != nil {
+				u.logger.Warnf("scheduled catalog update failed: %v", err)
+				continue
+			}
+			u.logger.Infof("scheduled catalog update finished, %d titles with new content", len(changes))
+			if len(changes) > 0 && u.onChange != nil {
+				u.onChange(changes)
+			}
+		}
+	}
+}
+
+// Update refreshes the catalog and returns new updates and DLC of titles present in the library.
+func (u *CatalogUpdater) Update() ([]CatalogChange, error) {
+	return u.UpdateWith(u.build)
+}
+
+// UpdateWith works like Update but replaces catalog data with the given builder, e.g. to import a catalog bundle.
+func (u *CatalogUpdater) UpdateWith(build CatalogBuilder) ([]CatalogChange, error) {
+	u.updateMutex.Lock()
+	defer u.updateMutex.Unlock()
+
+	before, err := u.db.GetCatalogEntries(nil, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not read catalog: %w", err)
+	}
+	// copy as the page may share memory with the catalog
+	oldEntries := append([]storage.CatalogEntry(nil), before.Data...)
+
+	err = build()
+	if err != nil {
+		return nil, fmt.Errorf("could not build catalog: %w", err)
+	}
+
+	after, err := u.db.GetCatalogEntries(nil, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not read catalog: %w", err)
+	}
+	entries, err := u.manager.GetEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not read library: %w", err)
+	}
+	return diffCatalogEntries(oldEntries, after.Data, libraryTitlePrefixes(entries)), nil
+}
+
+func catalogTitlePrefix(id string) string {
+	if len(id) < 4 {
+		return strings.ToUpper(id)
+	}
+	return strings.ToUpper(id[:len(id)-4])
+}
+
+func libraryTitlePrefixes(entries []LibraryFileEntry) map[string]struct{} {
+	prefixes := make(map[string]struct{})
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		for _, game := range entry.BaseGames {
+			prefixes[strings.ToUpper(game.IDPrefix)] = struct{}{}
+		}
+		for _, update := range entry.Updates {
+			prefixes[strings.ToUpper(update.ForIDPrefix)] = struct{}{}
+		}
+		for _, dlc := range entry.DLCs {
+			prefixes[strings.ToUpper(dlc.ForIDPrefix)] = struct{}{}
+		}
+	}
+	return prefixes
+}
+
+// catalogUpdateVersions returns all known update versions of the entry.
+func catalogUpdateVersions(entry storage.CatalogEntry) map[int]storage.CatalogEntryVersion {
+	versions := make(map[int]storage.CatalogEntryVersion, len(entry.Versions)+1)
+	for _, version := range entry.Versions {
+		versions[version.Version] = version
+	}
+	if _, ok := versions[entry.RecentUpdate.Version]; !ok && entry.RecentUpdate.Version > 0 {
+		versions[entry.RecentUpdate.Version] = storage.CatalogEntryVersion{Version: entry.RecentUpdate.Version}
+	}
+	return versions
+}
+
+// diffCatalogEntries returns updates and DLC that are present only in the new catalog, for owned titles only.
+// Titles missing in the old catalog are skipped, so the initial download does not report everything as new.
+func diffCatalogEntries(oldEntries, newEntries []storage.CatalogEntry, owned map[string]struct{}) []CatalogChange {
+	if len(oldEntries) == 0 {
+		return nil
+	}
+	old := make(map[string]storage.CatalogEntry, len(oldEntries))
+	for _, entry := range oldEntries {
+		old[catalogTitlePrefix(entry.ID)] = entry
+	}
+
+	var changes []CatalogChange
+	for _, entry := range newEntries {
+		prefix := catalogTitlePrefix(entry.ID)
+		if _, ok := owned[prefix]; !ok {
+			continue
+		}
+		oldEntry, ok := old[prefix]
+		if !ok {
+			continue
+		}
+
+		change := CatalogChange{TitleID: entry.ID, Name: entry.Name}
+		oldVersions := catalogUpdateVersions(oldEntry)
+		for version, data := range catalogUpdateVersions(entry) {
+			if _, ok := oldVersions[version]; !ok {
+				change.NewUpdates = append(change.NewUpdates, data)
+			}
+		}
+		sort.Slice(change.NewUpdates, func(i, j int) bool {
+			return change.NewUpdates[i].Version < change.NewUpdates[j].Version
+		})
+
+		oldDLCs := make(map[string]struct{}, len(oldEntry.DLCs))
+		for _, dlc := range oldEntry.DLCs {
+			oldDLCs[strings.ToUpper(dlc.ID)] = struct{}{}
+		}
+		for _, dlc := range entry.DLCs {
+			if _, ok := oldDLCs[strings.ToUpper(dlc.ID)]; !ok {
+				change.NewDLCs = append(change.NewDLCs, dlc)
+			}
+		}
+
+		if len(change.NewUpdates) > 0 || len(change.NewDLCs) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].TitleID < changes[j].TitleID
+	})
+	return changes
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/keys"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCatalogUpdater(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "Game [0100000000010000][v0].nsp"), []byte("base"), 0644))
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, true, 1)
+	_, err = manager.Rescan(false, nil)
+	assert.Nil(t, err)
+
+	owned := storage.CatalogEntry{
+		CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Owned"},
+		Versions:         []storage.CatalogEntryVersion{{Version: 65536, ReleaseDate: "2020-01-01"}},
+		DLCs:             []storage.CatalogEntryDLC{{CatalogEntryData: storage.CatalogEntryData{ID: "0100000000011001"}}},
+	}
+	other := storage.CatalogEntry{
+		CatalogEntryData: storage.CatalogEntryData{ID: "0100000000020000", Name: "Other"},
+	}
+
+	var catalog map[string]storage.CatalogEntry
+	updater := NewCatalogUpdater(zap.NewNop().Sugar(), db, manager, 0, func() error {
+		return db.ReplaceCatalog(catalog, storage.CatalogMetadata{})
+	}, nil)
+
+	// initial download reports nothing
+	catalog = map[string]storage.CatalogEntry{"010000000001": owned, "010000000002": other}
+	changes, err := updater.Update()
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	newDLC := storage.CatalogEntryDLC{CatalogEntryData: storage.CatalogEntryData{ID: "0100000000011002"}}
+	updatedOwned := owned
+	updatedOwned.Versions = append(owned.Versions, storage.CatalogEntryVersion{Version: 131072, ReleaseDate: "2020-02-01"})
+	updatedOwned.RecentUpdate = storage.CatalogEntryRecentUpdate{ID: "0100000000010800", Version: 196608}
+	updatedOwned.DLCs = append(owned.DLCs, newDLC)
+	updatedOther := other
+	updatedOther.Versions = []storage.CatalogEntryVersion{{Version: 65536}}
+
+	catalog = map[string]storage.CatalogEntry{"010000000001": updatedOwned, "010000000002": updatedOther}
+	changes, err = updater.Update()
+	assert.Nil(t, err)
+	assert.Equal(t, []CatalogChange{{
+		TitleID: "0100000000010000",
+		Name:    "Owned",
+		NewUpdates: []storage.CatalogEntryVersion{
+			{Version: 131072, ReleaseDate: "2020-02-01"},
+			{Version: 196608},
+		},
+		NewDLCs: []storage.CatalogEntryDLC{newDLC},
+	}}, changes)
+
+	// no changes
+	changes, err = updater.Update()
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+}
@@ -13,6 +13,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -82,38 +84,68 @@ type LibraryFileEntry struct {
 	*LibraryGameFileMetadata
 }
 
+// RescanSummary holds counts of files found by a library rescan.
+type RescanSummary struct {
+	Added     int
+	Changed   int
+	Removed   int
+	Unchanged int
+}
+
 type LibraryManager interface {
-	// Rescan performs a scan of library and reports back progress
-	Rescan(hardRescan bool, progressCallback ProgressCallback) error
+	// Rescan performs a scan of library and reports back progress. Soft rescan reuses stored metadata
+	// of files with unchanged size and modification date, hard rescan processes every file again.
+	Rescan(hardRescan bool, progressCallback ProgressCallback) (RescanSummary, error)
+	// RescanPath updates library entries of a single file or directory, removing them if path no longer exists
+	RescanPath(path string) error
+	// MoveEntries updates paths of library entries after their files were moved, moves map old paths to new ones
+	MoveEntries(moves map[string]string) error
 	GetEntries() ([]LibraryFileEntry, error)
 	GetFilesForID(id string) ([]LibraryFileEntry, error)
+	// GetScanIssues returns files that were skipped or failed to be processed during recent scans
+	GetScanIssues() ([]ScanIssue, error)
 	Clear() error
 }
 
 type LibraryManagerImpl struct {
+	scanMutex       sync.Mutex
+	issuesMutex     sync.RWMutex
+	issues          scanIssues
 	logger          *zap.SugaredLogger
 	db              storage.SwitchDatabaseLibrary
 	keysProvider    keys.KeysProvider
 	allowedFormats  []string
 	scanDirectories []string
-
-	// TODO: replace with persistence
-	entries []LibraryFileEntry
+	scanRecursive   bool
+	// scanWorkers is a number of files processed concurrently
+	scanWorkers int
 }
 
-func NewLibraryManager(logger *zap.SugaredLogger, keysProvider keys.KeysProvider, scanDirectories []string) *LibraryManagerImpl {
+func NewLibraryManager(logger *zap.SugaredLogger, db storage.SwitchDatabaseLibrary, keysProvider keys.KeysProvider, scanDirectories []string, scanRecursive bool, scanWorkers int) *LibraryManagerImpl {
 	return &LibraryManagerImpl{
 		logger:          logger,
-		db:              nil,
+		db:              db,
 		keysProvider:    keysProvider,
 		allowedFormats:  []string{"xci", "nsp", "nsz", "xcz"},
 		scanDirectories: scanDirectories,
-		entries:         nil,
+		scanRecursive:   scanRecursive,
+		scanWorkers:     scanWorkers,
+		issues:          make(scanIssues),
 	}
 }
 
-func (l *LibraryManagerImpl) Rescan(hardRescan bool, progressCallback ProgressCallback) error {
-	var recursive bool = true
+func (l *LibraryManagerImpl) Rescan(hardRescan bool, progressCallback ProgressCallback) (RescanSummary, error) {
+	l.scanMutex.Lock()
+	defer l.scanMutex.Unlock()
+
+	existingEntries, err := l.db.GetLibraryEntries()
+	if err != nil {
+		return RescanSummary{}, fmt.Errorf("could not get library entries: %w", err)
+	}
+	existing := make(map[string]storage.LibraryEntry, len(existingEntries))
+	for _, entry := range existingEntries {
+		existing[entry.FilePath] = entry
+	}
 
 	var files []fileInfo
 	errs := make(map[string]error)
@@ -124,36 +156,272 @@ func (l *LibraryManagerImpl) Rescan(hardRescan bool, progressCallback ProgressCa
 				progressCallback(dirIdx, len(l.scanDirectories), filePath)
 			}
 		}
-		l.traverseFolder(path, recursive, dirProgress, &files, errs)
+		l.traverseFolder(path, l.scanRecursive, dirProgress, &files, errs)
 	}
 	if len(errs) > 0 {
 		l.logger.Warnf("errors: %v", errs)
 	}
-	// TODO: handle errors
+	issues := make(scanIssues)
+	issues.add(errs, time.Now())
+
+	var summary RescanSummary
+	found := make(map[string]struct{}, len(files))
+	var toProcess []fileInfo
+	for _, file := range files {
+		existingEntry, isExisting := existing[file.FullPath]
+		if !hardRescan && isExisting && existingEntry.FileSize == file.Size && existingEntry.FileModified == file.Modified {
+			found[file.FullPath] = struct{}{}
+			summary.Unchanged += 1
+			continue
+		}
+		toProcess = append(toProcess, file)
+	}
+
+	results := l.processFiles(toProcess, progressCallback)
 
 	errs = make(map[string]error)
-	fileEntries := make([]LibraryFileEntry, 0, len(files))
-	for idx, file := range files {
-		if progressCallback != nil {
-			progressCallback(idx, len(files), "processing file: "+file.Name)
+	fileEntries := make([]storage.LibraryEntry, 0, len(toProcess))
+	for idx, result := range results {
+		file := toProcess[idx]
+		if result.err != nil {
+			errs[file.FullPath] = result.err
+			continue
+		}
+		found[file.FullPath] = struct{}{}
+		fileEntries = append(fileEntries, toStorageEntry(*result.entry))
+		if _, isExisting := existing[file.FullPath]; isExisting {
+			summary.Changed += 1
+		} else {
+			summary.Added += 1
+		}
+	}
+	if len(errs) > 0 {
+		l.logger.Warnf("errors: %v", errs)
+	}
+	issues.add(errs, time.Now())
+
+	l.issuesMutex.Lock()
+	l.issues = issues
+	l.issuesMutex.Unlock()
+
+	var removed []string
+	for filePath := range existing {
+		if _, ok := found[filePath]; !ok {
+			removed = append(removed, filePath)
+		}
+	}
+	summary.Removed = len(removed)
+
+	err = l.db.DeleteLibraryEntries(removed)
+	if err != nil {
+		return summary, fmt.Errorf("could not delete library entries: %w", err)
+	}
+	err = l.db.UpsertLibraryEntries(fileEntries)
+	if err != nil {
+		return summary, fmt.Errorf("could not store library entries: %w", err)
+	}
+
+	l.logger.Infof("library rescan finished: %+v", summary)
+	return summary, nil
+}
+
+func (l *LibraryManagerImpl) RescanPath(path string) error {
+	l.scanMutex.Lock()
+	defer l.scanMutex.Unlock()
+
+	fullPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("could not get absolute path: %w", err)
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("could not get file details: %w", err)
+		}
+		l.issuesMutex.Lock()
+		l.issues.removePath(fullPath)
+		l.issuesMutex.Unlock()
+		return l.removePath(fullPath)
+	}
+
+	var files []fileInfo
+	errs := make(map[string]error)
+	if stat.IsDir() {
+		l.traverseFolder(fullPath, l.scanRecursive, nil, &files, errs)
+	} else {
+		isLibraryFile, err := l.isLibraryFile(fullPath)
+		if err != nil {
+			errs[fullPath] = err
+		} else if isLibraryFile {
+			files = append(files, fileInfo{
+				FullPath: fullPath,
+				Name:     stat.Name(),
+				Size:     int(stat.Size()),
+				Modified: int(stat.ModTime().Unix()),
+			})
+		}
+	}
+
+	var fileEntries []storage.LibraryEntry
+	var failed []string
+	for _, file := range files {
+		existingEntry, isExisting, err := l.db.GetLibraryEntry(file.FullPath)
+		if err != nil {
+			return fmt.Errorf("could not get library entry: %w", err)
+		}
+		if isExisting && existingEntry.FileSize == file.Size && existingEntry.FileModified == file.Modified {
+			continue
 		}
 
 		fileEntry, err := l.processFile(file)
 		if err != nil {
 			errs[file.FullPath] = err
+			if isExisting {
+				failed = append(failed, file.FullPath)
+			}
 			continue
 		}
-		fileEntries = append(fileEntries, *fileEntry)
+		fileEntries = append(fileEntries, toStorageEntry(*fileEntry))
 	}
 	if len(errs) > 0 {
 		l.logger.Warnf("errors: %v", errs)
 	}
 
-	//TODO:  store to DB instead
-	l.entries = fileEntries
+	l.issuesMutex.Lock()
+	l.issues.removePath(fullPath)
+	l.issues.add(errs, time.Now())
+	l.issuesMutex.Unlock()
+
+	err = l.db.DeleteLibraryEntries(failed)
+	if err != nil {
+		return fmt.Errorf("could not delete library entries: %w", err)
+	}
+	err = l.db.UpsertLibraryEntries(fileEntries)
+	if err != nil {
+		return fmt.Errorf("could not store library entries: %w", err)
+	}
+	if !stat.IsDir() && len(errs) > 0 {
+		return errs[fullPath]
+	}
 	return nil
 }
 
+func (l *LibraryManagerImpl) MoveEntries(moves map[string]string) error {
+	l.scanMutex.Lock()
+	defer l.scanMutex.Unlock()
+
+	var removed []string
+	var moved []storage.LibraryEntry
+	for from, to := range moves {
+		entry, isExisting, err := l.db.GetLibraryEntry(from)
+		if err != nil {
+			return fmt.Errorf("could not get library entry: %w", err)
+		}
+		if !isExisting {
+			continue
+		}
+		stat, err := os.Stat(to)
+		if err != nil {
+			return fmt.Errorf("could not get file details: %w", err)
+		}
+		entry.FilePath = to
+		entry.FileSize = int(stat.Size())
+		entry.FileModified = int(stat.ModTime().Unix())
+		removed = append(removed, from)
+		moved = append(moved, entry)
+	}
+
+	err := l.db.DeleteLibraryEntries(removed)
+	if err != nil {
+		return fmt.Errorf("could not delete library entries: %w", err)
+	}
+	err = l.db.UpsertLibraryEntries(moved)
+	if err != nil {
+		return fmt.Errorf("could not store library entries: %w", err)
+	}
+	return nil
+}
+
+// removePath removes entries of a file or all the files inside a directory.
+func (l *LibraryManagerImpl) removePath(fullPath string) error {
+	entries, err := l.db.GetLibraryEntries()
+	if err != nil {
+		return fmt.Errorf("could not get library entries: %w", err)
+	}
+
+	dirPrefix := fullPath + string(os.PathSeparator)
+	var removed []string
+	for _, entry := range entries {
+		if entry.FilePath == fullPath || strings.HasPrefix(entry.FilePath, dirPrefix) {
+			removed = append(removed, entry.FilePath)
+		}
+	}
+
+	err = l.db.DeleteLibraryEntries(removed)
+	if err != nil {
+		return fmt.Errorf("could not delete library entries: %w", err)
+	}
+	return nil
+}
+
+type processFileResult struct {
+	entry *LibraryFileEntry
+	err   error
+}
+
+// processFiles extracts metadata of given files using a pool of workers. Results are returned in the order of files,
+// progress is reported from a single goroutine as files get completed.
+func (l *LibraryManagerImpl) processFiles(files []fileInfo, progressCallback ProgressCallback) []processFileResult {
+	results := make([]processFileResult, len(files))
+	if len(files) == 0 {
+		return results
+	}
+
+	workers := l.scanWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	jobs := make(chan int)
+	completed := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				entry, err := l.processFile(files[idx])
+				results[idx] = processFileResult{entry: entry, err: err}
+				completed <- idx
+			}
+		}()
+	}
+
+	go func() {
+		for idx := range files {
+			jobs <- idx
+		}
+		close(jobs)
+		wg.Wait()
+		close(completed)
+	}()
+
+	done := 0
+	for idx := range completed {
+		done += 1
+		if progressCallback != nil {
+			progressCallback(done, len(files), "processed file: "+files[idx].Name)
+		}
+	}
+
+	return results
+}
+
 func (l *LibraryManagerImpl) traverseFolder(directory string, recursive bool, progress undeterminedFileProgressCallback, files *[]fileInfo, errs map[string]error) {
 	_ = filepath.WalkDir(directory, func(path string, info os.DirEntry, err error) error {
 		if err != nil {
@@ -164,17 +432,24 @@ func (l *LibraryManagerImpl) traverseFolder(directory string, recursive bool, pr
 			return nil
 		}
 		if info.Name()[0] == '.' {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if info.IsDir() {
-			if !recursive {
+			// folders named as a game file hold parts of a split file and are scanned even without recursion
+			if !recursive && !slices.Contains(l.allowedFormats, strings.TrimPrefix(filepath.Ext(path), ".")) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
-		fileExtension := strings.TrimPrefix(filepath.Ext(path), ".")
-		if !slices.Contains(l.allowedFormats, fileExtension) {
-			errs[path] = ErrUnsupportedExtension
+		isLibraryFile, err := l.isLibraryFile(path)
+		if err != nil {
+			errs[path] = err
+			return nil
+		}
+		if !isLibraryFile {
 			return nil
 		}
 
@@ -199,24 +474,56 @@ func (l *LibraryManagerImpl) traverseFolder(directory string, recursive bool, pr
 	})
 }
 
-func (l *LibraryManagerImpl) processFile(file fileInfo) (*LibraryFileEntry, error) {
-	//scan sub-folders if flag is present
-
-	isSplit := false
-	// check if it's a split file (ending with xx where x is a digit )
-	if partNum, err := strconv.Atoi(file.Name[len(file.Name)-2:]); err == nil {
-		if partNum == 0 {
-			isSplit = true
+// isLibraryFile checks whether the file is a game file in an allowed format or the first part of such split file.
+// Further parts of split files are not library files on their own.
+func (l *LibraryManagerImpl) isLibraryFile(path string) (bool, error) {
+	name := filepath.Base(path)
+	splitName, part, isSplit := splitFilePart(path)
+	if isSplit {
+		if part != 0 {
+			return false, nil
 		}
+		name = splitName
 	}
+	fileExtension := strings.TrimPrefix(filepath.Ext(name), ".")
+	if !slices.Contains(l.allowedFormats, fileExtension) {
+		return false, ErrUnsupportedExtension
+	}
+	return true, nil
+}
 
-	fileExtension := strings.TrimPrefix(filepath.Ext(file.Name), ".")
+// splitFilePart returns name of the split file and number of the part if the file is a part of a split file. Parts
+// are files numbered with two digits in a folder named as the split file.
+func splitFilePart(path string) (string, int, bool) {
+	name := filepath.Base(path)
+	if len(name) != 2 {
+		return "", 0, false
+	}
+	part, err := strconv.ParseUint(name, 10, 8)
+	if err != nil {
+		return "", 0, false
+	}
+	return filepath.Base(filepath.Dir(path)), int(part), true
+}
 
-	if !isSplit && !slices.Contains(l.allowedFormats, fileExtension) {
+func (l *LibraryManagerImpl) processFile(file fileInfo) (*LibraryFileEntry, error) {
+	isLibraryFile, err := l.isLibraryFile(file.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	if !isLibraryFile {
 		return nil, ErrUnsupportedExtension
 	}
 
-	gameFileMetadata, err := l.getGameMetadata(file.FullPath, file.Name, fileExtension)
+	// metadata of split files is read from the name and format of the whole file
+	name := file.Name
+	splitName, _, isSplit := splitFilePart(file.FullPath)
+	if isSplit {
+		name = splitName
+	}
+	fileExtension := strings.TrimPrefix(filepath.Ext(name), ".")
+
+	gameFileMetadata, err := l.getGameMetadata(file.FullPath, name, fileExtension, isSplit)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +539,7 @@ func (l *LibraryManagerImpl) processFile(file fileInfo) (*LibraryFileEntry, erro
 	return fileEntry, nil
 }
 
-func (l *LibraryManagerImpl) getGameMetadata(filePath, fileName, fileFormat string) (*LibraryGameFileMetadata, error) {
+func (l *LibraryManagerImpl) getGameMetadata(filePath, fileName, fileFormat string, isSplit bool) (*LibraryGameFileMetadata, error) {
 	var metadata map[string]*switchfs.ContentMetaAttributes
 	var err error
 	var extractionType ExtractionType
@@ -259,12 +566,12 @@ func (l *LibraryManagerImpl) getGameMetadata(filePath, fileName, fileFormat stri
 		metadata[titleId] = &switchfs.ContentMetaAttributes{TitleId: titleId, Version: version}
 	} else {
 		extractionType = ExtractionTypeKey
-		switch fileFormat {
-		case "nsp", "nsz":
+		switch {
+		case fileFormat == "nsp" || fileFormat == "nsz":
 			metadata, err = switchfs.ReadNspMetadata(l.keysProvider, filePath)
-		case "xci":
+		case fileFormat == "xci":
 			metadata, err = switchfs.ReadXciMetadata(l.keysProvider, filePath)
-		case "00":
+		case isSplit:
 			metadata, err = switchfs.ReadSplitFileMetadata(l.keysProvider, filePath)
 		}
 		if err != nil {
@@ -343,35 +650,38 @@ func (l *LibraryManagerImpl) getGameMetadata(filePath, fileName, fileFormat stri
 }
 
 func (l *LibraryManagerImpl) Clear() error {
-	return nil
+	l.issuesMutex.Lock()
+	l.issues = make(scanIssues)
+	l.issuesMutex.Unlock()
+	return l.db.ClearLibrary()
+}
+
+func (l *LibraryManagerImpl) GetScanIssues() ([]ScanIssue, error) {
+	l.issuesMutex.RLock()
+	defer l.issuesMutex.RUnlock()
+	return l.issues.list(), nil
 }
 
 func (l *LibraryManagerImpl) GetEntries() ([]LibraryFileEntry, error) {
-	return l.entries, nil
+	entries, err := l.db.GetLibraryEntries()
+	if err != nil {
+		return nil, fmt.Errorf("could not get library entries: %w", err)
+	}
+	result := make([]LibraryFileEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, fromStorageEntry(entry))
+	}
+	return result, nil
 }
 
 func (l *LibraryManagerImpl) GetFilesForID(id string) ([]LibraryFileEntry, error) {
-	var result []LibraryFileEntry
-outer:
-	for _, entry := range l.entries {
-		for _, t := range entry.BaseGames {
-			if t.ID == id {
-				result = append(result, entry)
-				continue outer
-			}
-		}
-		for _, t := range entry.DLCs {
-			if t.ID == id {
-				result = append(result, entry)
-				continue outer
-			}
-		}
-		for _, t := range entry.Updates {
-			if t.ID == id {
-				result = append(result, entry)
-				continue outer
-			}
-		}
+	entries, err := l.db.GetLibraryEntriesByTitleID(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not get library entries for %v: %w", id, err)
+	}
+	result := make([]LibraryFileEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, fromStorageEntry(entry))
 	}
 	return result, nil
 }
@@ -1,10 +1,16 @@
 package data
 
 import (
+	"errors"
+	"fmt"
 	"github.com/FrozenPear42/switch-library-manager/keys"
+	"github.com/FrozenPear42/switch-library-manager/storage"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestProcessFile(t *testing.T) {
@@ -20,16 +26,234 @@ func TestProcessFile(t *testing.T) {
 	err = keysProvider.LoadFromFile([]string{"../fixtures/prod.keys"})
 	assert.Nil(t, err)
 
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
 	manager := LibraryManagerImpl{
 		logger:          logger.Sugar(),
-		db:              nil,
+		db:              db,
 		keysProvider:    keysProvider,
 		allowedFormats:  []string{"nsp"},
 		scanDirectories: []string{"../fixtures"},
 	}
 
-	err = manager.Rescan(true, func(current, total int, message string) {
+	_, err = manager.Rescan(true, func(current, total int, message string) {
 		logger.Sugar().Debugf("progress: %v/%v: %v", current, total, message)
 	})
 	assert.Nil(t, err)
 }
+
+func TestRescanIncremental(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	writeFile("Game [0100000000010000][v0].nsp", "base")
+	updatePath := writeFile("Game [0100000000010800][v65536].nsp", "update")
+	dlcPath := writeFile("Game DLC [0100000000011001][v0].nsp", "dlc")
+
+	// no keys available, metadata is extracted from file names
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, true, 2)
+
+	summary, err := manager.Rescan(false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Added: 3}, summary)
+
+	summary, err = manager.Rescan(false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Unchanged: 3}, summary)
+
+	writeFile("Game [0100000000010800][v65536].nsp", "update changed")
+	modified := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(updatePath, modified, modified))
+	assert.Nil(t, os.Remove(dlcPath))
+	writeFile("Game [0100000000010800][v131072].nsp", "new update")
+
+	summary, err = manager.Rescan(false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Added: 1, Changed: 1, Removed: 1, Unchanged: 1}, summary)
+
+	entries, err := manager.GetEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 3)
+
+	files, err := manager.GetFilesForID("0100000000010800")
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+
+	summary, err = manager.Rescan(true, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Changed: 3}, summary)
+}
+
+func TestRescanParallel(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	filesCount := 50
+	for i := 0; i < filesCount; i++ {
+		name := fmt.Sprintf("Game [01000000%04X0000][v0].nsp", i)
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "broken.nsp"), []byte{}, 0644))
+
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, true, 8)
+
+	var processed []int
+	summary, err := manager.Rescan(false, func(current, total int, message string) {
+		if total == filesCount+1 {
+			processed = append(processed, current)
+		}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Added: filesCount}, summary)
+
+	assert.Len(t, processed, filesCount+1)
+	for idx, current := range processed {
+		assert.Equal(t, idx+1, current)
+	}
+
+	entries, err := manager.GetEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, filesCount)
+}
+
+func TestRescanSplitFiles(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	writeFile := func(path ...string) string {
+		fullPath := filepath.Join(append([]string{dir}, path...)...)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		assert.Nil(t, os.WriteFile(fullPath, []byte("part"), 0644))
+		return fullPath
+	}
+	// parts numbered in a folder named as the split file
+	basePart := writeFile("Game [0100000000010000][v0].nsp", "00")
+	writeFile("Game [0100000000010000][v0].nsp", "01")
+	updatePart := writeFile("Game [0100000000010800][v65536].xci", "00")
+	writeFile("Game [0100000000010800][v65536].xci", "01")
+	// folders not named as a game file are not scanned without recursion
+	writeFile("Game [0100000000020000][v0].zip", "00")
+	// parts named as the split file are not supported, they would share the folder with other files
+	writeFile("Game DLC [0100000000011001][v0].nsp.00")
+	// parts directly in the scan directory
+	writeFile("00")
+
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, false, 1)
+	summary, err := manager.Rescan(false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Added: 2}, summary)
+
+	entries, err := manager.GetEntries()
+	assert.Nil(t, err)
+	paths := make(map[string]bool)
+	for _, entry := range entries {
+		paths[entry.FilePath] = entry.IsSplit
+	}
+	assert.Equal(t, map[string]bool{basePart: true, updatePart: true}, paths)
+
+	files, err := manager.GetFilesForID("0100000000010800")
+	assert.Nil(t, err)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, 65536, files[0].Updates[0].Version)
+	}
+
+	issues, err := manager.GetScanIssues()
+	assert.Nil(t, err)
+	assert.Len(t, issues, 2)
+	for _, issue := range issues {
+		assert.Equal(t, ScanIssueReasonUnsupportedExtension, issue.Reason, issue.FilePath)
+	}
+}
+
+func TestScanIssues(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "Game [0100000000010000][v0].nsp"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "No ID [v0].nsp"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "No version [0100000000020000].nsp"), []byte{}, 0644))
+
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, true, 1)
+	_, err = manager.Rescan(false, nil)
+	assert.Nil(t, err)
+
+	issues, err := manager.GetScanIssues()
+	assert.Nil(t, err)
+	assert.Len(t, issues, 3)
+
+	reasons := make(map[string]ScanIssueReason)
+	for _, issue := range issues {
+		reasons[filepath.Base(issue.FilePath)] = issue.Reason
+		assert.NotEmpty(t, issue.ErrorChain())
+		assert.False(t, issue.SeenAt.IsZero())
+	}
+	assert.Equal(t, map[string]ScanIssueReason{
+		"readme.txt":                        ScanIssueReasonUnsupportedExtension,
+		"No ID [v0].nsp":                    ScanIssueReasonFailedToReadTitleID,
+		"No version [0100000000020000].nsp": ScanIssueReasonFailedToReadVersion,
+	}, reasons)
+
+	assert.Nil(t, os.Remove(filepath.Join(dir, "readme.txt")))
+	assert.Nil(t, manager.RescanPath(filepath.Join(dir, "readme.txt")))
+
+	issues, err = manager.GetScanIssues()
+	assert.Nil(t, err)
+	assert.Len(t, issues, 2)
+}
+
+func TestScanIssueErrorChain(t *testing.T) {
+	err := fmt.Errorf("%w: %w", ErrFailedToReadFileMetadata, errors.New("invalid header"))
+	issue := newScanIssue("/a.nsp", err, time.Now())
+
+	assert.Equal(t, ScanIssueReasonFailedToReadMetadata, issue.Reason)
+	assert.Equal(t, []string{
+		"failed to read file metadata: invalid header",
+		"failed to read file metadata",
+		"invalid header",
+	}, issue.ErrorChain())
+}
+
+func TestMoveEntries(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	from := filepath.Join(dir, "Game [0100000000010000][v0].nsp")
+	assert.Nil(t, os.WriteFile(from, []byte("base"), 0644))
+
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, true, 1)
+	_, err = manager.Rescan(false, nil)
+	assert.Nil(t, err)
+
+	to := filepath.Join(dir, "Game", "Game [0100000000010000][v0].nsp")
+	assert.Nil(t, os.Mkdir(filepath.Dir(to), 0755))
+	assert.Nil(t, os.Rename(from, to))
+	assert.Nil(t, manager.MoveEntries(map[string]string{from: to}))
+
+	entries, err := manager.GetEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, to, entries[0].FilePath)
+	assert.Equal(t, "0100000000010000", entries[0].BaseGames[0].ID)
+
+	summary, err := manager.Rescan(false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, RescanSummary{Unchanged: 1}, summary)
+}
@@ -0,0 +1,99 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/search"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LibrarySearchResult is a library title matching a search query.
+type LibrarySearchResult struct {
+	IDPrefix string
+	// TitleID is ID of the base title, taken from the catalog or the library
+	TitleID string
+	Score   float64
+}
+
+// SearchLibrary ranks titles present in the library against the query. Titles are matched by NACP names in every
+// language, file names and, if catalog is not nil, catalog name, publisher and description.
+func SearchLibrary(entries []LibraryFileEntry, catalog storage.SwitchDatabaseCatalog, query string) ([]LibrarySearchResult, error) {
+	// NACP names go before file names so they are treated as the title when catalog entry is missing
+	names := make(map[string][]search.Field)
+	files := make(map[string][]search.Field)
+	titleIDs := make(map[string]string)
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		file := search.Field{Text: strings.TrimSuffix(filepath.Base(entry.FilePath), filepath.Ext(entry.FilePath)), Weight: 1}
+		for _, game := range entry.BaseGames {
+			prefix := strings.ToUpper(game.IDPrefix)
+			files[prefix] = append(files[prefix], file)
+			titleIDs[prefix] = strings.ToUpper(game.ID)
+			languages := make([]string, 0, len(game.Name))
+			for language := range game.Name {
+				languages = append(languages, language)
+			}
+			sort.Strings(languages)
+			for _, language := range languages {
+				names[prefix] = append(names[prefix], search.Field{Text: game.Name[language], Weight: 3})
+			}
+		}
+		for _, update := range entry.Updates {
+			prefix := strings.ToUpper(update.ForIDPrefix)
+			files[prefix] = append(files[prefix], file)
+			if _, ok := titleIDs[prefix]; !ok {
+				titleIDs[prefix] = BaseTitleID(update.ID)
+			}
+		}
+		for _, dlc := range entry.DLCs {
+			prefix := strings.ToUpper(dlc.ForIDPrefix)
+			files[prefix] = append(files[prefix], file)
+			if _, ok := titleIDs[prefix]; !ok {
+				titleIDs[prefix] = BaseTitleID(dlc.ID)
+			}
+		}
+	}
+
+	prefixes := make([]string, 0, len(files))
+	for prefix := range files {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	documents := make([]search.Document, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		var fields []search.Field
+		if catalog != nil {
+			catalogEntry, err := catalog.GetCatalogEntryByIDPrefix(prefix)
+			if err != nil && !errors.Is(err, storage.ErrCatalogEntryNotFound) {
+				return nil, fmt.Errorf("could not read catalog entry %v: %w", prefix, err)
+			}
+			if err == nil && catalogEntry.ID != "" {
+				titleIDs[prefix] = strings.ToUpper(catalogEntry.ID)
+			}
+			if err == nil {
+				// catalog name goes first so it is treated as the title
+				fields = append(fields,
+					search.Field{Text: catalogEntry.Name, Weight: 3},
+					search.Field{Text: catalogEntry.Publisher, Weight: 1},
+					search.Field{Text: catalogEntry.Description, Weight: 0.5},
+				)
+			}
+		}
+		fields = append(fields, names[prefix]...)
+		documents = append(documents, search.Document{Fields: append(fields, files[prefix]...)})
+	}
+
+	results := search.NewIndex(documents).Search(query)
+	titleResults := make([]LibrarySearchResult, 0, len(results))
+	for _, result := range results {
+		prefix := prefixes[result.Document]
+		titleResults = append(titleResults, LibrarySearchResult{IDPrefix: prefix, TitleID: titleIDs[prefix], Score: result.Score})
+	}
+	return titleResults, nil
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchLibrary(t *testing.T) {
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	err = db.ReplaceCatalog(map[string]storage.CatalogEntry{
+		"0100000000010000": {CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Pokémon™ Sword", Publisher: "Nintendo"}},
+	}, storage.CatalogMetadata{})
+	assert.Nil(t, err)
+
+	entries := []LibraryFileEntry{
+		{FilePath: "/library/sword.nsp", LibraryGameFileMetadata: &LibraryGameFileMetadata{
+			BaseGames: []SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}},
+		}},
+		{FilePath: "/library/zelda.nsp", LibraryGameFileMetadata: &LibraryGameFileMetadata{
+			BaseGames: []SwitchFileGame{{IDPrefix: "010000000002", ID: "0100000000020000", Name: map[string]string{
+				"AmericanEnglish": "The Legend of Zelda",
+				"Japanese":        "ゼルダの伝説",
+			}}},
+		}},
+		{FilePath: "/library/Zelda Update [0100000000020800].nsp", LibraryGameFileMetadata: &LibraryGameFileMetadata{
+			Updates: []SwitchFileUpdate{{ForIDPrefix: "010000000002", ID: "0100000000020800"}},
+		}},
+		{FilePath: "/library/broken.nsp"},
+		{FilePath: "/library/Metroid Dread [01007EF00011E800].nsp", LibraryGameFileMetadata: &LibraryGameFileMetadata{
+			Updates: []SwitchFileUpdate{{ForIDPrefix: "01007EF00011", ID: "01007EF00011E800"}},
+		}},
+	}
+
+	prefixes := func(results []LibrarySearchResult) []string {
+		result := make([]string, 0, len(results))
+		for _, r := range results {
+			result = append(result, r.IDPrefix)
+		}
+		return result
+	}
+
+	tests := []struct {
+		query    string
+		prefixes []string
+	}{
+		{"pokemon", []string{"010000000001"}},
+		{"nintendo", []string{"010000000001"}},
+		{"sword", []string{"010000000001"}},
+		{"legend of zleda", []string{"010000000002"}},
+		{"ゼルダ", []string{"010000000002"}},
+		{"update", []string{"010000000002"}},
+		{"mario", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results, err := SearchLibrary(entries, db, test.query)
+			assert.Nil(t, err)
+			assert.Equal(t, test.prefixes, prefixes(results))
+		})
+	}
+
+	// base title IDs come from the library when catalog entry is missing
+	results, err := SearchLibrary(entries, db, "metroid")
+	assert.Nil(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "01007EF00011E000", results[0].TitleID)
+	}
+	results, err = SearchLibrary(entries, db, "sword")
+	assert.Nil(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "0100000000010000", results[0].TitleID)
+	}
+
+	// library works without catalog
+	results, err = SearchLibrary(entries, nil, "zelda")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"010000000002"}, prefixes(results))
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/storage"
+)
+
+func toStorageEntry(entry LibraryFileEntry) storage.LibraryEntry {
+	result := storage.LibraryEntry{
+		FilePath:     entry.FilePath,
+		FileSize:     entry.FileSize,
+		FileModified: entry.FileModified,
+		IsSplit:      entry.IsSplit,
+	}
+	if entry.LibraryGameFileMetadata == nil {
+		return result
+	}
+
+	result.ExtractionType = string(entry.ExtractionType)
+	result.IsMultiContent = entry.IsMultiContent
+	for _, game := range entry.BaseGames {
+		result.TitleIDs = append(result.TitleIDs, game.ID)
+		result.BaseGames = append(result.BaseGames, storage.LibraryEntryGame{
+			IDPrefix:        game.IDPrefix,
+			ID:              game.ID,
+			Version:         game.Version,
+			Name:            game.Name,
+			ReadableVersion: game.ReadableVersion,
+			ISBN:            game.ISBN,
+		})
+	}
+	for _, dlc := range entry.DLCs {
+		result.TitleIDs = append(result.TitleIDs, dlc.ID)
+		result.DLCs = append(result.DLCs, storage.LibraryEntryDLC{
+			ForIDPrefix: dlc.ForIDPrefix,
+			ID:          dlc.ID,
+			Version:     dlc.Version,
+		})
+	}
+	for _, update := range entry.Updates {
+		result.TitleIDs = append(result.TitleIDs, update.ID)
+		result.Updates = append(result.Updates, storage.LibraryEntryUpdate{
+			ForIDPrefix:     update.ForIDPrefix,
+			ID:              update.ID,
+			Version:         update.Version,
+			ReadableVersion: update.ReadableVersion,
+		})
+	}
+	return result
+}
+
+func fromStorageEntry(entry storage.LibraryEntry) LibraryFileEntry {
+	metadata := &LibraryGameFileMetadata{
+		ExtractionType: ExtractionType(entry.ExtractionType),
+		IsMultiContent: entry.IsMultiContent,
+	}
+	for _, game := range entry.BaseGames {
+		metadata.BaseGames = append(metadata.BaseGames, SwitchFileGame{
+			IDPrefix:        game.IDPrefix,
+			ID:              game.ID,
+			Version:         game.Version,
+			Name:            game.Name,
+			ReadableVersion: game.ReadableVersion,
+			ISBN:            game.ISBN,
+		})
+	}
+	for _, dlc := range entry.DLCs {
+		metadata.DLCs = append(metadata.DLCs, SwitchFileDLC{
+			ForIDPrefix: dlc.ForIDPrefix,
+			ID:          dlc.ID,
+			Version:     dlc.Version,
+		})
+	}
+	for _, update := range entry.Updates {
+		metadata.Updates = append(metadata.Updates, SwitchFileUpdate{
+			ForIDPrefix:     update.ForIDPrefix,
+			ID:              update.ID,
+			Version:         update.Version,
+			ReadableVersion: update.ReadableVersion,
+		})
+	}
+	return LibraryFileEntry{
+		FilePath:                entry.FilePath,
+		FileSize:                entry.FileSize,
+		FileModified:            entry.FileModified,
+		IsSplit:                 entry.IsSplit,
+		LibraryGameFileMetadata: metadata,
+	}
+}
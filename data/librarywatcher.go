@@ -0,0 +1,209 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWatcherDebounce = 2 * time.Second
+)
+
+// LibraryChangedCallback is called with paths that were updated in the library after a batch of file system events.
+type LibraryChangedCallback func(paths []string)
+
+// LibraryWatcher watches scan directories and feeds changed paths into the LibraryManager.
+type LibraryWatcher struct {
+	logger      *zap.SugaredLogger
+	manager     LibraryManager
+	directories []string
+	recursive   bool
+	debounce    time.Duration
+	onChange    LibraryChangedCallback
+
+	mutex sync.Mutex
+	// flushMutex is held while pending paths are fed into the manager, Close waits for it
+	flushMutex sync.Mutex
+	watcher    *fsnotify.Watcher
+	pending    map[string]struct{}
+	timer      *time.Timer
+	done       chan struct{}
+}
+
+func NewLibraryWatcher(logger *zap.SugaredLogger, manager LibraryManager, directories []string, recursive bool, onChange LibraryChangedCallback) *LibraryWatcher {
+	return &LibraryWatcher{
+		logger:      logger,
+		manager:     manager,
+		directories: directories,
+		recursive:   recursive,
+		debounce:    defaultWatcherDebounce,
+		onChange:    onChange,
+		pending:     make(map[string]struct{}),
+	}
+}
+
+// Start starts watching all the directories. Events are handled in background until Close is called.
+func (w *LibraryWatcher) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not create watcher: %w", err)
+	}
+
+	w.mutex.Lock()
+	w.watcher = watcher
+	w.done = make(chan struct{})
+	w.mutex.Unlock()
+
+	for _, directory := range w.directories {
+		err = w.addDirectory(directory)
+		if err != nil {
+			w.logger.Warnf("could not watch directory %v: %v", directory, err)
+		}
+	}
+
+	go w.run(watcher, w.done)
+	return nil
+}
+
+// Close stops watching the directories. A flush that is already running stops before the next path, Close waits
+// for it so the manager and the callback are not used after Close returns.
+func (w *LibraryWatcher) Close() error {
+	w.mutex.Lock()
+	if w.watcher == nil {
+		w.mutex.Unlock()
+		return nil
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	close(w.done)
+	err := w.watcher.Close()
+	w.watcher = nil
+	w.mutex.Unlock()
+
+	w.flushMutex.Lock()
+	defer w.flushMutex.Unlock()
+	return err
+}
+
+func (w *LibraryWatcher) isClosed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.watcher == nil
+}
+
+func (w *LibraryWatcher) addDirectory(directory string) error {
+	if !w.recursive {
+		return w.watcher.Add(directory)
+	}
+	return filepath.WalkDir(directory, func(path string, info os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != directory && info.Name()[0] == '.' {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(path)
+	})
+}
+
+func (w *LibraryWatcher) run(watcher *fsnotify.Watcher, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warnf("watcher error: %v", err)
+		}
+	}
+}
+
+func (w *LibraryWatcher) handleEvent(event fsnotify.Event) {
+	if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) {
+		return
+	}
+	if filepath.Base(event.Name)[0] == '.' {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.watcher == nil {
+		return
+	}
+
+	if event.Has(fsnotify.Create) && w.recursive {
+		if stat, err := os.Stat(event.Name); err == nil && stat.IsDir() {
+			err = w.addDirectory(event.Name)
+			if err != nil {
+				w.logger.Warnf("could not watch directory %v: %v", event.Name, err)
+			}
+		}
+	}
+
+	w.pending[event.Name] = struct{}{}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.debounce, w.flush)
+	} else {
+		w.timer.Reset(w.debounce)
+	}
+}
+
+// flush feeds all pending paths into the library manager one at a time.
+func (w *LibraryWatcher) flush() {
+	w.flushMutex.Lock()
+	defer w.flushMutex.Unlock()
+
+	w.mutex.Lock()
+	if w.watcher == nil {
+		w.mutex.Unlock()
+		return
+	}
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	w.pending = make(map[string]struct{})
+	w.mutex.Unlock()
+
+	sort.Strings(paths)
+
+	var changed []string
+	for _, path := range paths {
+		if w.isClosed() {
+			return
+		}
+		err := w.manager.RescanPath(path)
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedExtension) {
+				continue
+			}
+			w.logger.Warnf("could not update library entry %v: %v", path, err)
+			continue
+		}
+		changed = append(changed, path)
+	}
+
+	if len(changed) > 0 && w.onChange != nil && !w.isClosed() {
+		w.onChange(changed)
+	}
+}
@@ -0,0 +1,117 @@
+package data
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/keys"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLibraryWatcher(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	manager := NewLibraryManager(zap.NewNop().Sugar(), db, keys.NewKeyProvider(), []string{dir}, true, 1)
+
+	changes := make(chan []string, 10)
+	watcher := NewLibraryWatcher(zap.NewNop().Sugar(), manager, []string{dir}, true, func(paths []string) {
+		changes <- paths
+	})
+	watcher.debounce = 50 * time.Millisecond
+	assert.Nil(t, watcher.Start())
+	defer watcher.Close()
+
+	waitForChange := func() []string {
+		select {
+		case paths := <-changes:
+			return paths
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for library change")
+			return nil
+		}
+	}
+
+	subDir := filepath.Join(dir, "sub")
+	assert.Nil(t, os.Mkdir(subDir, 0755))
+	waitForChange()
+
+	gamePath := filepath.Join(subDir, "Game [0100000000010000][v0].nsp")
+	assert.Nil(t, os.WriteFile(gamePath, []byte("base"), 0644))
+	waitForChange()
+
+	files, err := manager.GetFilesForID("0100000000010000")
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	renamedPath := filepath.Join(dir, "Game [0100000000010000][v0] renamed.nsp")
+	assert.Nil(t, os.Rename(gamePath, renamedPath))
+	waitForChange()
+
+	files, err = manager.GetFilesForID("0100000000010000")
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, renamedPath, files[0].FilePath)
+
+	assert.Nil(t, os.Remove(renamedPath))
+	waitForChange()
+
+	entries, err := manager.GetEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+}
+
+// blockingLibraryManager records rescanned paths, every rescan waits until release is closed
+type blockingLibraryManager struct {
+	LibraryManager
+	started chan string
+	release chan struct{}
+
+	mutex     sync.Mutex
+	rescanned []string
+}
+
+func (m *blockingLibraryManager) RescanPath(path string) error {
+	m.started <- path
+	<-m.release
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rescanned = append(m.rescanned, path)
+	return nil
+}
+
+func TestLibraryWatcherCloseStopsFlush(t *testing.T) {
+	manager := &blockingLibraryManager{started: make(chan string, 10), release: make(chan struct{})}
+	changed := false
+	watcher := NewLibraryWatcher(zap.NewNop().Sugar(), manager, nil, false, func(paths []string) {
+		changed = true
+	})
+	assert.Nil(t, watcher.Start())
+
+	watcher.mutex.Lock()
+	watcher.pending = map[string]struct{}{"/a.nsp": {}, "/b.nsp": {}}
+	watcher.mutex.Unlock()
+	go watcher.flush()
+	assert.Equal(t, "/a.nsp", <-manager.started)
+
+	closed := make(chan error)
+	go func() {
+		closed <- watcher.Close()
+	}()
+	assert.Eventually(t, watcher.isClosed, 5*time.Second, time.Millisecond)
+	close(manager.release)
+	assert.Nil(t, <-closed)
+
+	// flush started after Close does nothing
+	watcher.pending = map[string]struct{}{"/c.nsp": {}}
+	watcher.flush()
+
+	assert.Equal(t, []string{"/a.nsp"}, manager.rescanned)
+	assert.False(t, changed)
+}
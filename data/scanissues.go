@@ -0,0 +1,111 @@
+package data
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type ScanIssueReason string
+
+const (
+	ScanIssueReasonUnsupportedExtension  ScanIssueReason = "unsupportedExtension"
+	ScanIssueReasonFileAccess            ScanIssueReason = "fileAccess"
+	ScanIssueReasonFailedToReadMetadata  ScanIssueReason = "failedToReadMetadata"
+	ScanIssueReasonFailedToReadTitleID   ScanIssueReason = "failedToReadTitleID"
+	ScanIssueReasonFailedToReadVersion   ScanIssueReason = "failedToReadVersion"
+	ScanIssueReasonFailedToCalculateHash ScanIssueReason = "failedToCalculateChecksum"
+	ScanIssueReasonUnknown               ScanIssueReason = "unknown"
+)
+
+// ScanIssue describes a file that was skipped or failed to be processed during a scan.
+type ScanIssue struct {
+	FilePath string
+	Reason   ScanIssueReason
+	Err      error
+	SeenAt   time.Time
+}
+
+// ErrorChain returns messages of the issue error and all the errors wrapped by it.
+func (i ScanIssue) ErrorChain() []string {
+	var chain []string
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		chain = append(chain, err.Error())
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, wrapped := range e.Unwrap() {
+				walk(wrapped)
+			}
+		}
+	}
+	walk(i.Err)
+	return chain
+}
+
+func newScanIssue(filePath string, err error, seenAt time.Time) ScanIssue {
+	return ScanIssue{
+		FilePath: filePath,
+		Reason:   scanIssueReason(err),
+		Err:      err,
+		SeenAt:   seenAt,
+	}
+}
+
+func scanIssueReason(err error) ScanIssueReason {
+	switch {
+	case errors.Is(err, ErrUnsupportedExtension):
+		return ScanIssueReasonUnsupportedExtension
+	case errors.Is(err, ErrFailedToReadTitleID):
+		return ScanIssueReasonFailedToReadTitleID
+	case errors.Is(err, ErrFailedToReadTitleVersion):
+		return ScanIssueReasonFailedToReadVersion
+	case errors.Is(err, ErrFailedToReadFileMetadata):
+		return ScanIssueReasonFailedToReadMetadata
+	case errors.Is(err, ErrFailedToCalculateChecksum):
+		return ScanIssueReasonFailedToCalculateHash
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return ScanIssueReasonFileAccess
+	}
+	return ScanIssueReasonUnknown
+}
+
+// scanIssues holds the most recent issue of every file.
+type scanIssues map[string]ScanIssue
+
+func (s scanIssues) add(errs map[string]error, seenAt time.Time) {
+	for filePath, err := range errs {
+		s[filePath] = newScanIssue(filePath, err, seenAt)
+	}
+}
+
+// removePath removes issues of a file or all the files inside a directory.
+func (s scanIssues) removePath(fullPath string) {
+	dirPrefix := fullPath + string(os.PathSeparator)
+	for filePath := range s {
+		if filePath == fullPath || strings.HasPrefix(filePath, dirPrefix) {
+			delete(s, filePath)
+		}
+	}
+}
+
+func (s scanIssues) list() []ScanIssue {
+	result := make([]ScanIssue, 0, len(s))
+	for _, issue := range s {
+		result = append(result, issue)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FilePath < result[j].FilePath
+	})
+	return result
+}
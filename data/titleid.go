@@ -0,0 +1,25 @@
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BaseTitleID returns ID of the base title of a base game, update or DLC ID. Updates are the base ID with 800 in
+// the last three digits, DLCs are the base ID increased by 0x1000 and the DLC index. Invalid IDs are returned
+// uppercased as they are.
+func BaseTitleID(id string) string {
+	value, err := strconv.ParseUint(id, 16, 64)
+	if err != nil || len(id) != 16 {
+		return strings.ToUpper(id)
+	}
+	switch value & 0xFFF {
+	case 0:
+	case 0x800:
+		value &^= 0xFFF
+	default:
+		value = (value - 0x1000) &^ 0xFFF
+	}
+	return fmt.Sprintf("%016X", value)
+}
@@ -0,0 +1,21 @@
+package data
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBaseTitleID(t *testing.T) {
+	tests := map[string]string{
+		"0100000000010000": "0100000000010000",
+		"0100000000010800": "0100000000010000",
+		"0100000000011001": "0100000000010000",
+		"01007ef00011e800": "01007EF00011E000",
+		"01007EF00011F001": "01007EF00011E000",
+		"01007EF00011F0FF": "01007EF00011E000",
+		"invalid":          "INVALID",
+	}
+	for id, expected := range tests {
+		assert.Equal(t, expected, BaseTitleID(id), id)
+	}
+}
@@ -1,5 +1,25 @@
 import styles from "./Footer.module.css";
+import { useCatalogUpdated } from "../../hooks/useCatalogUpdated";
 
 export default function Footer() {
-  return <div className={styles.footer}>NUT server not active...</div>;
+  const { titles, dismiss } = useCatalogUpdated();
+
+  return (
+    <div className={styles.footer}>
+      NUT server not active...
+      {titles.length > 0 && (
+        <span>
+          {" "}
+          New content for{" "}
+          {titles
+            .map(
+              (title) =>
+                `${title.name} (${title.newUpdates.length} updates, ${title.newDLC.length} DLC)`
+            )
+            .join(", ")}{" "}
+          <button onClick={dismiss}>Dismiss</button>
+        </span>
+      )}
+    </div>
+  );
 }
@@ -1,38 +1,92 @@
-import { useEffect, useState } from "react";
-import { LoadCatalog } from "../../wailsjs/go/main/App";
+import { ImportCatalog, LoadCatalog } from "../../wailsjs/go/main/App";
 import { main } from "../../wailsjs/go/models";
-import { useQuery } from "react-query";
+import { useInfiniteQuery, useMutation, useQueryClient } from "react-query";
+
+export type CatalogOwnership = "" | "owned" | "notOwned" | "missingContent";
 
 export type CatalogFilters = {
   name: string | null;
+  sortBy?: string;
+  sortDescending?: boolean;
+  publisher?: string;
+  developer?: string;
+  category?: string[];
+  language?: string[];
+  minSize?: number;
+  maxSize?: number;
+  releasedAfter?: string;
+  releasedBefore?: string;
+  isDemo?: boolean;
+  ownership?: CatalogOwnership;
 };
 
 type HookReturnType = {
-  data: main.CatalogPage | undefined;
+  data: main.CatalogPage[] | undefined;
   isLoading: boolean;
   error: unknown;
+  hasNextPage: boolean;
+  isFetchingNextPage: boolean;
+  fetchNextPage: () => void;
 };
 
 export const useCatalog = (
-  page: number,
   pageSize: number,
   filters?: CatalogFilters
 ): HookReturnType => {
-  const { data, isLoading, error } = useQuery(
-    ["catalog", filters, page, pageSize],
-    async () =>
+  const {
+    data,
+    isLoading,
+    error,
+    hasNextPage,
+    isFetchingNextPage,
+    fetchNextPage,
+  } = useInfiniteQuery(
+    ["catalog", filters, pageSize],
+    async ({ pageParam = "" }) =>
       await LoadCatalog({
-        cursor: page * pageSize,
+        cursor: pageParam,
         limit: pageSize,
         region: [],
-        sortBy: "name",
+        name: filters?.name || undefined,
+        // search results are ordered by relevance
+        sortBy: filters?.sortBy || (filters?.name ? "relevance" : "name"),
+        sortDescending: filters?.sortDescending ?? false,
+        publisher: filters?.publisher || undefined,
+        developer: filters?.developer || undefined,
+        category: filters?.category ?? [],
+        language: filters?.language ?? [],
+        minSize: filters?.minSize,
+        maxSize: filters?.maxSize,
+        releasedAfter: filters?.releasedAfter || undefined,
+        releasedBefore: filters?.releasedBefore || undefined,
+        isDemo: filters?.isDemo,
+        ownership: filters?.ownership ?? "",
       }),
-    { keepPreviousData: true }
+    {
+      keepPreviousData: true,
+      // cursors point at the last loaded title, so pages stay consistent even if the catalog changes meanwhile
+      getNextPageParam: (lastPage) =>
+        lastPage.isLastPage ? undefined : lastPage.nextCursor,
+    }
   );
 
   return {
-    data,
+    data: data?.pages,
     isLoading,
     error,
+    hasNextPage: hasNextPage ?? false,
+    isFetchingNextPage,
+    fetchNextPage: () => fetchNextPage(),
   };
 };
+
+export const useImportCatalog = () => {
+  const queryClient = useQueryClient();
+  const { mutate, isLoading, error } = useMutation(
+    async () => await ImportCatalog(),
+    {
+      onSuccess: () => queryClient.invalidateQueries("catalog"),
+    }
+  );
+  return { importCatalog: () => mutate(), isLoading, error };
+};
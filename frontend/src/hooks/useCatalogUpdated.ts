@@ -0,0 +1,30 @@
+import { useEffect, useState } from "react";
+import { useQueryClient } from "react-query";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+import {
+  CatalogUpdatedTitle,
+  EventMessage,
+  EventType,
+} from "../model/events";
+
+export const useCatalogUpdated = () => {
+  const queryClient = useQueryClient();
+  const [titles, setTitles] = useState<CatalogUpdatedTitle[]>([]);
+
+  useEffect(() => {
+    const unsubscribe = EventsOn(
+      EventType.CatalogUpdated,
+      (payload: EventMessage) => {
+        if (payload.type !== EventType.CatalogUpdated) {
+          return;
+        }
+        setTitles(payload.data.titles);
+        queryClient.invalidateQueries("catalog");
+        queryClient.invalidateQueries("library");
+      }
+    );
+    return unsubscribe;
+  }, [queryClient]);
+
+  return { titles, dismiss: () => setTitles([]) };
+};
@@ -1,11 +1,13 @@
 import { useQuery } from "react-query";
 import { LoadLibraryFiles } from "../../wailsjs/go/main/App";
+import { useLibraryChanged } from "./useLibraryChanged";
 
 export const useFiles = () => {
   const { data, isLoading, error } = useQuery(
     "files",
     async () => await LoadLibraryFiles()
   );
+  useLibraryChanged("files");
 
   return {
     data,
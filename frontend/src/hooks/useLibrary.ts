@@ -1,5 +1,6 @@
 import { useQuery } from "react-query";
-import { LoadLibraryGames } from "../../wailsjs/go/main/App";
+import { LoadLibraryGames, SearchLibrary } from "../../wailsjs/go/main/App";
+import { useLibraryChanged } from "./useLibraryChanged";
 
 export const useLibrary = () => {
   const { data, isLoading, error } = useQuery("library", async () => {
@@ -7,6 +8,7 @@ export const useLibrary = () => {
     console.log(games);
     return games;
   });
+  useLibraryChanged("library");
 
   return {
     data,
@@ -14,3 +16,19 @@ export const useLibrary = () => {
     error,
   };
 };
+
+// useLibrarySearch returns IDs of titles matching the query, best matches first, or undefined for empty query
+export const useLibrarySearch = (query: string) => {
+  const { data, isLoading, error } = useQuery(
+    ["librarySearch", query],
+    async () => await SearchLibrary(query),
+    { enabled: query.trim() !== "", keepPreviousData: true }
+  );
+  useLibraryChanged("librarySearch");
+
+  return {
+    data: query.trim() !== "" ? data : undefined,
+    isLoading,
+    error,
+  };
+};
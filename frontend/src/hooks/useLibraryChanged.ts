@@ -0,0 +1,21 @@
+import { useEffect } from "react";
+import { useQueryClient } from "react-query";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+import { EventMessage, EventType } from "../model/events";
+
+export const useLibraryChanged = (queryKey: string) => {
+  const queryClient = useQueryClient();
+
+  useEffect(() => {
+    const unsubscribe = EventsOn(
+      EventType.LibraryChanged,
+      (payload: EventMessage) => {
+        if (payload.type !== EventType.LibraryChanged) {
+          return;
+        }
+        queryClient.invalidateQueries(queryKey);
+      }
+    );
+    return unsubscribe;
+  }, [queryClient, queryKey]);
+};
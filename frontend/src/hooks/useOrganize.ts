@@ -0,0 +1,54 @@
+import { useEffect, useState } from "react";
+import { useMutation } from "react-query";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+import {
+  OrganizeLibrary,
+  PlanOrganizeLibrary,
+  UndoOrganizeLibrary,
+} from "../../wailsjs/go/main/App";
+import {
+  EventMessage,
+  EventType,
+  OrganizeProgressPayload,
+} from "../model/events";
+
+export const useOrganize = () => {
+  const [progress, setProgress] = useState<OrganizeProgressPayload | null>(
+    null
+  );
+
+  useEffect(() => {
+    const unsubscribe = EventsOn(
+      EventType.OrganizeProgress,
+      (payload: EventMessage) => {
+        if (payload.type !== EventType.OrganizeProgress) {
+          return;
+        }
+        setProgress(payload.data);
+      }
+    );
+    return unsubscribe;
+  }, [setProgress]);
+
+  const planMutation = useMutation(async () => await PlanOrganizeLibrary());
+  const organizeMutation = useMutation(async () => await OrganizeLibrary(), {
+    onSuccess: () => planMutation.reset(),
+  });
+  const undoMutation = useMutation(async () => await UndoOrganizeLibrary(), {
+    onSuccess: () => planMutation.reset(),
+  });
+
+  return {
+    plan: () => planMutation.mutate(),
+    organize: () => organizeMutation.mutate(),
+    undo: () => undoMutation.mutate(),
+    preview: planMutation.data,
+    progress,
+    result: organizeMutation.data ?? undoMutation.data,
+    isLoading:
+      planMutation.isLoading ||
+      organizeMutation.isLoading ||
+      undoMutation.isLoading,
+    error: planMutation.error ?? organizeMutation.error ?? undoMutation.error,
+  };
+};
@@ -0,0 +1,42 @@
+import { useMutation, useQuery, useQueryClient } from "react-query";
+import {
+  CleanupLibrary,
+  LoadCleanupCandidates,
+  LoadQuarantine,
+  RestoreQuarantined,
+} from "../../wailsjs/go/main/App";
+import { useLibraryChanged } from "./useLibraryChanged";
+
+export const useQuarantine = () => {
+  const queryClient = useQueryClient();
+  const candidates = useQuery(
+    "cleanupCandidates",
+    async () => await LoadCleanupCandidates()
+  );
+  const quarantine = useQuery(
+    "quarantine",
+    async () => await LoadQuarantine()
+  );
+  useLibraryChanged("cleanupCandidates");
+  useLibraryChanged("quarantine");
+
+  const invalidate = () => {
+    queryClient.invalidateQueries("cleanupCandidates");
+    queryClient.invalidateQueries("quarantine");
+  };
+  const cleanupMutation = useMutation(async () => await CleanupLibrary(), {
+    onSuccess: invalidate,
+  });
+  const restoreMutation = useMutation(
+    async (paths: string[]) => await RestoreQuarantined(paths),
+    { onSuccess: invalidate }
+  );
+
+  return {
+    candidates: candidates.data,
+    quarantine: quarantine.data,
+    cleanup: () => cleanupMutation.mutate(),
+    restore: (paths: string[]) => restoreMutation.mutate(paths),
+    isLoading: cleanupMutation.isLoading || restoreMutation.isLoading,
+  };
+};
@@ -0,0 +1,17 @@
+import { useQuery } from "react-query";
+import { LoadScanIssues } from "../../wailsjs/go/main/App";
+import { useLibraryChanged } from "./useLibraryChanged";
+
+export const useScanIssues = () => {
+  const { data, isLoading, error } = useQuery(
+    "scanIssues",
+    async () => await LoadScanIssues()
+  );
+  useLibraryChanged("scanIssues");
+
+  return {
+    data,
+    isLoading,
+    error,
+  };
+};
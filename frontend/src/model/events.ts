@@ -1,5 +1,8 @@
 export enum EventType {
   StartupProgress = "startupProgress",
+  LibraryChanged = "libraryChanged",
+  OrganizeProgress = "organizeProgress",
+  CatalogUpdated = "catalogUpdated",
 }
 
 export type StartupProgressPayload = {
@@ -10,7 +13,43 @@ export type StartupProgressPayload = {
   total: number;
 };
 
-export type EventMessage = {
-  type: EventType.StartupProgress;
-  data: StartupProgressPayload;
+export type LibraryChangedPayload = {
+  paths: string[];
 };
+
+export type OrganizeProgressPayload = {
+  completed: boolean;
+  running: boolean;
+  message: string;
+  current: number;
+  total: number;
+};
+
+export type CatalogUpdatedTitle = {
+  titleID: string;
+  name: string;
+  newUpdates: { version: number; releaseDate: string }[];
+  newDLC: { titleID: string; name: string }[];
+};
+
+export type CatalogUpdatedPayload = {
+  titles: CatalogUpdatedTitle[];
+};
+
+export type EventMessage =
+  | {
+      type: EventType.StartupProgress;
+      data: StartupProgressPayload;
+    }
+  | {
+      type: EventType.LibraryChanged;
+      data: LibraryChangedPayload;
+    }
+  | {
+      type: EventType.OrganizeProgress;
+      data: OrganizeProgressPayload;
+    }
+  | {
+      type: EventType.CatalogUpdated;
+      data: CatalogUpdatedPayload;
+    };
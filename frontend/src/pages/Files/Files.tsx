@@ -1,8 +1,18 @@
 import { useFiles } from "../../hooks/useFiles";
 import {useLibrary} from "../../hooks/useLibrary";
+import { useScanIssues } from "../../hooks/useScanIssues";
+import { useQuarantine } from "../../hooks/useQuarantine";
 
 export default function Files() {
   const { data, isLoading, error } = useLibrary();
+  const { data: issues } = useScanIssues();
+  const {
+    candidates,
+    quarantine,
+    cleanup,
+    restore,
+    isLoading: isCleanupLoading,
+  } = useQuarantine();
 
   return (
     <div>
@@ -10,6 +20,89 @@ export default function Files() {
         {isLoading && "loading"} {`${error}`}
       </div>
       <div>{data && data.map((e) => <div>{JSON.stringify(e)}</div>)}</div>
+      {issues && issues.length > 0 && (
+        <div>
+          <h3>Skipped files</h3>
+          <table>
+            <thead>
+              <tr>
+                <th>File</th>
+                <th>Reason</th>
+                <th>Details</th>
+                <th>Seen at</th>
+              </tr>
+            </thead>
+            <tbody>
+              {issues.map((issue) => (
+                <tr key={issue.filePath}>
+                  <td>{issue.filePath}</td>
+                  <td>{issue.reason}</td>
+                  <td>{issue.errors?.[0]}</td>
+                  <td>{issue.seenAt}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      )}
+      {candidates && candidates.length > 0 && (
+        <div>
+          <h3>Old updates and duplicates</h3>
+          <button disabled={isCleanupLoading} onClick={() => cleanup()}>
+            Move to quarantine
+          </button>
+          <table>
+            <thead>
+              <tr>
+                <th>File</th>
+                <th>Reason</th>
+                <th>Kept file</th>
+              </tr>
+            </thead>
+            <tbody>
+              {candidates.map((candidate) => (
+                <tr key={candidate.filePath}>
+                  <td>{candidate.filePath}</td>
+                  <td>{candidate.reason}</td>
+                  <td>{candidate.keptFilePath}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      )}
+      {quarantine && quarantine.length > 0 && (
+        <div>
+          <h3>Quarantine</h3>
+          <table>
+            <thead>
+              <tr>
+                <th>File</th>
+                <th>Reason</th>
+                <th>Quarantined at</th>
+                <th></th>
+              </tr>
+            </thead>
+            <tbody>
+              {quarantine.map((entry) => (
+                <tr key={entry.quarantinePath}>
+                  <td>{entry.originalPath}</td>
+                  <td>{entry.reason}</td>
+                  <td>{entry.quarantinedAt}</td>
+                  <td>
+                    <button
+                      disabled={isCleanupLoading}
+                      onClick={() => restore([entry.quarantinePath])}
+                    >
+                      Restore
+                    </button>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        </div>
+      )}
     </div>
   );
 }
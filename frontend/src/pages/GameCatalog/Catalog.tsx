@@ -1,27 +1,107 @@
-import { useCatalog } from "../../hooks/useCatalog";
+import {
+  CatalogOwnership,
+  useCatalog,
+  useImportCatalog,
+} from "../../hooks/useCatalog";
 import { CatalogGameCard } from "./CatalogGameCard";
 
 import styles from "./Catalog.module.css";
+import { useEffect, useRef, useState } from "react";
+import AppTextField from "../../components/TextField/TextField";
+import { AppSelect, AppSelectItem } from "../../components/Select/Select";
 
 export default function Catalog() {
-  const { data, isLoading, error } = useCatalog(0, 100);
+  const [query, setQuery] = useState("");
+  const [sortBy, setSortBy] = useState("");
+  const [ownership, setOwnership] = useState<CatalogOwnership>("");
+  const {
+    data,
+    isLoading,
+    error,
+    hasNextPage,
+    isFetchingNextPage,
+    fetchNextPage,
+  } = useCatalog(100, {
+    name: query,
+    sortBy,
+    // newest and largest titles first
+    sortDescending: sortBy === "releaseDate" || sortBy === "size",
+    ownership,
+  });
+  const { importCatalog, isLoading: isImporting } = useImportCatalog();
+
+  // load next page when the end of the list scrolls into view
+  const listEnd = useRef<HTMLDivElement>(null);
+  useEffect(() => {
+    if (!listEnd.current || !hasNextPage) {
+      return;
+    }
+    const observer = new IntersectionObserver((entries) => {
+      if (entries[0].isIntersecting && !isFetchingNextPage) {
+        fetchNextPage();
+      }
+    });
+    observer.observe(listEnd.current);
+    return () => observer.disconnect();
+  }, [hasNextPage, isFetchingNextPage, fetchNextPage]);
 
   return (
     <div>
-      <div>Filters</div>
+      <div>
+        Filters{" "}
+        <AppTextField label="Search" value={query} onChange={setQuery} />
+        <AppSelect
+          label="Sort by"
+          items={[
+            { key: "", label: "Default" },
+            { key: "name", label: "Name" },
+            { key: "publisher", label: "Publisher" },
+            { key: "developer", label: "Developer" },
+            { key: "releaseDate", label: "Release date" },
+            { key: "size", label: "Size" },
+          ]}
+          onSelectionChange={(key) => setSortBy(key as string)}
+        >
+          {(item) => (
+            <AppSelectItem key={item.key} id={item.key}>
+              {item.label}
+            </AppSelectItem>
+          )}
+        </AppSelect>
+        <AppSelect
+          label="Show"
+          items={[
+            { key: "", label: "All titles" },
+            { key: "owned", label: "Owned" },
+            { key: "notOwned", label: "Not owned" },
+            { key: "missingContent", label: "Missing updates or DLC" },
+          ]}
+          onSelectionChange={(key) => setOwnership(key as CatalogOwnership)}
+        >
+          {(item) => (
+            <AppSelectItem key={item.key} id={item.key}>
+              {item.label}
+            </AppSelectItem>
+          )}
+        </AppSelect>
+        <button disabled={isImporting} onClick={importCatalog}>
+          Import catalog
+        </button>
+      </div>
       <div>
         {isLoading && "loading"} {`${error}`}
       </div>
 
       <div className={styles.gameList}>
-        {data?.games
+        {data
+          ?.flatMap((page) => page.games ?? [])
           .filter((e) => e.titleID != "" && e.name != "")
           // .filter((e) => e.versions.length > 0)
-          .slice(0, 100)
           .map((e) => {
             return <CatalogGameCard data={e} key={e.titleID} />;
           })}
       </div>
+      <div ref={listEnd}>{isFetchingNextPage && "loading"}</div>
     </div>
   );
 }
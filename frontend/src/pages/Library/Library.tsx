@@ -3,7 +3,7 @@ import {
   IconMessageCircleExclamation,
 } from "@tabler/icons-react";
 import Spinner from "../../components/Spinner/Spinner";
-import { useLibrary } from "../../hooks/useLibrary";
+import { useLibrary, useLibrarySearch } from "../../hooks/useLibrary";
 import GameCard from "./GameCard";
 import styles from "./Library.module.css";
 import { AppSelect, AppSelectItem } from "../../components/Select/Select";
@@ -14,6 +14,8 @@ import AppTextField from "../../components/TextField/TextField";
 
 export default function Library() {
   const { data: games, isLoading, error } = useLibrary();
+  const [query, setQuery] = useState("");
+  const { data: searchResults } = useLibrarySearch(query);
   const [sortMode, setSortMode] = useState<"id" | "name" | "region" | "issues">(
     "name"
   );
@@ -29,6 +31,23 @@ export default function Library() {
     issues: (a, b) => 0,
   };
 
+  // with a search query games are filtered and ordered by relevance
+  const visibleGames = (() => {
+    if (!searchResults) {
+      return games?.sort(sorters[sortMode]);
+    }
+    const ranks = new Map(
+      searchResults.map((id, rank) => [id.toUpperCase(), rank])
+    );
+    return games
+      ?.filter((game) => ranks.has(game.titleID.toUpperCase()))
+      .sort(
+        (a, b) =>
+          ranks.get(a.titleID.toUpperCase())! -
+          ranks.get(b.titleID.toUpperCase())!
+      );
+  })();
+
   if (isLoading) {
     return (
       <div className={styles.page}>
@@ -78,10 +97,14 @@ export default function Library() {
             )}
           </AppSelect>
           <AppTextField label="ID"></AppTextField>
-          <AppTextField label="Name"></AppTextField>
+          <AppTextField
+            label="Search"
+            value={query}
+            onChange={setQuery}
+          ></AppTextField>
         </div>
         <div className={styles.gameGrid}>
-          {games?.sort(sorters[sortMode]).map((game) => (
+          {visibleGames?.map((game) => (
             <GameCard key={game.titleID} game={game}></GameCard>
           ))}
         </div>
@@ -9,6 +9,8 @@ import {
 import styles from "./OrganizeFilesModal.module.css";
 import Toggle from "../../components/Toggle/Toggle";
 import AppTextField from "../../components/TextField/TextField";
+import Progress from "../../components/Progress/Progress";
+import { useOrganize } from "../../hooks/useOrganize";
 
 type OrganizeFilesModalProps = {
   isOpened: boolean;
@@ -19,7 +21,8 @@ export default function OrganizeFilesModal({
   isOpened,
   onOpen,
 }: OrganizeFilesModalProps) {
-  console.log("xD", isOpened);
+  const { plan, organize, undo, preview, progress, result, isLoading, error } =
+    useOrganize();
 
   return (
     <>
@@ -61,7 +64,62 @@ export default function OrganizeFilesModal({
                   <AppTextField label="File name pattern"></AppTextField>
                 </div>
               </div>
-              <button>Start</button>
+              {isLoading && progress && (
+                <Progress
+                  label="Organizing"
+                  details={progress.message}
+                  value={progress.current}
+                  maxValue={progress.total}
+                />
+              )}
+              {result && (
+                <div>
+                  <div>{`Moved ${result.moved} files`}</div>
+                  {result.errors.map((e) => (
+                    <div key={e.filePath}>{`${e.filePath}: ${e.error}`}</div>
+                  ))}
+                </div>
+              )}
+              {preview && (
+                <div className={styles.plan}>
+                  {preview.operations.length === 0 && (
+                    <div>Library is already organized</div>
+                  )}
+                  {preview.operations.map((operation, idx) => (
+                    <div
+                      key={idx}
+                      className={
+                        operation.issues.length > 0
+                          ? styles.planIssue
+                          : undefined
+                      }
+                    >
+                      <div>{`${operation.type}: ${
+                        operation.from || operation.to
+                      }`}</div>
+                      {operation.type === "move" && (
+                        <div>{`→ ${operation.to}`}</div>
+                      )}
+                      {operation.issues.length > 0 && (
+                        <div>{`skipped: ${operation.issues.join(", ")}`}</div>
+                      )}
+                    </div>
+                  ))}
+                </div>
+              )}
+              {error && <div>{`${error}`}</div>}
+              <button disabled={isLoading} onClick={() => plan()}>
+                Preview
+              </button>
+              <button
+                disabled={isLoading || !preview}
+                onClick={() => organize()}
+              >
+                Start
+              </button>
+              <button disabled={isLoading} onClick={() => undo()}>
+                Undo last organize
+              </button>
             </Dialog>
           </Modal>
         </ModalOverlay>
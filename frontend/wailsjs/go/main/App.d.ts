@@ -2,10 +2,44 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function CleanupLibrary():Promise<main.LibraryCleanupResult>;
+
+export function DeleteConsole(arg1:string):Promise<void>;
+
+export function ExportMissingUpdates(arg1:string):Promise<string>;
+
+export function ImportCatalog():Promise<string>;
+
 export function LoadCatalog(arg1:main.CatalogFilters):Promise<main.CatalogPage>;
 
+export function LoadCleanupCandidates():Promise<Array<main.LibraryCleanupCandidate>>;
+
+export function LoadConsoleInstalls():Promise<Array<main.LibraryTitleInstalls>>;
+
+export function LoadConsoles():Promise<Array<main.Console>>;
+
+export function LoadLibraryCompletion():Promise<main.LibraryCompletion>;
+
 export function LoadLibraryFiles():Promise<Array<main.LibraryFileEntry>>;
 
 export function LoadLibraryGames():Promise<Array<main.LibrarySwitchGame>>;
 
+export function LoadMissingDLC():Promise<Array<main.LibraryMissingDLCTitle>>;
+
+export function LoadMissingUpdates():Promise<Array<main.LibraryMissingUpdate>>;
+
+export function LoadQuarantine():Promise<Array<main.LibraryQuarantineEntry>>;
+
+export function LoadScanIssues():Promise<Array<main.LibraryScanIssue>>;
+
+export function OrganizeLibrary():Promise<main.LibraryOrganizeResult>;
+
+export function PlanOrganizeLibrary():Promise<main.LibraryOrganizePlan>;
+
 export function RequestStartupProgress():Promise<void>;
+
+export function RestoreQuarantined(arg1:Array<string>):Promise<main.LibraryCleanupResult>;
+
+export function SearchLibrary(arg1:string):Promise<Array<string>>;
+
+export function UndoOrganizeLibrary():Promise<main.LibraryOrganizeResult>;
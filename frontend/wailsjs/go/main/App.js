@@ -2,10 +2,42 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CleanupLibrary() {
+  return window['go']['main']['App']['CleanupLibrary']();
+}
+
+export function DeleteConsole(arg1) {
+  return window['go']['main']['App']['DeleteConsole'](arg1);
+}
+
+export function ExportMissingUpdates(arg1) {
+  return window['go']['main']['App']['ExportMissingUpdates'](arg1);
+}
+
+export function ImportCatalog() {
+  return window['go']['main']['App']['ImportCatalog']();
+}
+
 export function LoadCatalog(arg1) {
   return window['go']['main']['App']['LoadCatalog'](arg1);
 }
 
+export function LoadCleanupCandidates() {
+  return window['go']['main']['App']['LoadCleanupCandidates']();
+}
+
+export function LoadConsoleInstalls() {
+  return window['go']['main']['App']['LoadConsoleInstalls']();
+}
+
+export function LoadConsoles() {
+  return window['go']['main']['App']['LoadConsoles']();
+}
+
+export function LoadLibraryCompletion() {
+  return window['go']['main']['App']['LoadLibraryCompletion']();
+}
+
 export function LoadLibraryFiles() {
   return window['go']['main']['App']['LoadLibraryFiles']();
 }
@@ -14,6 +46,42 @@ export function LoadLibraryGames() {
   return window['go']['main']['App']['LoadLibraryGames']();
 }
 
+export function LoadMissingDLC() {
+  return window['go']['main']['App']['LoadMissingDLC']();
+}
+
+export function LoadMissingUpdates() {
+  return window['go']['main']['App']['LoadMissingUpdates']();
+}
+
+export function LoadQuarantine() {
+  return window['go']['main']['App']['LoadQuarantine']();
+}
+
+export function LoadScanIssues() {
+  return window['go']['main']['App']['LoadScanIssues']();
+}
+
+export function OrganizeLibrary() {
+  return window['go']['main']['App']['OrganizeLibrary']();
+}
+
+export function PlanOrganizeLibrary() {
+  return window['go']['main']['App']['PlanOrganizeLibrary']();
+}
+
 export function RequestStartupProgress() {
   return window['go']['main']['App']['RequestStartupProgress']();
 }
+
+export function RestoreQuarantined(arg1) {
+  return window['go']['main']['App']['RestoreQuarantined'](arg1);
+}
+
+export function SearchLibrary(arg1) {
+  return window['go']['main']['App']['SearchLibrary'](arg1);
+}
+
+export function UndoOrganizeLibrary() {
+  return window['go']['main']['App']['UndoOrganizeLibrary']();
+}
@@ -24,10 +24,21 @@ export namespace main {
 	}
 	export class CatalogFilters {
 	    sortBy: string;
+	    sortDescending: boolean;
 	    name?: string;
 	    id?: string;
 	    region: string[];
-	    cursor: number;
+	    publisher?: string;
+	    developer?: string;
+	    category: string[];
+	    language: string[];
+	    minSize?: number;
+	    maxSize?: number;
+	    releasedAfter?: string;
+	    releasedBefore?: string;
+	    isDemo?: boolean;
+	    ownership: string;
+	    cursor: string;
 	    limit: number;
 	
 	    static createFrom(source: any = {}) {
@@ -37,9 +48,20 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.sortBy = source["sortBy"];
+	        this.sortDescending = source["sortDescending"];
 	        this.name = source["name"];
 	        this.id = source["id"];
 	        this.region = source["region"];
+	        this.publisher = source["publisher"];
+	        this.developer = source["developer"];
+	        this.category = source["category"];
+	        this.language = source["language"];
+	        this.minSize = source["minSize"];
+	        this.maxSize = source["maxSize"];
+	        this.releasedAfter = source["releasedAfter"];
+	        this.releasedBefore = source["releasedBefore"];
+	        this.isDemo = source["isDemo"];
+	        this.ownership = source["ownership"];
 	        this.cursor = source["cursor"];
 	        this.limit = source["limit"];
 	    }
@@ -69,6 +91,11 @@ export namespace main {
 	    description: string;
 	    intro: string;
 	    publisher: string;
+	    developer: string;
+	    category: string[];
+	    languages: string[];
+	    size: number;
+	    isDemo: boolean;
 	    screenshots: string[];
 	    dlcs: CatalogDLCData[];
 	    versions: CatalogVersionData[];
@@ -89,6 +116,11 @@ export namespace main {
 	        this.description = source["description"];
 	        this.intro = source["intro"];
 	        this.publisher = source["publisher"];
+	        this.developer = source["developer"];
+	        this.category = source["category"];
+	        this.languages = source["languages"];
+	        this.size = source["size"];
+	        this.isDemo = source["isDemo"];
 	        this.screenshots = source["screenshots"];
 	        this.dlcs = this.convertValues(source["dlcs"], CatalogDLCData);
 	        this.versions = this.convertValues(source["versions"], CatalogVersionData);
@@ -115,7 +147,7 @@ export namespace main {
 	export class CatalogPage {
 	    games: CatalogSwitchGame[];
 	    totalTitles: number;
-	    nextCursor: number;
+	    nextCursor: string;
 	    isLastPage: boolean;
 	
 	    static createFrom(source: any = {}) {
@@ -308,6 +340,11 @@ export namespace main {
 	    description: string;
 	    intro: string;
 	    publisher: string;
+	    developer: string;
+	    category: string[];
+	    languages: string[];
+	    size: number;
+	    isDemo: boolean;
 	    screenshots: string[];
 	    inLibrary: boolean;
 	    files: LibraryGameDataFile[];
@@ -332,6 +369,11 @@ export namespace main {
 	        this.description = source["description"];
 	        this.intro = source["intro"];
 	        this.publisher = source["publisher"];
+	        this.developer = source["developer"];
+	        this.category = source["category"];
+	        this.languages = source["languages"];
+	        this.size = source["size"];
+	        this.isDemo = source["isDemo"];
 	        this.screenshots = source["screenshots"];
 	        this.inLibrary = source["inLibrary"];
 	        this.files = this.convertValues(source["files"], LibraryGameDataFile);
@@ -359,6 +401,378 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class LibraryScanIssue {
+	    filePath: string;
+	    reason: string;
+	    errors: string[];
+	    seenAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryScanIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.reason = source["reason"];
+	        this.errors = source["errors"];
+	        this.seenAt = source["seenAt"];
+	    }
+	}
+	export class LibraryMissingUpdate {
+	    titleID: string;
+	    updateID: string;
+	    name: string;
+	    localVersion: number;
+	    localReadableVersion: string;
+	    latestVersion: number;
+	    latestReleaseDate: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryMissingUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.titleID = source["titleID"];
+	        this.updateID = source["updateID"];
+	        this.name = source["name"];
+	        this.localVersion = source["localVersion"];
+	        this.localReadableVersion = source["localReadableVersion"];
+	        this.latestVersion = source["latestVersion"];
+	        this.latestReleaseDate = source["latestReleaseDate"];
+	    }
+	}
+	export class LibraryMissingDLCTitle {
+	    titleID: string;
+	    name: string;
+	    ownedDLC: number;
+	    missingDLC: CatalogDLCData[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryMissingDLCTitle(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.titleID = source["titleID"];
+	        this.name = source["name"];
+	        this.ownedDLC = source["ownedDLC"];
+	        this.missingDLC = this.convertValues(source["missingDLC"], CatalogDLCData);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LibraryCompletionStat {
+	    owned: number;
+	    total: number;
+	    percentage: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryCompletionStat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.owned = source["owned"];
+	        this.total = source["total"];
+	        this.percentage = source["percentage"];
+	    }
+	}
+	export class LibraryCompletion {
+	    titles: LibraryCompletionStat;
+	    dlc: LibraryCompletionStat;
+	    updates: LibraryCompletionStat;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryCompletion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.titles = this.convertValues(source["titles"], LibraryCompletionStat);
+	        this.dlc = this.convertValues(source["dlc"], LibraryCompletionStat);
+	        this.updates = this.convertValues(source["updates"], LibraryCompletionStat);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LibraryOrganizeError {
+	    filePath: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryOrganizeError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.error = source["error"];
+	    }
+	}
+	export class LibraryOrganizeOperation {
+	    type: string;
+	    from: string;
+	    to: string;
+	    issues: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryOrganizeOperation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.issues = source["issues"];
+	    }
+	}
+	export class LibraryOrganizePlan {
+	    operations: LibraryOrganizeOperation[];
+	    hasIssues: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryOrganizePlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.operations = this.convertValues(source["operations"], LibraryOrganizeOperation);
+	        this.hasIssues = source["hasIssues"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LibraryOrganizeResult {
+	    moved: number;
+	    quarantined: number;
+	    errors: LibraryOrganizeError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryOrganizeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.moved = source["moved"];
+	        this.quarantined = source["quarantined"];
+	        this.errors = this.convertValues(source["errors"], LibraryOrganizeError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LibraryCleanupCandidate {
+	    filePath: string;
+	    reason: string;
+	    titleID: string;
+	    version: number;
+	    keptFilePath: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryCleanupCandidate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filePath = source["filePath"];
+	        this.reason = source["reason"];
+	        this.titleID = source["titleID"];
+	        this.version = source["version"];
+	        this.keptFilePath = source["keptFilePath"];
+	    }
+	}
+	export class LibraryQuarantineEntry {
+	    originalPath: string;
+	    quarantinePath: string;
+	    reason: string;
+	    titleID: string;
+	    quarantinedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryQuarantineEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.originalPath = source["originalPath"];
+	        this.quarantinePath = source["quarantinePath"];
+	        this.reason = source["reason"];
+	        this.titleID = source["titleID"];
+	        this.quarantinedAt = source["quarantinedAt"];
+	    }
+	}
+	export class LibraryCleanupResult {
+	    entries: LibraryQuarantineEntry[];
+	    errors: LibraryOrganizeError[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryCleanupResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.entries = this.convertValues(source["entries"], LibraryQuarantineEntry);
+	        this.errors = this.convertValues(source["errors"], LibraryOrganizeError);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Console {
+	    consoleID: string;
+	    user: string;
+	    client: string;
+	    reportedAt: string;
+	    titleCount: number;
+	    behindCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Console(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.consoleID = source["consoleID"];
+	        this.user = source["user"];
+	        this.client = source["client"];
+	        this.reportedAt = source["reportedAt"];
+	        this.titleCount = source["titleCount"];
+	        this.behindCount = source["behindCount"];
+	    }
+	}
+	export class ConsoleTitleInstall {
+	    consoleID: string;
+	    installedVersion: number;
+	    isBehind: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConsoleTitleInstall(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.consoleID = source["consoleID"];
+	        this.installedVersion = source["installedVersion"];
+	        this.isBehind = source["isBehind"];
+	    }
+	}
+	export class LibraryTitleInstalls {
+	    titleID: string;
+	    name: string;
+	    localVersion: number;
+	    consoles: ConsoleTitleInstall[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryTitleInstalls(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.titleID = source["titleID"];
+	        this.name = source["name"];
+	        this.localVersion = source["localVersion"];
+	        this.consoles = this.convertValues(source["consoles"], ConsoleTitleInstall);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	
 
 }
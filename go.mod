@@ -5,6 +5,7 @@ go 1.20
 require (
 	github.com/avast/retry-go v2.6.1+incompatible
 	github.com/creasty/defaults v1.7.0
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/go-chi/chi/v5 v5.0.12
 	github.com/google/uuid v1.4.0
 	github.com/hashicorp/go-version v1.6.0
@@ -14,6 +15,7 @@ require (
 	github.com/wailsapp/wails/v2 v2.8.1
 	go.etcd.io/bbolt v1.3.9
 	go.uber.org/zap v1.21.0
+	golang.org/x/crypto v0.18.0
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9
 	gopkg.in/yaml.v2 v2.4.0
 	robpike.io/nihongo v0.0.0-20200511095354-a985f0929cfa
@@ -46,7 +48,6 @@ require (
 	github.com/wailsapp/mimetype v1.4.1 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
-	golang.org/x/crypto v0.18.0 // indirect
 	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
@@ -0,0 +1,112 @@
+package nut
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type userContextKey struct{}
+
+// HashPassword returns bcrypt hash of the password to be stored in NUT user settings.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// authenticator checks HTTP Basic credentials, as sent by Tinfoil, against configured users.
+type authenticator struct {
+	users map[string]settings.NUTUserSettings
+	// verified holds SHA-256 of the last password verified for a user, so that bcrypt does not run on every
+	// chunk of a download
+	verified sync.Map
+}
+
+func newAuthenticator(users []settings.NUTUserSettings) *authenticator {
+	result := &authenticator{users: make(map[string]settings.NUTUserSettings, len(users))}
+	for _, user := range users {
+		result.users[user.Username] = user
+	}
+	return result
+}
+
+// Middleware rejects requests without valid credentials with 401 and stores the user in the request context.
+// Without configured users all the requests are allowed.
+func (a *authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if len(a.users) == 0 {
+			next.ServeHTTP(writer, request)
+			return
+		}
+		username, password, ok := request.BasicAuth()
+		if !ok {
+			unauthorized(writer)
+			return
+		}
+		user, ok := a.users[username]
+		if !ok || !a.verify(user, password) {
+			unauthorized(writer)
+			return
+		}
+		next.ServeHTTP(writer, request.WithContext(context.WithValue(request.Context(), userContextKey{}, &user)))
+	})
+}
+
+func (a *authenticator) verify(user settings.NUTUserSettings, password string) bool {
+	sum := sha256.Sum256([]byte(password))
+	if verified, ok := a.verified.Load(user.Username); ok {
+		if subtle.ConstantTimeCompare(verified.([]byte), sum[:]) == 1 {
+			return true
+		}
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return false
+	}
+	a.verified.Store(user.Username, sum[:])
+	return true
+}
+
+func unauthorized(writer http.ResponseWriter) {
+	writer.Header().Set("WWW-Authenticate", `Basic realm="NUT", charset="UTF-8"`)
+	http.Error(writer, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// requestUser returns the authenticated user, nil if authentication is disabled.
+func requestUser(request *http.Request) *settings.NUTUserSettings {
+	user, _ := request.Context().Value(userContextKey{}).(*settings.NUTUserSettings)
+	return user
+}
+
+// canAccess checks if the user may see the title in the library file. Users without allowlists can access
+// everything, otherwise either the title or the file directory has to be allowed. Allowed base title IDs cover
+// their updates and DLCs.
+func canAccess(user *settings.NUTUserSettings, entry data.LibraryFileEntry, titleID string) bool {
+	if user == nil || (len(user.AllowedTitleIDs) == 0 && len(user.AllowedDirectories) == 0) {
+		return true
+	}
+	prefix := searchTitlePrefix(strings.ToUpper(titleID))
+	for _, allowed := range user.AllowedTitleIDs {
+		if searchTitlePrefix(strings.ToUpper(allowed)) == prefix {
+			return true
+		}
+	}
+	filePath := filepath.Clean(entry.FilePath)
+	for _, directory := range user.AllowedDirectories {
+		directory = filepath.Clean(directory)
+		if strings.HasPrefix(filePath, directory+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package nut
+
+import (
+	"encoding/json"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestAuthentication(t *testing.T) {
+	hash, err := HashPassword("secret")
+	assert.Nil(t, err)
+	users := []settings.NUTUserSettings{{Username: "alice", PasswordHash: hash}}
+
+	tests := []struct {
+		name     string
+		users    []settings.NUTUserSettings
+		username string
+		password string
+		code     int
+	}{
+		{"no users configured", nil, "", "", http.StatusOK},
+		{"missing credentials", users, "", "", http.StatusUnauthorized},
+		{"unknown user", users, "bob", "secret", http.StatusUnauthorized},
+		{"wrong password", users, "alice", "wrong", http.StatusUnauthorized},
+		{"valid credentials", users, "alice", "secret", http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := NewRouter(&fakeLibraryManager{}, nil, nil, nil, nopReporter{}, settings.NUTSettings{Users: test.users}, nil)
+			// the second request checks verified passwords are remembered correctly
+			for i := 0; i < 2; i++ {
+				request := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+				if test.username != "" {
+					request.SetBasicAuth(test.username, test.password)
+				}
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, request)
+				assert.Equal(t, test.code, recorder.Code)
+				if test.code == http.StatusUnauthorized {
+					assert.Equal(t, `Basic realm="NUT", charset="UTF-8"`, recorder.Header().Get("WWW-Authenticate"))
+				}
+			}
+		})
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	directory := t.TempDir()
+	allowedDirectory := filepath.Join(directory, "kids")
+	assert.Nil(t, os.Mkdir(allowedDirectory, 0755))
+	for _, path := range []string{filepath.Join(directory, "game.nsp"), filepath.Join(directory, "dlc.nsp"), filepath.Join(allowedDirectory, "kids.nsp")} {
+		assert.Nil(t, os.WriteFile(path, []byte("data"), 0644))
+	}
+	library := &fakeLibraryManager{entries: []data.LibraryFileEntry{
+		{FilePath: filepath.Join(directory, "game.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}},
+		}},
+		{FilePath: filepath.Join(directory, "dlc.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			DLCs: []data.SwitchFileDLC{{ForIDPrefix: "010000000001", ID: "0100000000011001"}},
+		}},
+		{FilePath: filepath.Join(allowedDirectory, "kids.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000002", ID: "0100000000020000"}},
+		}},
+	}}
+
+	hash, err := HashPassword("secret")
+	assert.Nil(t, err)
+	router := NewRouter(library, nil, nil, nil, nopReporter{}, settings.NUTSettings{Users: []settings.NUTUserSettings{
+		{Username: "admin", PasswordHash: hash},
+		{Username: "titles", PasswordHash: hash, AllowedTitleIDs: []string{"0100000000010000"}},
+		{Username: "kids", PasswordHash: hash, AllowedDirectories: []string{allowedDirectory}},
+	}}, nil)
+
+	tests := []struct {
+		username string
+		ids      []string
+		// download status for every library file, in order
+		downloads []int
+	}{
+		{"admin", []string{"0100000000010000", "0100000000011001", "0100000000020000"}, []int{http.StatusPartialContent, http.StatusPartialContent, http.StatusPartialContent}},
+		{"titles", []string{"0100000000010000", "0100000000011001"}, []int{http.StatusPartialContent, http.StatusPartialContent, http.StatusForbidden}},
+		{"kids", []string{"0100000000020000"}, []int{http.StatusForbidden, http.StatusForbidden, http.StatusPartialContent}},
+	}
+	for _, test := range tests {
+		t.Run(test.username, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+			request.SetBasicAuth(test.username, "secret")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var result searchResultDTO
+			assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&result))
+			ids := make([]string, 0, len(result))
+			for _, entry := range result {
+				ids = append(ids, entry.ID)
+			}
+			sort.Strings(ids)
+			assert.Equal(t, test.ids, ids)
+
+			for i, entry := range library.entries {
+				id := libraryFileContents(entry)[0].id
+				request := httptest.NewRequest(http.MethodGet, "/api/download/"+id+"/"+filepath.Base(entry.FilePath), nil)
+				request.SetBasicAuth(test.username, "secret")
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, request)
+				assert.Equal(t, test.downloads[i], recorder.Code, entry.FilePath)
+			}
+		})
+	}
+}
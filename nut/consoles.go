@@ -0,0 +1,152 @@
+package nut
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxInstalledAppsSize limits size of an installed apps report
+const maxInstalledAppsSize = 10 << 20
+
+type installedAppDTO struct {
+	ID      string      `json:"id"`
+	TitleID string      `json:"titleId"`
+	Version json.Number `json:"version"`
+}
+
+type successDTO struct {
+	Success bool   `json:"success"`
+	Result  string `json:"result"`
+}
+
+// HandlePostInstalledApps stores titles installed on a console as reported by Tinfoil. Console is identified by
+// the "consoleId" URL parameter, the "UID" header sent by Tinfoil, the user name or the client address, in that
+// order. The report replaces the previous one of the console.
+func HandlePostInstalledApps(consoles storage.SwitchDatabaseConsoles) http.HandlerFunc {
+	logger := zap.S()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxInstalledAppsSize))
+		if err != nil {
+			http.Error(writer, fmt.Sprintf("could not read report: %v", err), http.StatusBadRequest)
+			return
+		}
+		titles, err := parseInstalledApps(body)
+		if err != nil {
+			http.Error(writer, fmt.Sprintf("invalid report: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		report := storage.ConsoleReport{
+			ConsoleID:  requestConsoleID(request),
+			Client:     request.UserAgent(),
+			ReportedAt: time.Now(),
+			Titles:     titles,
+		}
+		if user := requestUser(request); user != nil {
+			report.User = user.Username
+		}
+		err = consoles.UpsertConsoleReport(report)
+		if err != nil {
+			logger.Errorf("could not store installed apps: %v", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		logger.Infof("console %v reported %d installed titles", report.ConsoleID, len(titles))
+		writeJSON(writer, successDTO{Success: true, Result: "OK"})
+	}
+}
+
+func requestConsoleID(request *http.Request) string {
+	if consoleID := chi.URLParam(request, "consoleId"); consoleID != "" {
+		return consoleID
+	}
+	if uid := request.Header.Get("UID"); uid != "" {
+		return uid
+	}
+	if user := requestUser(request); user != nil {
+		return user.Username
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// parseInstalledApps reads installed titles from a list of apps, an object with such list in "titles" or an object
+// with apps by title ID. Versions may be numbers or strings, entries without a valid title ID are skipped. Result
+// is sorted by title ID.
+func parseInstalledApps(body []byte) ([]storage.ConsoleReportTitle, error) {
+	body = bytes.TrimSpace(body)
+	var apps []installedAppDTO
+	if len(body) > 0 && body[0] == '[' {
+		err := json.Unmarshal(body, &apps)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var object map[string]json.RawMessage
+		err := json.Unmarshal(body, &object)
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := object["titles"]; ok {
+			err = json.Unmarshal(list, &apps)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			for id, value := range object {
+				app := installedAppDTO{ID: id}
+				if bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+					err = json.Unmarshal(value, &app)
+				} else {
+					err = json.Unmarshal(value, &app.Version)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("invalid title %v: %w", id, err)
+				}
+				if app.ID == "" {
+					app.ID = id
+				}
+				apps = append(apps, app)
+			}
+		}
+	}
+
+	titles := make([]storage.ConsoleReportTitle, 0, len(apps))
+	for _, app := range apps {
+		id := app.ID
+		if id == "" {
+			id = app.TitleID
+		}
+		if _, err := strconv.ParseUint(id, 16, 64); err != nil || len(id) != 16 {
+			continue
+		}
+		version := 0
+		if app.Version != "" {
+			v, err := strconv.Atoi(app.Version.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid version of title %v", id)
+			}
+			version = v
+		}
+		titles = append(titles, storage.ConsoleReportTitle{ID: strings.ToUpper(id), Version: version})
+	}
+	sort.Slice(titles, func(i, j int) bool {
+		return titles[i].ID < titles[j].ID
+	})
+	return titles, nil
+}
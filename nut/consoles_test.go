@@ -0,0 +1,75 @@
+package nut
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPostInstalledApps(t *testing.T) {
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	hash, err := HashPassword("secret")
+	assert.Nil(t, err)
+	router := NewRouter(&fakeLibraryManager{}, nil, db, nil, nopReporter{}, settings.NUTSettings{Users: []settings.NUTUserSettings{
+		{Username: "alice", PasswordHash: hash},
+	}}, nil)
+
+	tests := []struct {
+		name    string
+		target  string
+		uid     string
+		body    string
+		code    int
+		console string
+		titles  []storage.ConsoleReportTitle
+	}{
+		{"list of apps", "/api/tinfoilSetInstalledApps", "UID1", `[{"id": "0100000000010000", "version": 65536}, {"titleId": "0100000000020800", "version": "131072"}]`, http.StatusOK,
+			"UID1", []storage.ConsoleReportTitle{{ID: "0100000000010000", Version: 65536}, {ID: "0100000000020800", Version: 131072}}},
+		{"titles object", "/api/tinfoilSetInstalledApps/living-room", "UID1", `{"titles": [{"id": "0100000000010000"}]}`, http.StatusOK,
+			"living-room", []storage.ConsoleReportTitle{{ID: "0100000000010000"}}},
+		{"apps by ID", "/api/tinfoilSetInstalledApps", "", `{"0100000000020000": {"version": 0}, "0100000000010800": 65536, "homebrew": 1}`, http.StatusOK,
+			"alice", []storage.ConsoleReportTitle{{ID: "0100000000010800", Version: 65536}, {ID: "0100000000020000"}}},
+		{"invalid json", "/api/tinfoilSetInstalledApps", "UID2", `{`, http.StatusBadRequest, "", nil},
+		{"invalid version", "/api/tinfoilSetInstalledApps", "UID2", `[{"id": "0100000000010000", "version": "v1"}]`, http.StatusBadRequest, "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(test.body))
+			request.SetBasicAuth("alice", "secret")
+			request.Header.Set("User-Agent", "Tinfoil")
+			if test.uid != "" {
+				request.Header.Set("UID", test.uid)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			assert.Equal(t, test.code, recorder.Code)
+			if test.code != http.StatusOK {
+				return
+			}
+
+			reports, err := db.GetConsoleReports()
+			assert.Nil(t, err)
+			for _, report := range reports {
+				if report.ConsoleID == test.console {
+					assert.Equal(t, "alice", report.User)
+					assert.Equal(t, "Tinfoil", report.Client)
+					assert.Equal(t, test.titles, report.Titles)
+					return
+				}
+			}
+			assert.Fail(t, "report not stored", test.console)
+		})
+	}
+
+	reports, err := db.GetConsoleReports()
+	assert.Nil(t, err)
+	assert.Len(t, reports, 3)
+}
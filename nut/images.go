@@ -0,0 +1,184 @@
+package nut
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ImageCacheDirectoryName is name of the folder inside working directory holding downloaded catalog images
+	ImageCacheDirectoryName = "images"
+
+	imageDialTimeout = 10 * time.Second
+)
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// ImageCache downloads catalog images once and serves them from disk afterwards.
+type ImageCache struct {
+	directory string
+	client    http.Client
+
+	mutex sync.Mutex
+	// downloads holds a lock for every image being downloaded, so that an image is downloaded only once
+	downloads map[string]*sync.Mutex
+}
+
+func NewImageCache(directory string) *ImageCache {
+	return &ImageCache{
+		directory: directory,
+		client: http.Client{
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout: imageDialTimeout,
+				}).DialContext,
+			},
+		},
+		downloads: make(map[string]*sync.Mutex),
+	}
+}
+
+// Path returns path of the cached image, downloading it first if needed.
+func (c *ImageCache) Path(imageURL string) (string, error) {
+	parsedURL, err := url.Parse(imageURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return "", fmt.Errorf("invalid image URL %v", imageURL)
+	}
+	sum := sha256.Sum256([]byte(imageURL))
+	cachePath := filepath.Join(c.directory, hex.EncodeToString(sum[:])+path.Ext(parsedURL.Path))
+
+	c.mutex.Lock()
+	lock, ok := c.downloads[cachePath]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.downloads[cachePath] = lock
+	}
+	c.mutex.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(cachePath); err == nil {
+		return cachePath, nil
+	}
+	err = c.download(imageURL, cachePath)
+	if err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+func (c *ImageCache) download(imageURL, cachePath string) error {
+	err := os.MkdirAll(c.directory, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create image cache dir: %w", err)
+	}
+
+	resp, err := c.client.Get(imageURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrImageNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got a non 200 response - %v", resp.Status)
+	}
+
+	// download next to the cached file so that a failed download never leaves a broken image in the cache
+	f, err := os.CreateTemp(c.directory, "download-*")
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	_, err = io.Copy(f, resp.Body)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), cachePath)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("could not download image: %w", err)
+	}
+	return nil
+}
+
+type imageKind int
+
+const (
+	imageKindIcon imageKind = iota
+	imageKindBanner
+	imageKindScreenshot
+)
+
+// HandleGetImage serves title icon, banner or screenshot taken from the catalog, DLCs use their own images.
+// Screenshots are selected by the "index" URL parameter.
+func HandleGetImage(catalog storage.SwitchDatabaseCatalog, images *ImageCache, kind imageKind) http.HandlerFunc {
+	logger := zap.S()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		titleID := chi.URLParam(request, "titleId")
+		if images == nil {
+			http.NotFound(writer, request)
+			return
+		}
+		_, titleData, ok, err := catalogTitleData(catalog, titleID)
+		if err != nil {
+			logger.Errorf("could not read catalog entry %v: %v", titleID, err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			http.NotFound(writer, request)
+			return
+		}
+
+		var imageURL string
+		switch kind {
+		case imageKindIcon:
+			imageURL = titleData.IconURL
+		case imageKindBanner:
+			imageURL = titleData.BannerURL
+		case imageKindScreenshot:
+			index, err := strconv.Atoi(chi.URLParam(request, "index"))
+			if err == nil && index >= 0 && index < len(titleData.Screenshots) {
+				imageURL = titleData.Screenshots[index]
+			}
+		}
+		if imageURL == "" {
+			http.NotFound(writer, request)
+			return
+		}
+
+		imagePath, err := images.Path(imageURL)
+		if errors.Is(err, ErrImageNotFound) {
+			http.NotFound(writer, request)
+			return
+		}
+		if err != nil {
+			logger.Errorf("could not get image %v: %v", imageURL, err)
+			writer.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		http.ServeFile(writer, request, imagePath)
+	}
+}
@@ -0,0 +1,329 @@
+package nut
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/process"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type titleDTO struct {
+	ID       string `json:"id"`
+	BaseID   string `json:"baseId"`
+	Name     string `json:"name"`
+	IsUpdate bool   `json:"isUpdate"`
+	IsDLC    bool   `json:"isDLC"`
+	// Version is version of the library file, LatestVersion the latest update known to the catalog
+	Version       int    `json:"version"`
+	LatestVersion int    `json:"latestVersion"`
+	Region        string `json:"region"`
+	Publisher     string `json:"publisher"`
+	Description   string `json:"description"`
+	ReleaseDate   int    `json:"releaseDate"`
+	FileName      string `json:"fileName"`
+	FileSize      int64  `json:"fileSize"`
+}
+
+type fileSizeDTO struct {
+	FileSize int64 `json:"fileSize"`
+}
+
+type titleUpdateDTO struct {
+	ID             string `json:"id"`
+	BaseID         string `json:"baseId"`
+	Name           string `json:"name"`
+	CurrentVersion int    `json:"currentVersion"`
+	NewVersion     int    `json:"newVersion"`
+}
+
+type directoryListDTO struct {
+	Dirs  []directoryListDirDTO  `json:"dirs"`
+	Files []directoryListFileDTO `json:"files"`
+}
+
+type directoryListDirDTO struct {
+	Name string `json:"name"`
+}
+
+type directoryListFileDTO struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	ID   string `json:"id"`
+	URL  string `json:"url"`
+}
+
+// HandleGetTitles lists titles in the library the user can access, described with catalog data when available.
+func HandleGetTitles(db data.LibraryManager, catalog storage.SwitchDatabaseCatalog) http.HandlerFunc {
+	logger := zap.S()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		entries, err := db.GetEntries()
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		files := libraryFiles(entries, requestUser(request))
+		dto := make([]titleDTO, 0, len(files))
+		for _, file := range files {
+			title, _, err := tinfoilTitle(catalog, file.id)
+			if err != nil {
+				logger.Errorf("could not read catalog entry %v: %v", file.id, err)
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			name := title.Name
+			if name == "" {
+				name = strings.TrimSuffix(file.name, filepath.Ext(file.name))
+			}
+			dto = append(dto, titleDTO{
+				ID:            file.id,
+				BaseID:        data.BaseTitleID(file.id),
+				Name:          name,
+				IsUpdate:      file.kind == contentKindUpdate,
+				IsDLC:         file.kind == contentKindDLC,
+				Version:       file.version,
+				LatestVersion: title.Version,
+				Region:        title.Region,
+				Publisher:     title.Publisher,
+				Description:   title.Description,
+				ReleaseDate:   title.ReleaseDate,
+				FileName:      file.name,
+				FileSize:      file.size,
+			})
+		}
+		writeJSON(writer, dto)
+	}
+}
+
+// HandleGetFileSize returns size of the library file selected the same way as by the download handler.
+func HandleGetFileSize(db data.LibraryManager) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		entry, ok := requestedLibraryFile(writer, request, db)
+		if !ok {
+			return
+		}
+		size, err := libraryFileSize(entry)
+		if err != nil {
+			http.Error(writer, "could not stat the file", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(writer, fileSizeDTO{FileSize: size})
+	}
+}
+
+// HandleGetTitleUpdates returns titles the user can access that have a newer update in the catalog than the one
+// in the library, by base title ID.
+func HandleGetTitleUpdates(db data.LibraryManager, catalog storage.SwitchDatabaseCatalog) http.HandlerFunc {
+	logger := zap.S()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		entries, err := db.GetEntries()
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		dto := make(map[string]titleUpdateDTO)
+		if catalog != nil {
+			updates, err := process.ScanForMissingUpdates(accessibleEntries(entries, requestUser(request)), catalog)
+			if err != nil {
+				logger.Errorf("could not scan for missing updates: %v", err)
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for _, update := range updates {
+				dto[update.TitleID] = titleUpdateDTO{
+					ID:             update.UpdateID,
+					BaseID:         update.TitleID,
+					Name:           update.Name,
+					CurrentVersion: update.LocalVersion,
+					NewVersion:     update.LatestVersion,
+				}
+			}
+		}
+		writeJSON(writer, dto)
+	}
+}
+
+// HandleGetDirectoryList lists library files and folders the user can access under the path from the "*" URL
+// parameter. The root lists scan directories by unique names, other folders are not exposed.
+func HandleGetDirectoryList(db data.LibraryManager, scanDirectories []string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		requestedPath, err := url.PathUnescape(chi.URLParam(request, "*"))
+		if err != nil {
+			http.Error(writer, "invalid path", http.StatusBadRequest)
+			return
+		}
+		segments := strings.Split(strings.Trim(path.Clean("/"+requestedPath), "/"), "/")
+
+		dto := directoryListDTO{Dirs: []directoryListDirDTO{}, Files: []directoryListFileDTO{}}
+		rootNames := scanDirectoryNames(scanDirectories)
+		if segments[0] == "" {
+			for _, name := range rootNames {
+				dto.Dirs = append(dto.Dirs, directoryListDirDTO{Name: name})
+			}
+			sort.Slice(dto.Dirs, func(i, j int) bool {
+				return dto.Dirs[i].Name < dto.Dirs[j].Name
+			})
+			writeJSON(writer, dto)
+			return
+		}
+
+		root := ""
+		for i, name := range rootNames {
+			if name == segments[0] {
+				root = scanDirectories[i]
+				break
+			}
+		}
+		if root == "" {
+			http.NotFound(writer, request)
+			return
+		}
+
+		entries, err := db.GetEntries()
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		listed := path.Join(segments[1:]...)
+		if listed == "" {
+			listed = "."
+		}
+		dirs := make(map[string]struct{})
+		user := requestUser(request)
+		for _, entry := range entries {
+			if entry.LibraryGameFileMetadata == nil {
+				continue
+			}
+			id := ""
+			for _, content := range libraryFileContents(entry) {
+				if canAccess(user, entry, content.id) {
+					id = strings.ToUpper(content.id)
+					break
+				}
+			}
+			if id == "" {
+				continue
+			}
+
+			// split files are listed as a single file in place of their folder
+			servedPath := entry.FilePath
+			if entry.IsSplit {
+				servedPath = filepath.Dir(servedPath)
+			}
+			relativePath, err := filepath.Rel(root, servedPath)
+			if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+				continue
+			}
+			relativePath = filepath.ToSlash(relativePath)
+
+			if path.Dir(relativePath) == listed {
+				size, err := libraryFileSize(entry)
+				if err != nil {
+					continue
+				}
+				name := path.Base(relativePath)
+				dto.Files = append(dto.Files, directoryListFileDTO{
+					Name: name,
+					Size: size,
+					ID:   id,
+					URL:  "/api/download/" + id + "/" + url.PathEscape(name),
+				})
+			} else if listed == "." || strings.HasPrefix(relativePath, listed+"/") {
+				remainder := strings.TrimPrefix(relativePath, listed+"/")
+				dirs[strings.SplitN(remainder, "/", 2)[0]] = struct{}{}
+			}
+		}
+		if listed != "." && len(dirs) == 0 && len(dto.Files) == 0 {
+			http.NotFound(writer, request)
+			return
+		}
+
+		for dir := range dirs {
+			dto.Dirs = append(dto.Dirs, directoryListDirDTO{Name: dir})
+		}
+		sort.Slice(dto.Dirs, func(i, j int) bool {
+			return dto.Dirs[i].Name < dto.Dirs[j].Name
+		})
+		sort.Slice(dto.Files, func(i, j int) bool {
+			return dto.Files[i].Name < dto.Files[j].Name
+		})
+		writeJSON(writer, dto)
+	}
+}
+
+// scanDirectoryNames returns names under which scan directories are listed, in the same order. Directories are named
+// by their base names, later directories with an already used name get a number appended.
+func scanDirectoryNames(scanDirectories []string) []string {
+	names := make([]string, len(scanDirectories))
+	used := make(map[string]struct{}, len(scanDirectories))
+	for i, directory := range scanDirectories {
+		base := filepath.Base(directory)
+		name := base
+		for n := 2; ; n++ {
+			if _, ok := used[name]; !ok {
+				break
+			}
+			name = fmt.Sprintf("%v (%d)", base, n)
+		}
+		used[name] = struct{}{}
+		names[i] = name
+	}
+	return names
+}
+
+// accessibleEntries returns library files reduced to titles the user can access, files without such titles are
+// left out.
+func accessibleEntries(entries []data.LibraryFileEntry, user *settings.NUTUserSettings) []data.LibraryFileEntry {
+	result := make([]data.LibraryFileEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		metadata := *entry.LibraryGameFileMetadata
+		metadata.BaseGames, metadata.Updates, metadata.DLCs = nil, nil, nil
+		for _, game := range entry.BaseGames {
+			if canAccess(user, entry, game.ID) {
+				metadata.BaseGames = append(metadata.BaseGames, game)
+			}
+		}
+		for _, update := range entry.Updates {
+			if canAccess(user, entry, update.ID) {
+				metadata.Updates = append(metadata.Updates, update)
+			}
+		}
+		for _, dlc := range entry.DLCs {
+			if canAccess(user, entry, dlc.ID) {
+				metadata.DLCs = append(metadata.DLCs, dlc)
+			}
+		}
+		if len(metadata.BaseGames) == 0 && len(metadata.Updates) == 0 && len(metadata.DLCs) == 0 {
+			continue
+		}
+		entry.LibraryGameFileMetadata = &metadata
+		result = append(result, entry)
+	}
+	return result
+}
+
+func writeJSON(writer http.ResponseWriter, value any) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(writer).Encode(value)
+	if err != nil {
+		zap.S().Errorf("failed to write json response: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package nut
+
+import (
+	"encoding/json"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLibraryEndpoints(t *testing.T) {
+	var imageRequests int32
+	imageServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(&imageRequests, 1)
+		if request.URL.Path != "/icon.jpg" {
+			http.NotFound(writer, request)
+			return
+		}
+		_, _ = writer.Write([]byte("icon"))
+	}))
+	defer imageServer.Close()
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	err = db.ReplaceCatalog(map[string]storage.CatalogEntry{
+		"0100000000010000": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Pokémon Sword", Region: "US", IconURL: imageServer.URL + "/icon.jpg", BannerURL: imageServer.URL + "/missing.jpg"},
+			Versions:         []storage.CatalogEntryVersion{{Version: 65536}, {Version: 131072}},
+		},
+		"01007EF00011E000": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "01007EF00011E000", Name: "Metroid Dread", Region: "US"},
+		},
+	}, storage.CatalogMetadata{})
+	assert.Nil(t, err)
+
+	root := filepath.Join(t.TempDir(), "games")
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "sword", "split.nsp"), 0755))
+	for _, path := range []string{filepath.Join(root, "sword", "sword.nsp"), filepath.Join(root, "homebrew.nsp"), filepath.Join(root, "sword", "split.nsp", "00")} {
+		assert.Nil(t, os.WriteFile(path, []byte("data"), 0644))
+	}
+	library := &fakeLibraryManager{entries: []data.LibraryFileEntry{
+		{FilePath: filepath.Join(root, "sword", "sword.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}},
+		}},
+		{FilePath: filepath.Join(root, "sword", "split.nsp", "00"), IsSplit: true, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			Updates: []data.SwitchFileUpdate{{ForIDPrefix: "010000000001", ID: "0100000000010800", Version: 65536}},
+		}},
+		{FilePath: filepath.Join(t.TempDir(), "metroid.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			Updates: []data.SwitchFileUpdate{{ForIDPrefix: "01007EF00011", ID: "01007EF00011E800", Version: 65536}},
+		}},
+		{FilePath: filepath.Join(root, "homebrew.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "05000000000A", ID: "05000000000A0000"}},
+		}},
+	}}
+
+	images := NewImageCache(filepath.Join(t.TempDir(), ImageCacheDirectoryName))
+	router := NewRouter(library, db, nil, images, nopReporter{}, settings.NUTSettings{}, []string{root})
+
+	get := func(target string, result any) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if result != nil && recorder.Code == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(recorder.Body).Decode(result))
+		}
+		return recorder
+	}
+
+	t.Run("titles", func(t *testing.T) {
+		var titles []titleDTO
+		get("/api/titles", &titles)
+		assert.Equal(t, []titleDTO{
+			{ID: "0100000000010000", BaseID: "0100000000010000", Name: "Pokémon Sword", LatestVersion: 131072, Region: "US", FileName: "sword.nsp", FileSize: 4},
+			{ID: "0100000000010800", BaseID: "0100000000010000", Name: "Pokémon Sword", IsUpdate: true, Version: 65536, LatestVersion: 131072, Region: "US", FileName: "split.nsp", FileSize: 4},
+			{ID: "01007EF00011E800", BaseID: "01007EF00011E000", Name: "Metroid Dread", IsUpdate: true, Version: 65536, Region: "US", FileName: "metroid.nsp", FileSize: 4},
+			{ID: "05000000000A0000", BaseID: "05000000000A0000", Name: "homebrew", FileName: "homebrew.nsp", FileSize: 4},
+		}, titles)
+	})
+
+	t.Run("images", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			recorder := get("/api/titleImage/0100000000010000/256", nil)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "icon", recorder.Body.String())
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&imageRequests))
+
+		assert.Equal(t, http.StatusNotFound, get("/api/bannerImage/0100000000010000", nil).Code)
+		assert.Equal(t, http.StatusNotFound, get("/api/screenshotImage/0100000000010000/0", nil).Code)
+		assert.Equal(t, http.StatusNotFound, get("/api/titleImage/05000000000A0000", nil).Code)
+	})
+
+	t.Run("file size", func(t *testing.T) {
+		var size fileSizeDTO
+		get("/api/fileSize/0100000000010800/split.nsp", &size)
+		assert.Equal(t, int64(4), size.FileSize)
+		assert.Equal(t, http.StatusBadRequest, get("/api/fileSize/0100000000010800/other.nsp", nil).Code)
+	})
+
+	t.Run("title updates", func(t *testing.T) {
+		var updates map[string]titleUpdateDTO
+		get("/api/titleUpdates", &updates)
+		assert.Equal(t, map[string]titleUpdateDTO{
+			"0100000000010000": {ID: "0100000000010800", BaseID: "0100000000010000", Name: "Pokémon Sword", CurrentVersion: 65536, NewVersion: 131072},
+		}, updates)
+	})
+
+	t.Run("directory list", func(t *testing.T) {
+		var list directoryListDTO
+		get("/api/directoryList", &list)
+		assert.Equal(t, directoryListDTO{Dirs: []directoryListDirDTO{{Name: "games"}}, Files: []directoryListFileDTO{}}, list)
+
+		get("/api/directoryList/games", &list)
+		assert.Equal(t, directoryListDTO{
+			Dirs:  []directoryListDirDTO{{Name: "sword"}},
+			Files: []directoryListFileDTO{{Name: "homebrew.nsp", Size: 4, ID: "05000000000A0000", URL: "/api/download/05000000000A0000/homebrew.nsp"}},
+		}, list)
+
+		get("/api/directoryList/games/sword/", &list)
+		assert.Equal(t, directoryListDTO{
+			Dirs: []directoryListDirDTO{},
+			Files: []directoryListFileDTO{
+				{Name: "split.nsp", Size: 4, ID: "0100000000010800", URL: "/api/download/0100000000010800/split.nsp"},
+				{Name: "sword.nsp", Size: 4, ID: "0100000000010000", URL: "/api/download/0100000000010000/sword.nsp"},
+			},
+		}, list)
+
+		assert.Equal(t, http.StatusNotFound, get("/api/directoryList/other", nil).Code)
+		assert.Equal(t, http.StatusNotFound, get("/api/directoryList/games/missing", nil).Code)
+	})
+}
+
+func TestAccessibleEntries(t *testing.T) {
+	entries := []data.LibraryFileEntry{
+		{FilePath: "/library/bundle.xci", LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			IsMultiContent: true,
+			BaseGames:      []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}, {IDPrefix: "010000000002", ID: "0100000000020000"}},
+			Updates:        []data.SwitchFileUpdate{{ForIDPrefix: "010000000002", ID: "0100000000020800", Version: 65536}},
+		}},
+		{FilePath: "/library/other.nsp", LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			Updates: []data.SwitchFileUpdate{{ForIDPrefix: "010000000003", ID: "0100000000030800", Version: 65536}},
+		}},
+		{FilePath: "/library/broken.nsp"},
+	}
+
+	accessible := accessibleEntries(entries, &settings.NUTUserSettings{AllowedTitleIDs: []string{"0100000000010000"}})
+	if assert.Len(t, accessible, 1) {
+		assert.Equal(t, []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}}, accessible[0].BaseGames)
+		assert.Empty(t, accessible[0].Updates)
+		assert.True(t, accessible[0].IsMultiContent)
+	}
+	// library entries are not modified
+	assert.Len(t, entries[0].BaseGames, 2)
+
+	assert.Len(t, accessibleEntries(entries, nil), 2)
+}
+
+func TestDirectoryListSameNames(t *testing.T) {
+	first := filepath.Join(t.TempDir(), "games")
+	second := filepath.Join(t.TempDir(), "games")
+	library := &fakeLibraryManager{entries: []data.LibraryFileEntry{
+		{FilePath: filepath.Join(first, "a.nsp"), FileSize: 1, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}},
+		}},
+		{FilePath: filepath.Join(second, "b.nsp"), FileSize: 2, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000002", ID: "0100000000020000"}},
+		}},
+	}}
+	router := NewRouter(library, nil, nil, nil, nopReporter{}, settings.NUTSettings{}, []string{first, second})
+
+	get := func(target string) directoryListDTO {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code, target)
+		var list directoryListDTO
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&list))
+		return list
+	}
+
+	assert.Equal(t, []directoryListDirDTO{{Name: "games"}, {Name: "games (2)"}}, get("/api/directoryList").Dirs)
+	assert.Equal(t, []directoryListFileDTO{{Name: "a.nsp", Size: 1, ID: "0100000000010000", URL: "/api/download/0100000000010000/a.nsp"}}, get("/api/directoryList/games").Files)
+	assert.Equal(t, []directoryListFileDTO{{Name: "b.nsp", Size: 2, ID: "0100000000020000", URL: "/api/download/0100000000020000/b.nsp"}}, get("/api/directoryList/games%20%282%29").Files)
+}
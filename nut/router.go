@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/FrozenPear42/switch-library-manager/switchfs"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
@@ -11,43 +14,54 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-func NewRouter(db data.LibraryManager, reporter ProgressReporter) http.Handler {
+// NewRouter creates NUT API handler. Catalog and image cache are optional, without them titles are described by
+// library data only and images are not served. Without consoles store installed apps reports are not accepted. Without users in config the API is available without
+// authentication. Directory listing exposes library files under scan directories.
+func NewRouter(db data.LibraryManager, catalog storage.SwitchDatabaseCatalog, consoles storage.SwitchDatabaseConsoles, images *ImageCache, reporter ProgressReporter, config settings.NUTSettings, scanDirectories []string) http.Handler {
 	router := chi.NewRouter()
 
 	router.Use(middleware.Logger)
+	router.Use(newAuthenticator(config.Users).Middleware)
 
 	router.NotFound(HandleNotFound())
+	router.Get("/", HandleGetTinfoilIndex(db, catalog, config.TinfoilIndex))
 	router.Route("/api", func(r chi.Router) {
-		r.Get("/search", HandleGetSearch(db))
+		r.Get("/search", HandleGetSearch(db, catalog))
 		r.Get("/download/{titleId}/{fileName}", HandleGetDownload(db, reporter, false))
 		r.Get("/download/{titleId}/{fileName}/{start}", HandleGetDownload(db, reporter, false))
 		r.Get("/download/{titleId}/{fileName}/{start}/{stop}", HandleGetDownload(db, reporter, false))
 		r.Head("/download/{titleId}/{fileName}", HandleGetDownload(db, reporter, true))
 		r.Head("/download/{titleId}/{fileName}/{start}", HandleGetDownload(db, reporter, true))
 		r.Head("/download/{titleId}/{fileName}/{start}/{stop}", HandleGetDownload(db, reporter, true))
+		r.Get("/titles", HandleGetTitles(db, catalog))
+		r.Get("/titleImage/{titleId}", HandleGetImage(catalog, images, imageKindIcon))
+		r.Get("/titleImage/{titleId}/{width}", HandleGetImage(catalog, images, imageKindIcon))
+		r.Get("/bannerImage/{titleId}", HandleGetImage(catalog, images, imageKindBanner))
+		r.Get("/screenshotImage/{titleId}/{index}", HandleGetImage(catalog, images, imageKindScreenshot))
+		r.Get("/fileSize/{titleId}/{fileName}", HandleGetFileSize(db))
+		r.Get("/titleUpdates", HandleGetTitleUpdates(db, catalog))
+		r.Get("/directoryList", HandleGetDirectoryList(db, scanDirectories))
+		r.Get("/directoryList/*", HandleGetDirectoryList(db, scanDirectories))
+		if consoles != nil {
+			r.Post("/tinfoilSetInstalledApps", HandlePostInstalledApps(consoles))
+			r.Post("/tinfoilSetInstalledApps/{consoleId}", HandlePostInstalledApps(consoles))
+		}
 
 		// those are not used by tinfoil so we skip implementation for now
 		//r.Get("/user", HandleGetUser)
 		//r.Get("/scan", HandleGetUser)
-		//r.Get("/titles", HandleGetUser)
-		//r.Get("/titleImage", HandleGetUser)
-		//r.Get("/bannerImage", HandleGetUser)
 		//r.Get("/frontArtBoxImage", HandleGetUser)
-		//r.Get("/screenshotImage", HandleGetUser)
 		//r.Get("/preload", HandleGetUser)
 		//r.Get("/install", HandleGetUser)
 		//r.Get("/offsetAndSize", HandleGetUser)
-		//r.Get("/directoryList", HandleGetUser)
 		//r.Get("/file", HandleGetUser)
 		//r.Head("/file", HandleGetUser)
-		//r.Get("/fileSize", HandleGetUser)
-		//r.Get("/titleUpdates", HandleGetUser)
 		//r.Get("/organize", HandleGetUser)
 		//r.Get("/updateDb", HandleGetUser)
 		//r.Get("/export", HandleGetUser)
@@ -57,7 +71,6 @@ func NewRouter(db data.LibraryManager, reporter ProgressReporter) http.Handler {
 		//r.Get("/updateAllVersions", HandleGetUser)
 		//r.Get("/scrapeShogun", HandleGetUser)
 		//r.Get("/submitKey", HandleGetUser)
-		//r.Post("/tinfoilSetInstalledApps", HandleGetUser)
 		//r.Get("/switchList", HandleGetUser)
 		//r.Get("/switchInstalled", HandleGetUser)
 	})
@@ -80,7 +93,9 @@ func HandleNotFound() http.HandlerFunc {
 	}
 }
 
-func HandleGetSearch(db data.LibraryManager) http.HandlerFunc {
+// HandleGetSearch lists library files, optional "q" query parameter limits them to titles matching the query
+// ordered by relevance.
+func HandleGetSearch(db data.LibraryManager, catalog storage.SwitchDatabaseCatalog) http.HandlerFunc {
 	logger := zap.S()
 
 	return func(writer http.ResponseWriter, request *http.Request) {
@@ -90,48 +105,46 @@ func HandleGetSearch(db data.LibraryManager) http.HandlerFunc {
 			writer.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		user := requestUser(request)
 
-		// avoid duplicates - maps id to a file entry
-		fileMap := make(map[string]searchResultEntryDTO)
-
-		for _, entry := range entries {
-			// TODO: check if its supported, its just assumption it's not looking at NUT source code
-			if entry.IsMultiContent {
-				logger.Warnf("skipping file, multicontent not supported by NUT: %v", entry.FilePath)
-				continue
+		// maps title ID prefix to its position in search results
+		var ranks map[string]int
+		if query := request.URL.Query().Get("q"); query != "" {
+			results, err := data.SearchLibrary(entries, catalog, query)
+			if err != nil {
+				logger.Errorf("search failed: %v", err)
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
 			}
-			if entry.IsSplit {
-				logger.Warnf("skipping file, split files not supported by NUT: %v", entry.FilePath)
-				continue
+			ranks = make(map[string]int, len(results))
+			for rank, result := range results {
+				ranks[result.IDPrefix] = rank
 			}
+		}
 
-			var id string
-			var version int
-			if len(entry.DLCs) > 0 {
-				id = entry.DLCs[0].ID
-				version = entry.DLCs[0].Version
-			} else if len(entry.Updates) > 0 {
-				id = entry.Updates[0].ID
-				version = entry.Updates[0].Version
-			} else if len(entry.BaseGames) > 0 {
-				id = entry.BaseGames[0].ID
-				version = entry.BaseGames[0].Version
-			}
-			id = strings.ToUpper(id)
-			fileName := filepath.Base(entry.FilePath)
-			if oldEntry, ok := fileMap[id]; !ok || oldEntry.Version < version {
-				fileMap[id] = searchResultEntryDTO{
-					ID:      id,
-					Name:    fileName,
-					Size:    entry.FileSize,
-					Version: version,
+		files := libraryFiles(entries, user)
+		dto := make(searchResultDTO, 0, len(files))
+		for _, file := range files {
+			if ranks != nil {
+				if _, ok := ranks[searchTitlePrefix(file.id)]; !ok {
+					continue
 				}
 			}
+			dto = append(dto, searchResultEntryDTO{
+				ID:      file.id,
+				Name:    file.name,
+				Size:    int(file.size),
+				Version: file.version,
+			})
 		}
-
-		dto := make(searchResultDTO, 0, len(fileMap))
-		for _, v := range fileMap {
-			dto = append(dto, v)
+		if ranks != nil {
+			sort.Slice(dto, func(i, j int) bool {
+				rankI, rankJ := ranks[searchTitlePrefix(dto[i].ID)], ranks[searchTitlePrefix(dto[j].ID)]
+				if rankI != rankJ {
+					return rankI < rankJ
+				}
+				return dto[i].ID < dto[j].ID
+			})
 		}
 
 		writer.Header().Set("Content-Type", "application/json")
@@ -143,57 +156,171 @@ func HandleGetSearch(db data.LibraryManager) http.HandlerFunc {
 	}
 }
 
-func HandleGetDownload(db data.LibraryManager, progress ProgressReporter, isHead bool) http.HandlerFunc {
-	logger := zap.S()
+// requestedLibraryFile returns library file selected by "titleId" and "fileName" URL parameters. If the file is
+// not found or the user cannot access it, an error response is written and false is returned.
+func requestedLibraryFile(writer http.ResponseWriter, request *http.Request, db data.LibraryManager) (data.LibraryFileEntry, bool) {
+	titleID := chi.URLParam(request, "titleId")
+	fileName, err := url.PathUnescape(chi.URLParam(request, "fileName"))
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("invalid file format: %v", err), http.StatusBadRequest)
+		return data.LibraryFileEntry{}, false
+	}
 
-	return func(writer http.ResponseWriter, request *http.Request) {
-		var err error
-		titleID := chi.URLParam(request, "titleId")
-		fileName := chi.URLParam(request, "fileName")
+	files, err := db.GetFilesForID(titleID)
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not get file list: %v", err), http.StatusInternalServerError)
+		return data.LibraryFileEntry{}, false
+	}
 
-		fileName, err = url.PathUnescape(fileName)
-		if err != nil {
-			http.Error(writer, fmt.Sprintf("invalid file format: %v", err), http.StatusBadRequest)
-			return
-		}
+	idx := slices.IndexFunc(files, func(entry data.LibraryFileEntry) bool {
+		return libraryFileName(entry) == fileName
+	})
+	if idx == -1 {
+		http.Error(writer, fmt.Sprintf("could not find specified file %v", fileName), http.StatusBadRequest)
+		return data.LibraryFileEntry{}, false
+		// TODO: validate if its expected behaviour
+		//logger.Errorf("could not find specified file (%v), using first available (%v)", fileName, filePath)
+		//filePath = files[0].FilePath
+	}
+	if !canAccess(requestUser(request), files[idx], titleID) {
+		http.Error(writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return data.LibraryFileEntry{}, false
+	}
+	return files[idx], true
+}
+
+// libraryFile is a library file served for a single title.
+type libraryFile struct {
+	id      string
+	version int
+	kind    contentKind
+	name    string
+	size    int64
+}
 
-		files, err := db.GetFilesForID(titleID)
+// libraryFiles returns the newest file of every title the user can access, ordered by title ID. Multi-content
+// files are listed once for every title they contain.
+func libraryFiles(entries []data.LibraryFileEntry, user *settings.NUTUserSettings) []libraryFile {
+	logger := zap.S()
+
+	// avoid duplicates - maps id to a file entry
+	fileMap := make(map[string]libraryFile)
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		fileName := libraryFileName(entry)
+		fileSize, err := libraryFileSize(entry)
 		if err != nil {
-			http.Error(writer, fmt.Sprintf("could not get file list: %v", err), http.StatusInternalServerError)
-			return
+			logger.Warnf("skipping file, could not get its size: %v", err)
+			continue
 		}
-		if len(files) == 0 {
-			http.Error(writer, fmt.Sprintf("could not find specified file %v", fileName), http.StatusBadRequest)
-			return
+
+		for _, content := range libraryFileContents(entry) {
+			id := strings.ToUpper(content.id)
+			if !canAccess(user, entry, id) {
+				continue
+			}
+			if oldEntry, ok := fileMap[id]; !ok || oldEntry.version < content.version {
+				fileMap[id] = libraryFile{
+					id:      id,
+					version: content.version,
+					kind:    content.kind,
+					name:    fileName,
+					size:    fileSize,
+				}
+			}
 		}
+	}
 
-		var filePath string
-		idx := slices.IndexFunc(files, func(entry data.LibraryFileEntry) bool {
-			fn := filepath.Base(entry.FilePath)
-			return fn == fileName
-		})
-		if idx != -1 {
-			filePath = files[idx].FilePath
-		} else {
-			http.Error(writer, fmt.Sprintf("could not find specified file %v", fileName), http.StatusBadRequest)
+	files := make([]libraryFile, 0, len(fileMap))
+	for _, file := range fileMap {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].id < files[j].id
+	})
+	return files
+}
+
+type contentKind string
+
+const (
+	contentKindBase   contentKind = "base"
+	contentKindUpdate contentKind = "update"
+	contentKindDLC    contentKind = "dlc"
+)
+
+type libraryFileContent struct {
+	id      string
+	version int
+	kind    contentKind
+}
+
+// libraryFileContents returns IDs and versions of all titles in the file.
+func libraryFileContents(entry data.LibraryFileEntry) []libraryFileContent {
+	contents := make([]libraryFileContent, 0, len(entry.BaseGames)+len(entry.Updates)+len(entry.DLCs))
+	for _, game := range entry.BaseGames {
+		contents = append(contents, libraryFileContent{id: game.ID, version: game.Version, kind: contentKindBase})
+	}
+	for _, update := range entry.Updates {
+		contents = append(contents, libraryFileContent{id: update.ID, version: update.Version, kind: contentKindUpdate})
+	}
+	for _, dlc := range entry.DLCs {
+		contents = append(contents, libraryFileContent{id: dlc.ID, version: dlc.Version, kind: contentKindDLC})
+	}
+	return contents
+}
+
+// libraryFileName returns name the file is served as, split files are named after the folder holding the parts.
+func libraryFileName(entry data.LibraryFileEntry) string {
+	if entry.IsSplit {
+		return filepath.Base(filepath.Dir(entry.FilePath))
+	}
+	return filepath.Base(entry.FilePath)
+}
+
+// libraryFileSize returns size of the served file, which is size of all the parts for split files.
+func libraryFileSize(entry data.LibraryFileEntry) (int64, error) {
+	if entry.IsSplit {
+		return switchfs.FileSize(entry.FilePath)
+	}
+	return int64(entry.FileSize), nil
+}
+
+// searchTitlePrefix returns title prefix of base, update or DLC ID as used by search results.
+func searchTitlePrefix(id string) string {
+	if len(id) < 4 {
+		return id
+	}
+	return id[:len(id)-4]
+}
+
+func HandleGetDownload(db data.LibraryManager, progress ProgressReporter, isHead bool) http.HandlerFunc {
+	logger := zap.S()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		titleID := chi.URLParam(request, "titleId")
+		entry, ok := requestedLibraryFile(writer, request, db)
+		if !ok {
 			return
-			// TODO: validate if its expected behaviour
-			//logger.Errorf("could not find specified file (%v), using first available (%v)", fileName, filePath)
-			//filePath = files[0].FilePath
 		}
+		fileName := libraryFileName(entry)
+		filePath := entry.FilePath
 
-		f, err := os.Open(filePath)
+		// split files are read as a single file made of all the parts
+		f, err := switchfs.OpenFile(filePath)
 		if err != nil {
 			http.Error(writer, fmt.Sprintf("could not open the file %v", fileName), http.StatusBadRequest)
 			return
 		}
+		defer f.Close()
 
-		stat, err := f.Stat()
+		fileSize, err := switchfs.FileSize(filePath)
 		if err != nil {
 			http.Error(writer, fmt.Sprintf("could not stat the file %v", fileName), http.StatusBadRequest)
 			return
 		}
-		fileSize := stat.Size()
 
 		chunkSize := 0x400000
 		start := int64(0)
@@ -223,12 +350,16 @@ func HandleGetDownload(db data.LibraryManager, progress ProgressReporter, isHead
 					return
 				}
 			}
+			if chi.URLParam(request, "start") != "" && (start < 0 || start >= stop || stop > fileSize) {
+				http.Error(writer, fmt.Sprintf("invalid range %v-%v of the file %v", start, stop, fileName), http.StatusBadRequest)
+				return
+			}
 		}
 
-		logger.Debugf("serving file %v, in chunk %v-%v")
-		_, err = f.Seek(int64(start), io.SeekStart)
+		logger.Debugf("serving file %v, in chunk %v-%v", filePath, start, stop)
+		reader := io.NewSectionReader(f, start, stop-start)
 
-		contentFileName := titleID + filepath.Ext(filePath)
+		contentFileName := titleID + filepath.Ext(fileName)
 
 		writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", contentFileName))
 		writer.Header().Set("Content-type", "application/octet-stream")
@@ -247,7 +378,7 @@ func HandleGetDownload(db data.LibraryManager, progress ProgressReporter, isHead
 			if toWrite-totalWritten == 0 {
 				break
 			}
-			n, err := io.CopyN(writer, f, slices.Min([]int64{int64(chunkSize), toWrite - totalWritten}))
+			n, err := io.CopyN(writer, reader, slices.Min([]int64{int64(chunkSize), toWrite - totalWritten}))
 			if err == io.EOF {
 				break
 			}
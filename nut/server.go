@@ -1,10 +1,16 @@
 package nut
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
+	"sync"
 )
 
 type ProgressReporter interface {
@@ -12,39 +18,82 @@ type ProgressReporter interface {
 }
 
 type Server struct {
-	logger         *zap.SugaredLogger
-	serverHost     string
-	serverPort     int
-	libraryManager data.LibraryManager
-	reporter       ProgressReporter
-	//users      []struct {
-	//	Username string
-	//	Password string
-	//}
+	logger          *zap.SugaredLogger
+	config          settings.NUTSettings
+	scanDirectories []string
+	libraryManager  data.LibraryManager
+	catalog         storage.SwitchDatabaseCatalog
+	consoles        storage.SwitchDatabaseConsoles
+	images          *ImageCache
+	reporter        ProgressReporter
+
+	mutex  sync.Mutex
+	server *http.Server
 }
 
-func NewServer(host string, port int, libraryManager data.LibraryManager, reporter ProgressReporter) *Server {
+func NewServer(config settings.NUTSettings, scanDirectories []string, libraryManager data.LibraryManager, catalog storage.SwitchDatabaseCatalog, consoles storage.SwitchDatabaseConsoles, images *ImageCache, reporter ProgressReporter) *Server {
 	return &Server{
-		logger:         zap.S(),
-		serverHost:     host,
-		serverPort:     port,
-		libraryManager: libraryManager,
-		reporter:       reporter,
+		logger:          zap.S(),
+		config:          config,
+		scanDirectories: scanDirectories,
+		libraryManager:  libraryManager,
+		catalog:         catalog,
+		consoles:        consoles,
+		images:          images,
+		reporter:        reporter,
 	}
 }
 
-func (s *Server) Listen() error {
-	router := NewRouter(s.libraryManager, s.reporter)
+// Listen binds server address and starts serving requests in background. Returns the running server.
+func (s *Server) Listen() (*http.Server, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.server != nil {
+		return nil, errors.New("server is already running")
+	}
+
+	if len(s.config.Users) == 0 {
+		s.logger.Warnf("NUT server has no users configured, anyone on the network can download the library")
+	}
+	router := NewRouter(s.libraryManager, s.catalog, s.consoles, s.images, s.reporter, s.config, s.scanDirectories)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.config.Host, s.config.Port))
+	if err != nil {
+		return nil, fmt.Errorf("could not listen: %w", err)
+	}
 
-	server := http.Server{
-		Addr:    fmt.Sprintf("%s:%d", s.serverHost, s.serverPort),
+	server := &http.Server{
+		Addr:    listener.Addr().String(),
 		Handler: router,
 	}
-	err := server.ListenAndServe()
+	s.server = server
+	s.logger.Infof("started NUT server at %s", listener.Addr())
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("NUT server failed: %v", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// Shutdown gracefully stops the server, waiting for active connections until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	server := s.server
+	s.server = nil
+	s.mutex.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	err := server.Shutdown(ctx)
 	if err != nil {
 		return err
 	}
-	s.logger.Infof("started NUT server at %s:%d", s.serverHost, s.serverPort)
-
+	s.logger.Infof("stopped NUT server")
 	return nil
 }
@@ -0,0 +1,201 @@
+package nut
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+type fakeLibraryManager struct {
+	entries []data.LibraryFileEntry
+}
+
+func (f *fakeLibraryManager) Rescan(hardRescan bool, progressCallback data.ProgressCallback) (data.RescanSummary, error) {
+	return data.RescanSummary{}, nil
+}
+
+func (f *fakeLibraryManager) RescanPath(path string) error {
+	return nil
+}
+
+func (f *fakeLibraryManager) MoveEntries(moves map[string]string) error {
+	return nil
+}
+
+func (f *fakeLibraryManager) GetEntries() ([]data.LibraryFileEntry, error) {
+	return f.entries, nil
+}
+
+func (f *fakeLibraryManager) GetFilesForID(id string) ([]data.LibraryFileEntry, error) {
+	var result []data.LibraryFileEntry
+	for _, entry := range f.entries {
+		for _, game := range entry.BaseGames {
+			if game.ID == id {
+				result = append(result, entry)
+			}
+		}
+		for _, update := range entry.Updates {
+			if update.ID == id {
+				result = append(result, entry)
+			}
+		}
+		for _, dlc := range entry.DLCs {
+			if dlc.ID == id {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeLibraryManager) GetScanIssues() ([]data.ScanIssue, error) {
+	return nil, nil
+}
+
+func (f *fakeLibraryManager) Clear() error {
+	return nil
+}
+
+type nopReporter struct{}
+
+func (n nopReporter) ReportProgress(filePath string, downloaded, total int64) {}
+
+func TestServerListenAndShutdown(t *testing.T) {
+	server := NewServer(settings.NUTSettings{Host: "127.0.0.1"}, nil, &fakeLibraryManager{}, nil, nil, nil, nopReporter{})
+
+	httpServer, err := server.Listen()
+	assert.Nil(t, err)
+
+	_, err = server.Listen()
+	assert.NotNil(t, err)
+
+	res, err := http.Get("http://" + httpServer.Addr + "/api/search")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	err = server.Shutdown(context.Background())
+	assert.Nil(t, err)
+
+	_, err = http.Get("http://" + httpServer.Addr + "/api/search")
+	assert.NotNil(t, err)
+}
+
+func TestSearch(t *testing.T) {
+	library := &fakeLibraryManager{entries: []data.LibraryFileEntry{
+		{FilePath: "/library/Pokemon Sword.nsp", FileSize: 1, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}},
+		}},
+		{FilePath: "/library/Pokemon Sword DLC.nsp", FileSize: 2, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			DLCs: []data.SwitchFileDLC{{ForIDPrefix: "010000000001", ID: "0100000000011001"}},
+		}},
+		{FilePath: "/library/game.nsp", FileSize: 3, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000002", ID: "0100000000020000", Name: map[string]string{
+				"AmericanEnglish": "Pokémon™ Shield",
+			}}},
+		}},
+	}}
+	router := NewRouter(library, nil, nil, nil, nopReporter{}, settings.NUTSettings{}, nil)
+
+	search := func(query string) []string {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/search?q="+url.QueryEscape(query), nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var result searchResultDTO
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&result))
+		ids := make([]string, 0, len(result))
+		for _, entry := range result {
+			ids = append(ids, entry.ID)
+		}
+		return ids
+	}
+
+	assert.Len(t, search(""), 3)
+	assert.Equal(t, []string{"0100000000020000", "0100000000010000", "0100000000011001"}, search("pokemon"))
+	assert.Equal(t, []string{"0100000000020000"}, search("shiled"))
+	assert.Empty(t, search("zelda"))
+}
+
+func TestSplitAndMultiContentFiles(t *testing.T) {
+	// split file parts are named 00, 01... in a folder named like the file
+	folder := filepath.Join(t.TempDir(), "Split Game [0100000000030000].nsp")
+	assert.Nil(t, os.Mkdir(folder, 0755))
+	content := []byte("0123456789abcdefghij")
+	for part := 0; part*8 < len(content); part++ {
+		end := part*8 + 8
+		if end > len(content) {
+			end = len(content)
+		}
+		assert.Nil(t, os.WriteFile(filepath.Join(folder, fmt.Sprintf("%02d", part)), content[part*8:end], 0644))
+	}
+
+	library := &fakeLibraryManager{entries: []data.LibraryFileEntry{
+		{FilePath: filepath.Join(folder, "00"), FileSize: 8, IsSplit: true, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000003", ID: "0100000000030000"}},
+		}},
+		{FilePath: "/library/bundle.xci", FileSize: 100, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			IsMultiContent: true,
+			BaseGames:      []data.SwitchFileGame{{IDPrefix: "010000000004", ID: "0100000000040000"}},
+			Updates:        []data.SwitchFileUpdate{{ForIDPrefix: "010000000004", ID: "0100000000040800", Version: 65536}},
+			DLCs:           []data.SwitchFileDLC{{ForIDPrefix: "010000000004", ID: "0100000000041001"}},
+		}},
+	}}
+	router := NewRouter(library, nil, nil, nil, nopReporter{}, settings.NUTSettings{}, nil)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/search", nil))
+	var result searchResultDTO
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&result))
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	assert.Equal(t, searchResultDTO{
+		{ID: "0100000000030000", Name: "Split Game [0100000000030000].nsp", Size: len(content)},
+		{ID: "0100000000040000", Name: "bundle.xci", Size: 100},
+		{ID: "0100000000040800", Name: "bundle.xci", Size: 100, Version: 65536},
+		{ID: "0100000000041001", Name: "bundle.xci", Size: 100},
+	}, result)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		rangeHeader  string
+		contentRange string
+		body         string
+	}{
+		{"whole file", http.MethodGet, "", "", "bytes 0-19/20", string(content)},
+		{"range across parts", http.MethodGet, "", "bytes=6-17", "bytes 6-17/20", "6789abcdefgh"},
+		{"open range", http.MethodGet, "", "bytes=16-", "bytes 16-19/20", "ghij"},
+		{"path range", http.MethodGet, "/4/10", "", "bytes 4-9/20", "456789"},
+		{"head", http.MethodHead, "", "", "bytes 0-19/20", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/api/download/0100000000030000/"+url.PathEscape(filepath.Base(folder))+test.path, nil)
+			if test.rangeHeader != "" {
+				request.Header.Set("Range", test.rangeHeader)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			assert.Equal(t, http.StatusPartialContent, recorder.Code)
+			assert.Equal(t, test.contentRange, recorder.Header().Get("Content-Range"))
+			assert.Equal(t, "attachment; filename=0100000000030000.nsp", recorder.Header().Get("Content-Disposition"))
+			assert.Equal(t, test.body, recorder.Body.String())
+		})
+	}
+
+	for _, path := range []string{"/-1/4", "/10/4", "/4/4", "/0/21", "/20"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/download/0100000000030000/"+url.PathEscape(filepath.Base(folder))+path, nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+	}
+}
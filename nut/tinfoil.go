@@ -0,0 +1,157 @@
+package nut
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tinfoilIndexDTO is the JSON index Tinfoil loads from a file server location.
+type tinfoilIndexDTO struct {
+	Files       []tinfoilFileDTO           `json:"files"`
+	Directories []string                   `json:"directories"`
+	Success     string                     `json:"success,omitempty"`
+	Referrer    string                     `json:"referrer,omitempty"`
+	Version     string                     `json:"version,omitempty"`
+	Headers     []string                   `json:"headers,omitempty"`
+	TitleDB     map[string]tinfoilTitleDTO `json:"titledb"`
+}
+
+type tinfoilFileDTO struct {
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+type tinfoilTitleDTO struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	Version     int    `json:"version,omitempty"`
+	Region      string `json:"region,omitempty"`
+	ReleaseDate int    `json:"releaseDate,omitempty"`
+	Publisher   string `json:"publisher,omitempty"`
+	Description string `json:"description,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	IconURL     string `json:"iconUrl,omitempty"`
+	BannerURL   string `json:"bannerUrl,omitempty"`
+}
+
+// HandleGetTinfoilIndex lists library files the user can access as a Tinfoil index. File URLs point at the
+// download handler, titles are described with catalog data when the catalog is available.
+func HandleGetTinfoilIndex(db data.LibraryManager, catalog storage.SwitchDatabaseCatalog, indexSettings settings.TinfoilIndexSettings) http.HandlerFunc {
+	logger := zap.S()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		entries, err := db.GetEntries()
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		scheme := "http"
+		if request.TLS != nil {
+			scheme = "https"
+		}
+		baseURL := fmt.Sprintf("%s://%s/api/download/", scheme, request.Host)
+
+		index := tinfoilIndexDTO{
+			Files:       []tinfoilFileDTO{},
+			Directories: []string{},
+			Success:     indexSettings.MessageOfTheDay,
+			Referrer:    indexSettings.Referrer,
+			Version:     indexSettings.Version,
+			Headers:     indexSettings.Headers,
+			TitleDB:     make(map[string]tinfoilTitleDTO),
+		}
+		for _, file := range libraryFiles(entries, requestUser(request)) {
+			title, ok, err := tinfoilTitle(catalog, file.id)
+			if err != nil {
+				logger.Errorf("could not read catalog entry %v: %v", file.id, err)
+				writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				index.TitleDB[file.id] = title
+			}
+
+			// Tinfoil identifies titles by the file name in the URL fragment
+			name := title.Name
+			if name == "" {
+				name = strings.TrimSuffix(file.name, filepath.Ext(file.name))
+			}
+			fragment := fmt.Sprintf("%s [%s][v%d]%s", name, file.id, file.version, filepath.Ext(file.name))
+			index.Files = append(index.Files, tinfoilFileDTO{
+				URL:  baseURL + file.id + "/" + url.PathEscape(file.name) + "#" + url.PathEscape(fragment),
+				Size: file.size,
+			})
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(index)
+		if err != nil {
+			logger.Errorf("failed to write json response: %v", err)
+		}
+	}
+}
+
+// tinfoilTitle returns catalog data of the base title or DLC, updates are described by their base title.
+func tinfoilTitle(catalog storage.SwitchDatabaseCatalog, id string) (tinfoilTitleDTO, bool, error) {
+	entry, titleData, ok, err := catalogTitleData(catalog, id)
+	if err != nil || !ok {
+		return tinfoilTitleDTO{}, false, err
+	}
+
+	// versions of the catalog entry are versions of base title updates
+	latestVersion := 0
+	if titleData.ID == entry.ID {
+		for _, version := range entry.Versions {
+			if version.Version > latestVersion {
+				latestVersion = version.Version
+			}
+		}
+	}
+
+	releaseDate, _ := strconv.Atoi(strings.ReplaceAll(titleData.ReleaseDate, "-", ""))
+	return tinfoilTitleDTO{
+		ID:          id,
+		Name:        titleData.Name,
+		Version:     latestVersion,
+		Region:      titleData.Region,
+		ReleaseDate: releaseDate,
+		Publisher:   titleData.Publisher,
+		Description: titleData.Description,
+		Size:        titleData.Size,
+		IconURL:     titleData.IconURL,
+		BannerURL:   titleData.BannerURL,
+	}, true, nil
+}
+
+// catalogTitleData returns catalog entry of the title with the base, update or DLC ID, and data of the DLC or
+// the base title. Catalog is optional, without it nothing is found.
+func catalogTitleData(catalog storage.SwitchDatabaseCatalog, id string) (storage.CatalogEntry, storage.CatalogEntryData, bool, error) {
+	if catalog == nil {
+		return storage.CatalogEntry{}, storage.CatalogEntryData{}, false, nil
+	}
+	entry, ok, err := catalog.GetCatalogEntryByID(id)
+	// catalog lists only the recent update of a title, other updates are looked up by the base title ID
+	if err == nil && !ok && strings.HasSuffix(strings.ToUpper(id), "800") {
+		entry, ok, err = catalog.GetCatalogEntryByID(data.BaseTitleID(id))
+	}
+	if err != nil || !ok {
+		return storage.CatalogEntry{}, storage.CatalogEntryData{}, false, err
+	}
+	for _, dlc := range entry.DLCs {
+		if strings.EqualFold(dlc.ID, id) {
+			return entry, dlc.CatalogEntryData, true, nil
+		}
+	}
+	return entry, entry.CatalogEntryData, true, nil
+}
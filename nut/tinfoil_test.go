@@ -0,0 +1,111 @@
+package nut
+
+import (
+	"encoding/json"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/settings"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTinfoilIndex(t *testing.T) {
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	err = db.ReplaceCatalog(map[string]storage.CatalogEntry{
+		"0100000000010000": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Pokémon Sword", Publisher: "Nintendo", Region: "US", ReleaseDate: "2019-11-15", Size: 1000},
+			Versions:         []storage.CatalogEntryVersion{{Version: 65536}, {Version: 131072}},
+			DLCs:             []storage.CatalogEntryDLC{{CatalogEntryData: storage.CatalogEntryData{ID: "0100000000011001", Name: "Expansion Pass"}}},
+		},
+	}, storage.CatalogMetadata{})
+	assert.Nil(t, err)
+
+	directory := t.TempDir()
+	for _, name := range []string{"sword.nsp", "dlc.nsp", "homebrew.nsp"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(directory, name), []byte("data"), 0644))
+	}
+	library := &fakeLibraryManager{entries: []data.LibraryFileEntry{
+		{FilePath: filepath.Join(directory, "sword.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "010000000001", ID: "0100000000010000"}},
+		}},
+		{FilePath: filepath.Join(directory, "dlc.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			DLCs: []data.SwitchFileDLC{{ForIDPrefix: "010000000001", ID: "0100000000011001", Version: 65536}},
+		}},
+		{FilePath: filepath.Join(directory, "homebrew.nsp"), FileSize: 4, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			BaseGames: []data.SwitchFileGame{{IDPrefix: "05000000000A", ID: "05000000000A0000"}},
+		}},
+	}}
+
+	hash, err := HashPassword("secret")
+	assert.Nil(t, err)
+	router := NewRouter(library, db, nil, nil, nopReporter{}, settings.NUTSettings{
+		Users: []settings.NUTUserSettings{
+			{Username: "admin", PasswordHash: hash},
+			{Username: "guest", PasswordHash: hash, AllowedTitleIDs: []string{"05000000000A0000"}},
+		},
+		TinfoilIndex: settings.TinfoilIndexSettings{
+			MessageOfTheDay: "Welcome",
+			Referrer:        "https://example.com",
+			Version:         "17.0",
+			Headers:         []string{"X-Library: home"},
+		},
+	}, nil)
+
+	index := func(username string) (int, tinfoilIndexDTO) {
+		request := httptest.NewRequest(http.MethodGet, "http://switch.local:9000/", nil)
+		if username != "" {
+			request.SetBasicAuth(username, "secret")
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var result tinfoilIndexDTO
+		if recorder.Code == http.StatusOK {
+			assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&result))
+		}
+		return recorder.Code, result
+	}
+
+	code, _ := index("")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, result := index("admin")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Welcome", result.Success)
+	assert.Equal(t, "https://example.com", result.Referrer)
+	assert.Equal(t, "17.0", result.Version)
+	assert.Equal(t, []string{"X-Library: home"}, result.Headers)
+	assert.Equal(t, []string{}, result.Directories)
+	assert.Equal(t, []tinfoilFileDTO{
+		{URL: "http://switch.local:9000/api/download/0100000000010000/sword.nsp#" + url.PathEscape("Pokémon Sword [0100000000010000][v0].nsp"), Size: 4},
+		{URL: "http://switch.local:9000/api/download/0100000000011001/dlc.nsp#" + url.PathEscape("Expansion Pass [0100000000011001][v65536].nsp"), Size: 4},
+		{URL: "http://switch.local:9000/api/download/05000000000A0000/homebrew.nsp#" + url.PathEscape("homebrew [05000000000A0000][v0].nsp"), Size: 4},
+	}, result.Files)
+	assert.Equal(t, map[string]tinfoilTitleDTO{
+		"0100000000010000": {ID: "0100000000010000", Name: "Pokémon Sword", Version: 131072, Region: "US", ReleaseDate: 20191115, Publisher: "Nintendo", Size: 1000},
+		"0100000000011001": {ID: "0100000000011001", Name: "Expansion Pass"},
+	}, result.TitleDB)
+
+	// listed URLs are served by the download handler, clients do not send the fragment
+	for _, file := range result.Files {
+		fileURL, err := url.Parse(file.URL)
+		assert.Nil(t, err)
+		fileURL.Fragment = ""
+		request := httptest.NewRequest(http.MethodGet, fileURL.String(), nil)
+		request.SetBasicAuth("admin", "secret")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusPartialContent, recorder.Code, file.URL)
+		assert.Equal(t, "data", recorder.Body.String())
+	}
+
+	_, result = index("guest")
+	assert.Len(t, result.Files, 1)
+	assert.Empty(t, result.TitleDB)
+}
@@ -0,0 +1,346 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// QuarantineDirectoryName is name of the folder created inside scan directories for quarantined files.
+	// It is hidden, so it is skipped by the library scan.
+	QuarantineDirectoryName = ".slm-quarantine"
+	quarantineManifestName  = "manifest.json"
+)
+
+var (
+	ErrNotQuarantined = errors.New("file is not quarantined")
+)
+
+type CleanupReason string
+
+const (
+	// CleanupReasonOldUpdate means that a newer version of the update is present in the library
+	CleanupReasonOldUpdate CleanupReason = "oldUpdate"
+	// CleanupReasonDuplicate means that the same content is present in another library file
+	CleanupReasonDuplicate CleanupReason = "duplicate"
+)
+
+type CleanupCandidate struct {
+	FilePath string
+	IsSplit  bool
+	Reason   CleanupReason
+	TitleID  string
+	Version  int
+	// KeptFilePath is the library file that is kept instead of the candidate
+	KeptFilePath string
+}
+
+type QuarantineEntry struct {
+	OriginalPath   string        `json:"originalPath"`
+	QuarantinePath string        `json:"quarantinePath"`
+	Reason         CleanupReason `json:"reason"`
+	TitleID        string        `json:"titleID"`
+	QuarantinedAt  time.Time     `json:"quarantinedAt"`
+}
+
+type CleanupResult struct {
+	Entries []QuarantineEntry
+	// Errors holds errors by library file path
+	Errors map[string]error
+}
+
+type cleanupContent struct {
+	entry   data.LibraryFileEntry
+	id      string
+	version int
+}
+
+// FindCleanupCandidates finds superseded update files and duplicated base game, update and DLC files.
+// Out of the files with the same content the one with the highest version is kept. With equal versions NSP is
+// preferred over XCI, NSZ and XCZ, then files with metadata read using keys over the ones parsed from file names.
+// Multi-content files are never removed as they may hold contents that are not present elsewhere.
+func FindCleanupCandidates(entries []data.LibraryFileEntry) []CleanupCandidate {
+	updates := make(map[string][]cleanupContent)
+	contents := make(map[string][]cleanupContent)
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		for _, game := range entry.BaseGames {
+			contents[game.ID] = append(contents[game.ID], cleanupContent{entry: entry, id: game.ID, version: game.Version})
+		}
+		for _, dlc := range entry.DLCs {
+			contents[dlc.ID] = append(contents[dlc.ID], cleanupContent{entry: entry, id: dlc.ID, version: dlc.Version})
+		}
+		for _, update := range entry.Updates {
+			updates[update.ID] = append(updates[update.ID], cleanupContent{entry: entry, id: update.ID, version: update.Version})
+		}
+	}
+
+	candidates := make(map[string]CleanupCandidate)
+	addCandidates := func(group []cleanupContent, olderReason CleanupReason) {
+		if len(group) < 2 {
+			return
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			return isPreferredCleanupContent(group[i], group[j])
+		})
+		keeper := group[0]
+		for _, content := range group[1:] {
+			if content.entry.IsMultiContent || content.entry.FilePath == keeper.entry.FilePath {
+				continue
+			}
+			if _, ok := candidates[content.entry.FilePath]; ok {
+				continue
+			}
+			reason := CleanupReasonDuplicate
+			if content.version < keeper.version {
+				reason = olderReason
+			}
+			candidates[content.entry.FilePath] = CleanupCandidate{
+				FilePath:     content.entry.FilePath,
+				IsSplit:      content.entry.IsSplit,
+				Reason:       reason,
+				TitleID:      content.id,
+				Version:      content.version,
+				KeptFilePath: keeper.entry.FilePath,
+			}
+		}
+	}
+	for _, group := range updates {
+		addCandidates(group, CleanupReasonOldUpdate)
+	}
+	for _, group := range contents {
+		addCandidates(group, CleanupReasonDuplicate)
+	}
+
+	result := make([]CleanupCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, candidate)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FilePath < result[j].FilePath
+	})
+	return result
+}
+
+var cleanupFormatPriority = map[string]int{
+	"nsp": 0,
+	"xci": 1,
+	"nsz": 2,
+	"xcz": 3,
+}
+
+func cleanupFormatRank(entry data.LibraryFileEntry) int {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(organizeSourcePath(entry)), "."))
+	if rank, ok := cleanupFormatPriority[format]; ok {
+		return rank
+	}
+	return len(cleanupFormatPriority)
+}
+
+func isPreferredCleanupContent(a, b cleanupContent) bool {
+	if a.version != b.version {
+		return a.version > b.version
+	}
+	if rankA, rankB := cleanupFormatRank(a.entry), cleanupFormatRank(b.entry); rankA != rankB {
+		return rankA < rankB
+	}
+	if a.entry.ExtractionType != b.entry.ExtractionType {
+		return a.entry.ExtractionType == data.ExtractionTypeKey
+	}
+	return a.entry.FilePath < b.entry.FilePath
+}
+
+// QuarantineFiles moves candidates into the quarantine folder of their scan directory, keeping relative paths,
+// so they can be restored later. Split files are moved together with their folder. Candidates of a scan directory
+// with unreadable manifest are not moved and are reported in the result.
+func QuarantineFiles(candidates []CleanupCandidate, scanDirectories []string) (CleanupResult, error) {
+	result := CleanupResult{Errors: make(map[string]error)}
+	quarantinedAt := time.Now()
+	runDirectory := quarantinedAt.Format("20060102-150405")
+
+	manifests := make(map[string][]QuarantineEntry)
+	manifestErrors := make(map[string]error)
+	for _, candidate := range candidates {
+		source := candidate.FilePath
+		if candidate.IsSplit {
+			source = filepath.Dir(source)
+		}
+		root := organizeRootDirectory(scanDirectories, filepath.Dir(source))
+		relativePath, err := filepath.Rel(root, source)
+		if err != nil {
+			result.Errors[candidate.FilePath] = err
+			continue
+		}
+
+		if err, ok := manifestErrors[root]; ok {
+			result.Errors[candidate.FilePath] = err
+			continue
+		}
+		if _, ok := manifests[root]; !ok {
+			manifest, err := readQuarantineManifest(root)
+			if err != nil {
+				manifestErrors[root] = err
+				result.Errors[candidate.FilePath] = err
+				continue
+			}
+			manifests[root] = manifest
+		}
+
+		destination := filepath.Join(root, QuarantineDirectoryName, runDirectory, relativePath)
+		err = moveFile(source, destination)
+		if err != nil {
+			result.Errors[candidate.FilePath] = err
+			continue
+		}
+		entry := QuarantineEntry{
+			OriginalPath:   source,
+			QuarantinePath: destination,
+			Reason:         candidate.Reason,
+			TitleID:        candidate.TitleID,
+			QuarantinedAt:  quarantinedAt,
+		}
+		manifests[root] = append(manifests[root], entry)
+		result.Entries = append(result.Entries, entry)
+	}
+
+	// every manifest is written even if some fail, so that moved files can be found
+	var writeErr error
+	for root, manifest := range manifests {
+		err := writeQuarantineManifest(root, manifest)
+		if err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	return result, writeErr
+}
+
+// ListQuarantine returns quarantined files of all the scan directories.
+func ListQuarantine(scanDirectories []string) ([]QuarantineEntry, error) {
+	var result []QuarantineEntry
+	for _, directory := range scanDirectories {
+		manifest, err := readQuarantineManifest(filepath.Clean(directory))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, manifest...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OriginalPath < result[j].OriginalPath
+	})
+	return result, nil
+}
+
+// RestoreQuarantined moves quarantined files back to their original paths. Files are identified by quarantine path.
+func RestoreQuarantined(quarantinePaths []string, scanDirectories []string) (CleanupResult, error) {
+	result := CleanupResult{Errors: make(map[string]error)}
+	restore := make(map[string]struct{}, len(quarantinePaths))
+	for _, path := range quarantinePaths {
+		restore[path] = struct{}{}
+	}
+
+	for _, directory := range scanDirectories {
+		root := filepath.Clean(directory)
+		manifest, err := readQuarantineManifest(root)
+		if err != nil {
+			return result, err
+		}
+
+		var remaining []QuarantineEntry
+		for _, entry := range manifest {
+			if _, ok := restore[entry.QuarantinePath]; !ok {
+				remaining = append(remaining, entry)
+				continue
+			}
+			delete(restore, entry.QuarantinePath)
+			err := moveFile(entry.QuarantinePath, entry.OriginalPath)
+			if err != nil {
+				result.Errors[entry.QuarantinePath] = err
+				remaining = append(remaining, entry)
+				continue
+			}
+			result.Entries = append(result.Entries, entry)
+		}
+		if len(remaining) == len(manifest) {
+			continue
+		}
+
+		err = writeQuarantineManifest(root, remaining)
+		if err != nil {
+			return result, err
+		}
+		_ = deleteEmptyQuarantineFolders(filepath.Join(root, QuarantineDirectoryName))
+	}
+
+	for path := range restore {
+		result.Errors[path] = ErrNotQuarantined
+	}
+	return result, nil
+}
+
+func readQuarantineManifest(root string) ([]QuarantineEntry, error) {
+	content, err := os.ReadFile(filepath.Join(root, QuarantineDirectoryName, quarantineManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read quarantine manifest: %w", err)
+	}
+	var manifest []QuarantineEntry
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode quarantine manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeQuarantineManifest(root string, manifest []QuarantineEntry) error {
+	if manifest == nil {
+		manifest = []QuarantineEntry{}
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode quarantine manifest: %w", err)
+	}
+	err = os.MkdirAll(filepath.Join(root, QuarantineDirectoryName), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create quarantine folder: %w", err)
+	}
+	err = os.WriteFile(filepath.Join(root, QuarantineDirectoryName, quarantineManifestName), content, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write quarantine manifest: %w", err)
+	}
+	return nil
+}
+
+// deleteEmptyQuarantineFolders removes folders left empty after files were restored.
+func deleteEmptyQuarantineFolders(quarantineDirectory string) error {
+	var folders []string
+	err := filepath.WalkDir(quarantineDirectory, func(path string, info os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != quarantineDirectory {
+			folders = append(folders, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return len(folders[i]) > len(folders[j])
+	})
+	for _, folder := range folders {
+		_ = os.Remove(folder)
+	}
+	return nil
+}
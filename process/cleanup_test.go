@@ -0,0 +1,109 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newUpdateFile(path string, idPrefix string, version int) data.LibraryFileEntry {
+	return data.LibraryFileEntry{FilePath: path, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+		Updates:        []data.SwitchFileUpdate{{ForIDPrefix: idPrefix, ID: idPrefix + "0800", Version: version}},
+		ExtractionType: data.ExtractionTypeFilename,
+	}}
+}
+
+func TestFindCleanupCandidates(t *testing.T) {
+	oldUpdate := newUpdateFile("/lib/update v1.nsp", "010000000001", 65536)
+	newUpdate := newUpdateFile("/lib/update v2.nsz", "010000000001", 131072)
+	sameUpdate := newUpdateFile("/lib/update v2.nsp", "010000000001", 131072)
+
+	nszBase := newGameFile("/lib/a.nsz", "010000000002", 0)
+	nspBase := newGameFile("/lib/b.nsp", "010000000002", 0)
+	keyBase := newGameFile("/lib/c.nsp", "010000000002", 0)
+	keyBase.ExtractionType = data.ExtractionTypeKey
+
+	multi := newGameFile("/lib/multi.xci", "010000000003", 0)
+	multi.IsMultiContent = true
+	single := newGameFile("/lib/single.xci", "010000000003", 0)
+
+	candidates := FindCleanupCandidates([]data.LibraryFileEntry{oldUpdate, newUpdate, sameUpdate, nszBase, nspBase, keyBase, multi, single})
+	assert.Equal(t, []CleanupCandidate{
+		{FilePath: "/lib/a.nsz", Reason: CleanupReasonDuplicate, TitleID: "0100000000020000", KeptFilePath: "/lib/c.nsp"},
+		{FilePath: "/lib/b.nsp", Reason: CleanupReasonDuplicate, TitleID: "0100000000020000", KeptFilePath: "/lib/c.nsp"},
+		{FilePath: "/lib/single.xci", Reason: CleanupReasonDuplicate, TitleID: "0100000000030000", KeptFilePath: "/lib/multi.xci"},
+		{FilePath: "/lib/update v1.nsp", Reason: CleanupReasonOldUpdate, TitleID: "0100000000010800", Version: 65536, KeptFilePath: "/lib/update v2.nsp"},
+		{FilePath: "/lib/update v2.nsz", Reason: CleanupReasonDuplicate, TitleID: "0100000000010800", Version: 131072, KeptFilePath: "/lib/update v2.nsp"},
+	}, candidates)
+}
+
+func TestQuarantineFiles(t *testing.T) {
+	dir := t.TempDir()
+	updatePath := filepath.Join(dir, "sub", "update.nsp")
+	splitPath := filepath.Join(dir, "split.xci", "00")
+	for _, path := range []string{updatePath, splitPath} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(path), 0644))
+	}
+
+	result, err := QuarantineFiles([]CleanupCandidate{
+		{FilePath: updatePath, Reason: CleanupReasonOldUpdate, TitleID: "0100000000010800"},
+		{FilePath: splitPath, IsSplit: true, Reason: CleanupReasonDuplicate, TitleID: "0100000000020000"},
+	}, []string{dir})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Len(t, result.Entries, 2)
+	assert.NoFileExists(t, updatePath)
+	assert.NoDirExists(t, filepath.Dir(splitPath))
+	for _, entry := range result.Entries {
+		assert.Contains(t, entry.QuarantinePath, filepath.Join(dir, QuarantineDirectoryName))
+	}
+	assert.FileExists(t, filepath.Join(result.Entries[1].QuarantinePath, "00"))
+
+	listed, err := ListQuarantine([]string{dir})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 2)
+	assert.Equal(t, filepath.Dir(splitPath), listed[0].OriginalPath)
+
+	restored, err := RestoreQuarantined([]string{result.Entries[0].QuarantinePath, "/missing"}, []string{dir})
+	assert.NoError(t, err)
+	assert.Len(t, restored.Entries, 1)
+	assert.ErrorIs(t, restored.Errors["/missing"], ErrNotQuarantined)
+	assert.FileExists(t, updatePath)
+
+	listed, err = ListQuarantine([]string{dir})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, filepath.Dir(splitPath), listed[0].OriginalPath)
+}
+
+func TestQuarantineFilesUnreadableManifest(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	firstPath := filepath.Join(first, "a.nsp")
+	secondPath := filepath.Join(second, "b.nsp")
+	for _, path := range []string{firstPath, secondPath} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(path), 0644))
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(second, QuarantineDirectoryName), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(second, QuarantineDirectoryName, quarantineManifestName), []byte("{"), 0644))
+
+	result, err := QuarantineFiles([]CleanupCandidate{
+		{FilePath: firstPath, Reason: CleanupReasonDuplicate, TitleID: "0100000000010000"},
+		{FilePath: secondPath, Reason: CleanupReasonDuplicate, TitleID: "0100000000020000"},
+	}, []string{first, second})
+	assert.NoError(t, err)
+	assert.Len(t, result.Entries, 1)
+	assert.Len(t, result.Errors, 1)
+	assert.Error(t, result.Errors[secondPath])
+	assert.FileExists(t, secondPath)
+
+	listed, err := ListQuarantine([]string{first})
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+	assert.Equal(t, firstPath, listed[0].OriginalPath)
+}
@@ -0,0 +1,68 @@
+package process
+
+import (
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+)
+
+// CompletionStat holds number of owned items out of all the known ones.
+type CompletionStat struct {
+	Owned int
+	Total int
+}
+
+// Percentage returns percentage of owned items.
+func (s CompletionStat) Percentage() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Owned) / float64(s.Total) * 100
+}
+
+type LibraryCompletion struct {
+	// Titles compares base games in the library with all the catalog titles
+	Titles CompletionStat
+	// DLC compares DLCs in the library with catalog DLCs of owned titles
+	DLC CompletionStat
+	// Updates compares owned titles on the latest update with all owned titles that have any update released
+	Updates CompletionStat
+}
+
+// CalculateLibraryCompletion compares contents of the library with the catalog.
+// DLCs with IDs present in ignoreIDs are skipped.
+func CalculateLibraryCompletion(entries []data.LibraryFileEntry, catalog storage.SwitchDatabaseCatalog, ignoreIDs []string) (LibraryCompletion, error) {
+	page, err := catalog.GetCatalogEntries(nil, 1, "")
+	if err != nil {
+		return LibraryCompletion{}, fmt.Errorf("could not get catalog entries: %w", err)
+	}
+
+	ignored := newIgnoredIDs(ignoreIDs)
+	completion := LibraryCompletion{
+		Titles: CompletionStat{Total: page.TotalCount},
+	}
+	for _, title := range groupLibraryTitles(entries) {
+		if len(title.BaseGames) == 0 {
+			continue
+		}
+		completion.Titles.Owned += 1
+
+		catalogEntry, err := catalog.GetCatalogEntryByIDPrefix(title.IDPrefix)
+		if err != nil {
+			continue
+		}
+
+		owned, missing := title.compareDLCs(catalogEntry, ignored)
+		completion.DLC.Owned += owned
+		completion.DLC.Total += owned + len(missing)
+
+		latestVersion, _ := latestCatalogVersion(catalogEntry)
+		if latestVersion > 0 {
+			completion.Updates.Total += 1
+			if localVersion, _ := title.localVersion(); localVersion >= latestVersion {
+				completion.Updates.Owned += 1
+			}
+		}
+	}
+	return completion, nil
+}
@@ -0,0 +1,92 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"strings"
+)
+
+// ConsoleInstall is version of a title installed on a single console.
+type ConsoleInstall struct {
+	ConsoleID        string
+	InstalledVersion int
+	// IsBehind is set when the library has a newer version of the title than the installed one
+	IsBehind bool
+}
+
+// TitleInstallState describes which consoles have a library title installed.
+type TitleInstallState struct {
+	TitleID string
+	Name    string
+	// LocalVersion is the latest version of the title available in the library
+	LocalVersion int
+	Consoles     []ConsoleInstall
+}
+
+// ScanConsoleInstalls cross-references titles installed on consoles with the library. Only titles that are both
+// in the library and installed on at least one console are returned, consoles are in the order of reports.
+// Installed version of a title is the highest version reported for its base game or update.
+func ScanConsoleInstalls(entries []data.LibraryFileEntry, reports []storage.ConsoleReport, catalog storage.SwitchDatabaseCatalog) []TitleInstallState {
+	// installed versions by console, by uppercase title ID prefix
+	installed := make([]map[string]int, len(reports))
+	for i, report := range reports {
+		installed[i] = make(map[string]int)
+		for _, title := range report.Titles {
+			id := strings.ToUpper(title.ID)
+			if len(id) != 16 || !(strings.HasSuffix(id, "000") || strings.HasSuffix(id, "800")) {
+				continue
+			}
+			prefix := id[:12]
+			if version, ok := installed[i][prefix]; !ok || title.Version > version {
+				installed[i][prefix] = title.Version
+			}
+		}
+	}
+
+	var result []TitleInstallState
+	for _, title := range groupLibraryTitles(entries) {
+		if len(title.BaseGames) == 0 && len(title.Updates) == 0 {
+			continue
+		}
+		prefix := strings.ToUpper(title.IDPrefix)
+
+		localVersion, _ := title.localVersion()
+
+		var consoles []ConsoleInstall
+		for i, report := range reports {
+			version, ok := installed[i][prefix]
+			if !ok {
+				continue
+			}
+			consoles = append(consoles, ConsoleInstall{
+				ConsoleID:        report.ConsoleID,
+				InstalledVersion: version,
+				IsBehind:         version < localVersion,
+			})
+		}
+		if len(consoles) == 0 {
+			continue
+		}
+
+		state := TitleInstallState{
+			LocalVersion: localVersion,
+			Consoles:     consoles,
+		}
+		if len(title.BaseGames) > 0 {
+			state.TitleID = strings.ToUpper(title.BaseGames[0].ID)
+		} else {
+			state.TitleID = data.BaseTitleID(title.Updates[0].ID)
+		}
+		if catalog != nil {
+			catalogEntry, err := catalog.GetCatalogEntryByIDPrefix(title.IDPrefix)
+			if err == nil {
+				state.Name = catalogEntry.Name
+				if catalogEntry.ID != "" {
+					state.TitleID = strings.ToUpper(catalogEntry.ID)
+				}
+			}
+		}
+		result = append(result, state)
+	}
+	return result
+}
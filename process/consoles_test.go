@@ -0,0 +1,49 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScanConsoleInstalls(t *testing.T) {
+	catalog := &fakeCatalog{entries: map[string]storage.CatalogEntry{
+		"010000000001": {CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Updated"}},
+	}}
+	entries := []data.LibraryFileEntry{
+		newGameFile("/a.nsp", "010000000001", 131072),
+		newGameFile("/b.nsp", "010000000002", 0),
+		newGameFile("/c.nsp", "010000000003", 0),
+		{FilePath: "/d.nsp", LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			Updates: []data.SwitchFileUpdate{{ForIDPrefix: "01007EF00011", ID: "01007EF00011E800", Version: 65536}},
+		}},
+	}
+	reports := []storage.ConsoleReport{
+		{ConsoleID: "bedroom", Titles: []storage.ConsoleReportTitle{
+			{ID: "0100000000010000"},
+			{ID: "0100000000010800", Version: 65536},
+			{ID: "0100000000011001", Version: 262144},
+			{ID: "0100000000020000"},
+		}},
+		{ConsoleID: "living-room", Titles: []storage.ConsoleReportTitle{
+			{ID: "0100000000010800", Version: 131072},
+			{ID: "0100000000040000"},
+			{ID: "01007EF00011E000"},
+		}},
+	}
+
+	states := ScanConsoleInstalls(entries, reports, catalog)
+	assert.Equal(t, []TitleInstallState{
+		{TitleID: "0100000000010000", Name: "Updated", LocalVersion: 131072, Consoles: []ConsoleInstall{
+			{ConsoleID: "bedroom", InstalledVersion: 65536, IsBehind: true},
+			{ConsoleID: "living-room", InstalledVersion: 131072},
+		}},
+		{TitleID: "0100000000020000", Consoles: []ConsoleInstall{
+			{ConsoleID: "bedroom"},
+		}},
+		{TitleID: "01007EF00011E000", LocalVersion: 65536, Consoles: []ConsoleInstall{
+			{ConsoleID: "living-room", IsBehind: true},
+		}},
+	}, states)
+}
@@ -0,0 +1,77 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"sort"
+)
+
+// libraryTitle groups contents of all the library files belonging to a single title.
+type libraryTitle struct {
+	IDPrefix  string
+	BaseGames []data.SwitchFileGame
+	Updates   []data.SwitchFileUpdate
+	DLCs      map[string][]data.SwitchFileDLC
+}
+
+// localVersion returns the highest version of the title in the library, base game dumps may already ship with
+// an update applied.
+func (t *libraryTitle) localVersion() (int, string) {
+	version := 0
+	readableVersion := ""
+	for _, game := range t.BaseGames {
+		if game.Version > version {
+			version = game.Version
+			readableVersion = game.ReadableVersion
+		}
+	}
+	for _, update := range t.Updates {
+		if update.Version > version {
+			version = update.Version
+			readableVersion = update.ReadableVersion
+		}
+	}
+	return version, readableVersion
+}
+
+// groupLibraryTitles groups library files by title ID prefix, result is sorted by the prefix.
+func groupLibraryTitles(entries []data.LibraryFileEntry) []*libraryTitle {
+	titles := make(map[string]*libraryTitle)
+	get := func(idPrefix string) *libraryTitle {
+		title, ok := titles[idPrefix]
+		if !ok {
+			title = &libraryTitle{
+				IDPrefix: idPrefix,
+				DLCs:     make(map[string][]data.SwitchFileDLC),
+			}
+			titles[idPrefix] = title
+		}
+		return title
+	}
+
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil {
+			continue
+		}
+		for _, game := range entry.BaseGames {
+			title := get(game.IDPrefix)
+			title.BaseGames = append(title.BaseGames, game)
+		}
+		for _, update := range entry.Updates {
+			title := get(update.ForIDPrefix)
+			title.Updates = append(title.Updates, update)
+		}
+		for _, dlc := range entry.DLCs {
+			title := get(dlc.ForIDPrefix)
+			title.DLCs[dlc.ID] = append(title.DLCs[dlc.ID], dlc)
+		}
+	}
+
+	result := make([]*libraryTitle, 0, len(titles))
+	for _, title := range titles {
+		result = append(result, title)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].IDPrefix < result[j].IDPrefix
+	})
+	return result
+}
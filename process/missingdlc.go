@@ -0,0 +1,75 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"strings"
+)
+
+type MissingDLCTitle struct {
+	TitleID string
+	Name    string
+	// OwnedDLC is number of the title DLCs from the catalog that are present in the library
+	OwnedDLC   int
+	MissingDLC []storage.CatalogEntryDLC
+}
+
+// ScanForMissingDLC returns base games from the library with DLCs from the catalog that are not present in the library.
+// DLCs with IDs present in ignoreIDs are skipped.
+func ScanForMissingDLC(entries []data.LibraryFileEntry, catalog storage.SwitchDatabaseCatalog, ignoreIDs []string) ([]MissingDLCTitle, error) {
+	ignored := newIgnoredIDs(ignoreIDs)
+
+	var result []MissingDLCTitle
+	for _, title := range groupLibraryTitles(entries) {
+		if len(title.BaseGames) == 0 {
+			continue
+		}
+		catalogEntry, err := catalog.GetCatalogEntryByIDPrefix(title.IDPrefix)
+		if err != nil {
+			continue
+		}
+
+		owned, missing := title.compareDLCs(catalogEntry, ignored)
+		if len(missing) > 0 {
+			result = append(result, MissingDLCTitle{
+				TitleID:    catalogEntry.ID,
+				Name:       catalogEntry.Name,
+				OwnedDLC:   owned,
+				MissingDLC: missing,
+			})
+		}
+	}
+	return result, nil
+}
+
+type ignoredIDs map[string]struct{}
+
+func newIgnoredIDs(ids []string) ignoredIDs {
+	ignored := make(ignoredIDs, len(ids))
+	for _, id := range ids {
+		ignored[strings.ToUpper(id)] = struct{}{}
+	}
+	return ignored
+}
+
+func (i ignoredIDs) contains(id string) bool {
+	_, ok := i[strings.ToUpper(id)]
+	return ok
+}
+
+// compareDLCs returns number of catalog DLCs present in the library and the ones that are missing.
+func (t *libraryTitle) compareDLCs(catalogEntry storage.CatalogEntry, ignored ignoredIDs) (int, []storage.CatalogEntryDLC) {
+	owned := 0
+	var missing []storage.CatalogEntryDLC
+	for _, dlc := range catalogEntry.DLCs {
+		if ignored.contains(dlc.ID) {
+			continue
+		}
+		if _, ok := t.DLCs[strings.ToUpper(dlc.ID)]; ok {
+			owned += 1
+			continue
+		}
+		missing = append(missing, dlc)
+	}
+	return owned, missing
+}
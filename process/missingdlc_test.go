@@ -0,0 +1,102 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newCatalogDLC(id string, name string) storage.CatalogEntryDLC {
+	return storage.CatalogEntryDLC{CatalogEntryData: storage.CatalogEntryData{ID: id, Name: name}}
+}
+
+func newDLCTestData() (*fakeCatalog, []data.LibraryFileEntry) {
+	catalog := &fakeCatalog{entries: map[string]storage.CatalogEntry{
+		"010000000001": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Game"},
+			Versions:         []storage.CatalogEntryVersion{{Version: 65536}},
+			DLCs: []storage.CatalogEntryDLC{
+				newCatalogDLC("0100000000011001", "Owned DLC"),
+				newCatalogDLC("0100000000011002", "Missing DLC"),
+				newCatalogDLC("0100000000011003", "Ignored DLC"),
+			},
+		},
+		"010000000002": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000020000", Name: "Complete"},
+			DLCs:             []storage.CatalogEntryDLC{newCatalogDLC("0100000000021001", "DLC")},
+		},
+		"010000000003": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000030000", Name: "Not owned"},
+			DLCs:             []storage.CatalogEntryDLC{newCatalogDLC("0100000000031001", "DLC")},
+		},
+	}}
+
+	game := newGameFile("a.nsp", "010000000001", 65536)
+	game.DLCs = []data.SwitchFileDLC{{ForIDPrefix: "010000000001", ID: "0100000000011001"}}
+	complete := newGameFile("b.nsp", "010000000002", 0)
+	complete.DLCs = []data.SwitchFileDLC{{ForIDPrefix: "010000000002", ID: "0100000000021001"}}
+	dlcOnly := data.LibraryFileEntry{
+		FilePath: "c.nsp",
+		LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+			DLCs: []data.SwitchFileDLC{{ForIDPrefix: "010000000003", ID: "0100000000031001"}},
+		},
+	}
+	return catalog, []data.LibraryFileEntry{game, complete, dlcOnly}
+}
+
+func TestScanForMissingDLC(t *testing.T) {
+	catalog, entries := newDLCTestData()
+
+	missing, err := ScanForMissingDLC(entries, catalog, []string{"0100000000011003"})
+	assert.NoError(t, err)
+	assert.Equal(t, []MissingDLCTitle{
+		{
+			TitleID:    "0100000000010000",
+			Name:       "Game",
+			OwnedDLC:   1,
+			MissingDLC: []storage.CatalogEntryDLC{newCatalogDLC("0100000000011002", "Missing DLC")},
+		},
+	}, missing)
+}
+
+func TestCalculateLibraryCompletion(t *testing.T) {
+	catalog, entries := newDLCTestData()
+	// base game dump that already ships with the latest version is up to date
+	complete := catalog.entries["010000000002"]
+	complete.Versions = []storage.CatalogEntryVersion{{Version: 65536}}
+	catalog.entries["010000000002"] = complete
+	entries[1].BaseGames[0].Version = 65536
+
+	completion, err := CalculateLibraryCompletion(entries, catalog, []string{"0100000000011003"})
+	assert.NoError(t, err)
+	assert.Equal(t, LibraryCompletion{
+		Titles:  CompletionStat{Owned: 2, Total: 3},
+		DLC:     CompletionStat{Owned: 2, Total: 3},
+		Updates: CompletionStat{Owned: 2, Total: 2},
+	}, completion)
+	assert.InDelta(t, 66.67, completion.Titles.Percentage(), 0.01)
+	assert.Equal(t, 0.0, CompletionStat{}.Percentage())
+}
+
+func TestScanLibraryOwnership(t *testing.T) {
+	catalog, entries := newDLCTestData()
+
+	ownership, err := ScanLibraryOwnership(entries, catalog, []string{"0100000000011003"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"010000000001": {}, "010000000002": {}}, ownership.Owned)
+	assert.Equal(t, map[string]struct{}{"010000000001": {}}, ownership.Incomplete)
+
+	ownership, err = ScanLibraryOwnership(entries, catalog, []string{"0100000000011002", "0100000000011003"})
+	assert.NoError(t, err)
+	assert.Empty(t, ownership.Incomplete)
+
+	// base game at the latest version does not need an update
+	complete := catalog.entries["010000000002"]
+	complete.Versions = []storage.CatalogEntryVersion{{Version: 65536}}
+	catalog.entries["010000000002"] = complete
+	entries[1].BaseGames[0].Version = 65536
+	ownership, err = ScanLibraryOwnership(entries, catalog, []string{"0100000000011002", "0100000000011003"})
+	assert.NoError(t, err)
+	assert.Empty(t, ownership.Incomplete)
+}
@@ -0,0 +1,118 @@
+package process
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type MissingUpdate struct {
+	TitleID              string `json:"titleID"`
+	UpdateID             string `json:"updateID"`
+	Name                 string `json:"name"`
+	LocalVersion         int    `json:"localVersion"`
+	LocalReadableVersion string `json:"localReadableVersion"`
+	LatestVersion        int    `json:"latestVersion"`
+	// LatestReleaseDate is release date of the latest version in ISO format, empty if unknown
+	LatestReleaseDate string `json:"latestReleaseDate"`
+}
+
+// ScanForMissingUpdates returns base games from the library that have a newer update available in the catalog.
+// Latest version is the highest of catalog versions list and the most recent update entry.
+func ScanForMissingUpdates(entries []data.LibraryFileEntry, catalog storage.SwitchDatabaseCatalog) ([]MissingUpdate, error) {
+	var result []MissingUpdate
+	for _, title := range groupLibraryTitles(entries) {
+		if len(title.BaseGames) == 0 {
+			continue
+		}
+		catalogEntry, err := catalog.GetCatalogEntryByIDPrefix(title.IDPrefix)
+		if err != nil {
+			continue
+		}
+
+		latestVersion, latestReleaseDate := latestCatalogVersion(catalogEntry)
+
+		localVersion, localReadableVersion := title.localVersion()
+
+		if latestVersion <= localVersion {
+			continue
+		}
+
+		// update ID is the base ID with 800 in place of the last three digits
+		updateID := catalogEntry.RecentUpdate.ID
+		if updateID == "" && len(catalogEntry.ID) == 16 {
+			updateID = catalogEntry.ID[:13] + "800"
+		}
+		result = append(result, MissingUpdate{
+			TitleID:              catalogEntry.ID,
+			UpdateID:             strings.ToUpper(updateID),
+			Name:                 catalogEntry.Name,
+			LocalVersion:         localVersion,
+			LocalReadableVersion: localReadableVersion,
+			LatestVersion:        latestVersion,
+			LatestReleaseDate:    latestReleaseDate,
+		})
+	}
+	return result, nil
+}
+
+// latestCatalogVersion returns the latest known update version of a title and its release date.
+func latestCatalogVersion(entry storage.CatalogEntry) (int, string) {
+	latestVersion := 0
+	releaseDate := ""
+	for _, version := range entry.Versions {
+		if version.Version > latestVersion {
+			latestVersion = version.Version
+			releaseDate = version.ReleaseDate
+		}
+	}
+	if entry.RecentUpdate.Version > latestVersion {
+		latestVersion = entry.RecentUpdate.Version
+		releaseDate = ""
+	}
+	return latestVersion, releaseDate
+}
+
+// WriteMissingUpdatesCSV writes missing updates report as CSV with a header row.
+func WriteMissingUpdatesCSV(writer io.Writer, updates []MissingUpdate) error {
+	w := csv.NewWriter(writer)
+	err := w.Write([]string{"Title ID", "Update ID", "Name", "Local version", "Local readable version", "Latest version", "Latest release date"})
+	if err != nil {
+		return err
+	}
+	for _, u := range updates {
+		err = w.Write([]string{
+			u.TitleID,
+			u.UpdateID,
+			u.Name,
+			strconv.Itoa(u.LocalVersion),
+			u.LocalReadableVersion,
+			strconv.Itoa(u.LatestVersion),
+			u.LatestReleaseDate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// WriteMissingUpdatesJSON writes missing updates report as JSON array.
+func WriteMissingUpdatesJSON(writer io.Writer, updates []MissingUpdate) error {
+	if updates == nil {
+		updates = []MissingUpdate{}
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(updates)
+	if err != nil {
+		return fmt.Errorf("could not encode missing updates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type fakeCatalog struct {
+	storage.SwitchDatabaseCatalog
+	entries map[string]storage.CatalogEntry
+}
+
+func (c *fakeCatalog) GetCatalogEntryByIDPrefix(idPrefix string) (storage.CatalogEntry, error) {
+	entry, ok := c.entries[idPrefix]
+	if !ok {
+		return storage.CatalogEntry{}, errors.New("not found")
+	}
+	return entry, nil
+}
+
+func (c *fakeCatalog) GetCatalogEntries(_ *storage.CatalogFilters, _ int, _ string) (storage.Page[storage.CatalogEntry], error) {
+	return storage.Page[storage.CatalogEntry]{TotalCount: len(c.entries)}, nil
+}
+
+func newGameFile(path string, idPrefix string, updateVersion int) data.LibraryFileEntry {
+	metadata := &data.LibraryGameFileMetadata{
+		BaseGames: []data.SwitchFileGame{{IDPrefix: idPrefix, ID: idPrefix + "0000"}},
+	}
+	if updateVersion > 0 {
+		metadata.Updates = []data.SwitchFileUpdate{{ForIDPrefix: idPrefix, ID: idPrefix + "0800", Version: updateVersion, ReadableVersion: "1.0.1"}}
+	}
+	return data.LibraryFileEntry{FilePath: path, LibraryGameFileMetadata: metadata}
+}
+
+func TestScanForMissingUpdates(t *testing.T) {
+	catalog := &fakeCatalog{entries: map[string]storage.CatalogEntry{
+		"010000000001": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Outdated"},
+			Versions: []storage.CatalogEntryVersion{
+				{Version: 65536, ReleaseDate: "2020-01-01"},
+				{Version: 131072, ReleaseDate: "2020-06-01"},
+			},
+		},
+		"010000000002": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000020000", Name: "Up to date"},
+			Versions:         []storage.CatalogEntryVersion{{Version: 65536, ReleaseDate: "2020-01-01"}},
+		},
+		"010000000003": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000030000", Name: "Recent update only"},
+			RecentUpdate:     storage.CatalogEntryRecentUpdate{ID: "0100000000030800", Version: 196608},
+			Versions:         []storage.CatalogEntryVersion{{Version: 65536, ReleaseDate: "2020-01-01"}},
+		},
+		"01007EF00011": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "01007EF00011E000", Name: "Non-zero base digit"},
+			Versions:         []storage.CatalogEntryVersion{{Version: 65536, ReleaseDate: "2021-01-01"}},
+		},
+	}}
+	nonZeroDigit := data.LibraryFileEntry{FilePath: "e.nsp", LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+		BaseGames: []data.SwitchFileGame{{IDPrefix: "01007EF00011", ID: "01007EF00011E000"}},
+	}}
+	entries := []data.LibraryFileEntry{
+		newGameFile("a.nsp", "010000000001", 65536),
+		newGameFile("b.nsp", "010000000002", 65536),
+		newGameFile("c.nsp", "010000000003", 0),
+		newGameFile("d.nsp", "010000000004", 0),
+		nonZeroDigit,
+	}
+
+	missing, err := ScanForMissingUpdates(entries, catalog)
+	assert.NoError(t, err)
+	assert.Equal(t, []MissingUpdate{
+		{
+			TitleID:              "0100000000010000",
+			UpdateID:             "0100000000010800",
+			Name:                 "Outdated",
+			LocalVersion:         65536,
+			LocalReadableVersion: "1.0.1",
+			LatestVersion:        131072,
+			LatestReleaseDate:    "2020-06-01",
+		},
+		{
+			TitleID:       "0100000000030000",
+			UpdateID:      "0100000000030800",
+			Name:          "Recent update only",
+			LatestVersion: 196608,
+		},
+		{
+			TitleID:           "01007EF00011E000",
+			UpdateID:          "01007EF00011E800",
+			Name:              "Non-zero base digit",
+			LatestVersion:     65536,
+			LatestReleaseDate: "2021-01-01",
+		},
+	}, missing)
+}
+
+func TestMissingUpdatesExport(t *testing.T) {
+	updates := []MissingUpdate{{TitleID: "0100000000010000", UpdateID: "0100000000010800", Name: "Game, with comma", LatestVersion: 65536}}
+
+	var csvOut bytes.Buffer
+	assert.NoError(t, WriteMissingUpdatesCSV(&csvOut, updates))
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `0100000000010000,0100000000010800,"Game, with comma",0,,65536,`, lines[1])
+
+	var jsonOut bytes.Buffer
+	assert.NoError(t, WriteMissingUpdatesJSON(&jsonOut, updates))
+	var decoded []MissingUpdate
+	assert.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, updates, decoded)
+
+	jsonOut.Reset()
+	assert.NoError(t, WriteMissingUpdatesJSON(&jsonOut, nil))
+	assert.Equal(t, "[]\n", jsonOut.String())
+}
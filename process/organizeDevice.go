@@ -0,0 +1,26 @@
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"syscall"
+)
+
+// isSameDevice checks if both paths are located on the same device, so one can be renamed into another.
+func isSameDevice(a, b string) (bool, error) {
+	statA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	statB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	sysA, okA := statA.Sys().(*syscall.Stat_t)
+	sysB, okB := statB.Sys().(*syscall.Stat_t)
+	if !okA || !okB {
+		return true, nil
+	}
+	return sysA.Dev == sysB.Dev, nil
+}
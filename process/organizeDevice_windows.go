@@ -0,0 +1,19 @@
+package process
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// isSameDevice checks if both paths are located on the same volume, so one can be renamed into another.
+func isSameDevice(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(filepath.VolumeName(absA), filepath.VolumeName(absB)), nil
+}
@@ -0,0 +1,482 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"golang.org/x/exp/slices"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrOrganizeIssue     = errors.New("operation has unresolved issues")
+	ErrNoOrganizeJournal = errors.New("no organize journal to undo")
+)
+
+var (
+	reservedFileNameRegex = regexp.MustCompile(`(?i)^(CON|PRN|AUX|NUL|COM[1-9]|LPT[1-9])$`)
+)
+
+const (
+	// OrganizeJournalFileName is name of the journal file kept in the working directory
+	OrganizeJournalFileName = "organize_journal.json"
+	maxFileNameLength       = 255
+)
+
+type OrganizeOperationType string
+
+const (
+	OrganizeOperationCreateFolder OrganizeOperationType = "createFolder"
+	OrganizeOperationMove         OrganizeOperationType = "move"
+	OrganizeOperationDeleteFolder OrganizeOperationType = "deleteFolder"
+)
+
+type OrganizeIssue string
+
+const (
+	// OrganizeIssueCollision means that destination already exists or is a destination of another move
+	OrganizeIssueCollision OrganizeIssue = "collision"
+	// OrganizeIssueCrossDevice means that destination is on another device, so the file cannot be just renamed
+	OrganizeIssueCrossDevice OrganizeIssue = "crossDevice"
+	// OrganizeIssueIllegalName means that destination name is not valid on the Switch or common file systems
+	OrganizeIssueIllegalName OrganizeIssue = "illegalName"
+)
+
+type OrganizeOperation struct {
+	Type OrganizeOperationType `json:"type"`
+	From string                `json:"from,omitempty"`
+	To   string                `json:"to,omitempty"`
+	// EntryPath is path of the library file affected by a move, differs from From for split files moved with their folder
+	EntryPath string          `json:"entryPath,omitempty"`
+	Issues    []OrganizeIssue `json:"issues,omitempty"`
+}
+
+// OrganizePlan holds operations in order of execution: folders get created, files moved and empty folders deleted.
+// Operations with issues are not executed.
+type OrganizePlan struct {
+	Operations []OrganizeOperation
+}
+
+func (p OrganizePlan) HasIssues() bool {
+	for _, operation := range p.Operations {
+		if len(operation.Issues) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type OrganizeResult struct {
+	// Moves maps old paths of moved library files to the new ones
+	Moves map[string]string
+	// Errors holds errors of operations that could not be executed by library file path or folder path
+	Errors map[string]error
+}
+
+type OrganizeJournal struct {
+	CreatedAt  time.Time           `json:"createdAt"`
+	Operations []OrganizeOperation `json:"operations"`
+}
+
+// PlanOrganizeLibrary prepares operations needed to move and rename library files of every title with a base game
+// according to options. Nothing is changed on disk.
+// With CreateFolderPerGame all the title files are moved to a folder inside the scan directory of the base game.
+// Split files are moved together with their folder.
+func PlanOrganizeLibrary(entries []data.LibraryFileEntry, catalog storage.SwitchDatabaseCatalog, scanDirectories []string, options OrganizeOptions) (OrganizePlan, error) {
+	err := options.Validate()
+	if err != nil {
+		return OrganizePlan{}, err
+	}
+
+	var moves []OrganizeOperation
+	for _, title := range groupOrganizeTitles(entries) {
+		if len(title.base) == 0 {
+			continue
+		}
+		var catalogEntry *storage.CatalogEntry
+		if entry, err := catalog.GetCatalogEntryByIDPrefix(title.idPrefix); err == nil {
+			catalogEntry = &entry
+		}
+		titleName := organizeTitleName(catalogEntry, title.base[0])
+
+		files := append(append(append([]organizeFile{}, title.base...), title.updates...), title.dlcs...)
+		for _, file := range files {
+			file.templateData[TemplateTokenTitleName] = titleName
+			if catalogEntry != nil {
+				file.templateData[TemplateTokenRegion] = catalogEntry.Region
+				if file.templateData[TemplateTokenType] == "DLC" {
+					file.templateData[TemplateTokenDLCName] = organizeDLCName(*catalogEntry, file.templateData[TemplateTokenTitleID])
+				}
+			}
+		}
+
+		destination := filepath.Dir(organizeSourcePath(title.base[0].entry))
+		if options.CreateFolderPerGame {
+			root := organizeRootDirectory(scanDirectories, destination)
+			destination = filepath.Join(root, applyTemplate(title.base[0].templateData, options.SwitchSafeFileNames, options.FolderNameTemplate))
+		}
+
+		for _, file := range files {
+			from := organizeSourcePath(file.entry)
+			fileDestination := destination
+			if !options.CreateFolderPerGame {
+				fileDestination = filepath.Dir(from)
+			}
+			to := filepath.Join(fileDestination, organizeFileName(options, filepath.Base(from), file.templateData))
+			if from == to {
+				continue
+			}
+			moves = append(moves, OrganizeOperation{
+				Type:      OrganizeOperationMove,
+				From:      from,
+				To:        to,
+				EntryPath: file.entry.FilePath,
+			})
+		}
+	}
+
+	checkOrganizeMoves(moves)
+
+	var plan OrganizePlan
+	for _, folder := range planCreatedFolders(moves) {
+		operation := OrganizeOperation{Type: OrganizeOperationCreateFolder, To: folder}
+		if !isLegalFileName(filepath.Base(folder)) {
+			operation.Issues = append(operation.Issues, OrganizeIssueIllegalName)
+		}
+		plan.Operations = append(plan.Operations, operation)
+	}
+	plan.Operations = append(plan.Operations, moves...)
+	if options.DeleteEmptyFolders {
+		for _, folder := range planDeletedFolders(scanDirectories, moves) {
+			plan.Operations = append(plan.Operations, OrganizeOperation{Type: OrganizeOperationDeleteFolder, From: folder})
+		}
+	}
+	return plan, nil
+}
+
+// checkOrganizeMoves flags moves with colliding destinations, cross-device moves and illegal names.
+func checkOrganizeMoves(moves []OrganizeOperation) {
+	// destinations are compared case-insensitive as the Switch SD card file system is
+	destinations := make(map[string]int, len(moves))
+	for _, move := range moves {
+		destinations[strings.ToLower(move.To)] += 1
+	}
+
+	for i := range moves {
+		move := &moves[i]
+		if !isLegalFileName(filepath.Base(move.To)) {
+			move.Issues = append(move.Issues, OrganizeIssueIllegalName)
+		}
+		if destinations[strings.ToLower(move.To)] > 1 || isExistingDestination(move.From, move.To) {
+			move.Issues = append(move.Issues, OrganizeIssueCollision)
+		}
+		sameDevice, err := isSameDevice(move.From, nearestExistingPath(filepath.Dir(move.To)))
+		if err == nil && !sameDevice {
+			move.Issues = append(move.Issues, OrganizeIssueCrossDevice)
+		}
+	}
+}
+
+// isExistingDestination checks if destination exists and is not just the source with name in different case.
+func isExistingDestination(from, to string) bool {
+	toStat, err := os.Lstat(to)
+	if err != nil {
+		return false
+	}
+	fromStat, err := os.Lstat(from)
+	return err != nil || !os.SameFile(fromStat, toStat)
+}
+
+func isLegalFileName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > maxFileNameLength {
+		return false
+	}
+	if folderIllegalCharsRegex.MatchString(name) || strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		return false
+	}
+	base := strings.SplitN(name, ".", 2)[0]
+	return !reservedFileNameRegex.MatchString(strings.TrimSpace(base))
+}
+
+func nearestExistingPath(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// planCreatedFolders returns missing destination folders of moves without issues, parents go first.
+func planCreatedFolders(moves []OrganizeOperation) []string {
+	folders := make(map[string]struct{})
+	for _, move := range moves {
+		if len(move.Issues) > 0 {
+			continue
+		}
+		for folder := filepath.Dir(move.To); ; folder = filepath.Dir(folder) {
+			if _, ok := folders[folder]; ok {
+				break
+			}
+			if _, err := os.Stat(folder); err == nil {
+				break
+			}
+			folders[folder] = struct{}{}
+		}
+	}
+
+	result := make([]string, 0, len(folders))
+	for folder := range folders {
+		result = append(result, folder)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i]) != len(result[j]) {
+			return len(result[i]) < len(result[j])
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// planDeletedFolders returns folders inside scan directories that are going to be empty once moves without issues
+// are executed, nested folders go first. Scan directories themselves are never deleted.
+func planDeletedFolders(scanDirectories []string, moves []OrganizeOperation) []string {
+	movedFrom := make(map[string]struct{})
+	kept := make(map[string]struct{})
+	for _, move := range moves {
+		if len(move.Issues) > 0 {
+			continue
+		}
+		movedFrom[move.From] = struct{}{}
+		for folder := filepath.Dir(move.To); ; folder = filepath.Dir(folder) {
+			if _, ok := kept[folder]; ok || filepath.Dir(folder) == folder {
+				break
+			}
+			kept[folder] = struct{}{}
+		}
+	}
+
+	var result []string
+	for _, directory := range scanDirectories {
+		directory = filepath.Clean(directory)
+		children := make(map[string]int)
+		var folders []string
+		_ = filepath.WalkDir(directory, func(path string, info os.DirEntry, err error) error {
+			if err != nil || path == directory {
+				return nil
+			}
+			if _, ok := movedFrom[path]; ok {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			children[filepath.Dir(path)] += 1
+			if info.IsDir() {
+				folders = append(folders, path)
+			}
+			return nil
+		})
+
+		sort.Slice(folders, func(i, j int) bool {
+			return len(folders[i]) > len(folders[j])
+		})
+		for _, folder := range folders {
+			if _, ok := kept[folder]; ok || children[folder] > 0 {
+				continue
+			}
+			result = append(result, folder)
+			children[filepath.Dir(folder)] -= 1
+		}
+	}
+	return result
+}
+
+// ExecuteOrganizePlan executes operations of the plan that have no issues and writes executed ones into
+// the journal, so they can be reverted with UndoOrganize. The journal is updated after every operation, so
+// an interrupted run can be reverted too. Operations that could not be executed are reported in the result by
+// library file path or folder path.
+func ExecuteOrganizePlan(plan OrganizePlan, journalPath string, progress data.ProgressCallback) (OrganizeResult, error) {
+	if progress == nil {
+		progress = func(current, total int, message string) {}
+	}
+
+	result := OrganizeResult{
+		Moves:  make(map[string]string),
+		Errors: make(map[string]error),
+	}
+	journal := OrganizeJournal{CreatedAt: time.Now()}
+
+	total := len(plan.Operations)
+	for i, operation := range plan.Operations {
+		progress(i, total, organizeOperationMessage(operation))
+		key := organizeOperationPath(operation)
+		if len(operation.Issues) > 0 {
+			result.Errors[key] = fmt.Errorf("%w: %v", ErrOrganizeIssue, operation.Issues)
+			continue
+		}
+
+		var err error
+		switch operation.Type {
+		case OrganizeOperationCreateFolder:
+			err = os.Mkdir(operation.To, os.ModePerm)
+		case OrganizeOperationMove:
+			err = moveFile(operation.From, operation.To)
+			if err == nil {
+				result.Moves[operation.EntryPath] = movedEntryPath(operation.EntryPath, operation.From, operation.To)
+			}
+		case OrganizeOperationDeleteFolder:
+			err = os.Remove(operation.From)
+		}
+		if err != nil {
+			result.Errors[key] = err
+			continue
+		}
+		journal.Operations = append(journal.Operations, operation)
+		// stop rather than make changes that could not be reverted
+		err = writeOrganizeJournal(journalPath, journal)
+		if err != nil {
+			return result, err
+		}
+	}
+	progress(total, total, "done")
+	return result, nil
+}
+
+// UndoOrganize reverts operations from the journal in reverse order. The journal is removed once all the operations
+// are reverted, operations that failed are kept in it, so they can be retried.
+func UndoOrganize(journalPath string, progress data.ProgressCallback) (OrganizeResult, error) {
+	if progress == nil {
+		progress = func(current, total int, message string) {}
+	}
+
+	journal, err := readOrganizeJournal(journalPath)
+	if err != nil {
+		return OrganizeResult{}, err
+	}
+
+	result := OrganizeResult{
+		Moves:  make(map[string]string),
+		Errors: make(map[string]error),
+	}
+	var failed []OrganizeOperation
+	total := len(journal.Operations)
+	for i := total - 1; i >= 0; i-- {
+		operation := journal.Operations[i]
+		progress(total-1-i, total, "reverting: "+organizeOperationMessage(operation))
+
+		switch operation.Type {
+		case OrganizeOperationCreateFolder:
+			err = os.Remove(operation.To)
+		case OrganizeOperationMove:
+			err = moveFile(operation.To, operation.From)
+			if err == nil {
+				result.Moves[movedEntryPath(operation.EntryPath, operation.From, operation.To)] = operation.EntryPath
+			}
+		case OrganizeOperationDeleteFolder:
+			err = os.Mkdir(operation.From, os.ModePerm)
+		}
+		if err != nil {
+			result.Errors[organizeOperationPath(operation)] = err
+			failed = append(failed, operation)
+		}
+	}
+
+	if len(failed) > 0 {
+		// failed operations were collected in reverse order
+		slices.Reverse(failed)
+		journal.Operations = failed
+		err = writeOrganizeJournal(journalPath, journal)
+		if err != nil {
+			return result, err
+		}
+		progress(total, total, "done")
+		return result, nil
+	}
+
+	err = os.Remove(journalPath)
+	if err != nil {
+		return result, fmt.Errorf("could not remove organize journal: %w", err)
+	}
+	progress(total, total, "done")
+	return result, nil
+}
+
+// movedEntryPath returns new path of the library file after from was moved to to.
+func movedEntryPath(entryPath, from, to string) string {
+	if entryPath == from {
+		return to
+	}
+	relativePath, err := filepath.Rel(from, entryPath)
+	if err != nil {
+		return entryPath
+	}
+	return filepath.Join(to, relativePath)
+}
+
+// organizeOperationPath returns path identifying the operation, library file path for moves or the folder path.
+func organizeOperationPath(operation OrganizeOperation) string {
+	switch operation.Type {
+	case OrganizeOperationCreateFolder:
+		return operation.To
+	case OrganizeOperationMove:
+		return operation.EntryPath
+	}
+	return operation.From
+}
+
+func organizeOperationMessage(operation OrganizeOperation) string {
+	switch operation.Type {
+	case OrganizeOperationCreateFolder:
+		return "creating folder " + operation.To
+	case OrganizeOperationMove:
+		return "moving " + filepath.Base(operation.From)
+	case OrganizeOperationDeleteFolder:
+		return "deleting folder " + operation.From
+	}
+	return string(operation.Type)
+}
+
+func writeOrganizeJournal(journalPath string, journal OrganizeJournal) error {
+	content, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode organize journal: %w", err)
+	}
+	// replace the journal at once, so that an interrupted write never leaves it broken
+	temporaryPath := journalPath + ".tmp"
+	err = os.WriteFile(temporaryPath, content, 0644)
+	if err == nil {
+		err = os.Rename(temporaryPath, journalPath)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write organize journal: %w", err)
+	}
+	return nil
+}
+
+func readOrganizeJournal(journalPath string) (OrganizeJournal, error) {
+	content, err := os.ReadFile(journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return OrganizeJournal{}, ErrNoOrganizeJournal
+		}
+		return OrganizeJournal{}, fmt.Errorf("could not read organize journal: %w", err)
+	}
+	var journal OrganizeJournal
+	err = json.Unmarshal(content, &journal)
+	if err != nil {
+		return OrganizeJournal{}, fmt.Errorf("could not decode organize journal: %w", err)
+	}
+	return journal, nil
+}
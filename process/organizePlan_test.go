@@ -0,0 +1,167 @@
+package process
+
+import (
+	"errors"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOrganizeLibrary(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0644))
+		return path
+	}
+	basePath := writeFile(filepath.Join("old", "base.nsp"))
+	updatePath := writeFile("update.nsp")
+	dlcPath := writeFile("dlc.nsp")
+	splitPath := writeFile(filepath.Join("split.xci", "00"))
+	writeFile(filepath.Join("split.xci", "01"))
+	conflictPath := writeFile("conflict.nsp")
+	writeFile(filepath.Join("Other", "Other [0100000000020000][v0].nsp"))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0755))
+
+	game := newGameFile(basePath, "010000000001", 0)
+	update := data.LibraryFileEntry{FilePath: updatePath, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+		Updates: []data.SwitchFileUpdate{{ForIDPrefix: "010000000001", ID: "0100000000010800", Version: 65536}},
+	}}
+	dlc := data.LibraryFileEntry{FilePath: dlcPath, LibraryGameFileMetadata: &data.LibraryGameFileMetadata{
+		DLCs: []data.SwitchFileDLC{{ForIDPrefix: "010000000001", ID: "0100000000011001"}},
+	}}
+	split := newGameFile(splitPath, "010000000003", 0)
+	split.IsSplit = true
+	split.BaseGames[0].Name = map[string]string{"AmericanEnglish": "Split Game"}
+	conflict := newGameFile(conflictPath, "010000000002", 0)
+
+	catalog := &fakeCatalog{entries: map[string]storage.CatalogEntry{
+		"010000000001": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000010000", Name: "Game"},
+			DLCs:             []storage.CatalogEntryDLC{newCatalogDLC("0100000000011001", "Game - Extra\nLevels")},
+		},
+		"010000000002": {
+			CatalogEntryData: storage.CatalogEntryData{ID: "0100000000020000", Name: "Other"},
+		},
+	}}
+
+	options := OrganizeOptions{
+		CreateFolderPerGame: true,
+		RenameFiles:         true,
+		DeleteEmptyFolders:  true,
+		FolderNameTemplate:  "{TITLE_NAME}",
+		FileNameTemplate:    "{TITLE_NAME} ({DLC_NAME})[{TITLE_ID}][v{VERSION}]",
+	}
+	plan, err := PlanOrganizeLibrary([]data.LibraryFileEntry{game, update, dlc, split, conflict}, catalog, []string{dir}, options)
+	assert.NoError(t, err)
+
+	splitTo := filepath.Join(dir, "Split Game", "Split Game [0100000000030000][v0].xci")
+	assert.Equal(t, []OrganizeOperation{
+		{Type: OrganizeOperationCreateFolder, To: filepath.Join(dir, "Game")},
+		{Type: OrganizeOperationCreateFolder, To: filepath.Join(dir, "Split Game")},
+		{Type: OrganizeOperationMove, From: basePath, To: filepath.Join(dir, "Game", "Game [0100000000010000][v0].nsp"), EntryPath: basePath},
+		{Type: OrganizeOperationMove, From: updatePath, To: filepath.Join(dir, "Game", "Game [0100000000010800][v65536].nsp"), EntryPath: updatePath},
+		{Type: OrganizeOperationMove, From: dlcPath, To: filepath.Join(dir, "Game", "Game (Extra Levels)[0100000000011001][v0].nsp"), EntryPath: dlcPath},
+		{Type: OrganizeOperationMove, From: conflictPath, To: filepath.Join(dir, "Other", "Other [0100000000020000][v0].nsp"), EntryPath: conflictPath, Issues: []OrganizeIssue{OrganizeIssueCollision}},
+		{Type: OrganizeOperationMove, From: filepath.Dir(splitPath), To: splitTo, EntryPath: splitPath},
+		{Type: OrganizeOperationDeleteFolder, From: filepath.Join(dir, "empty")},
+		{Type: OrganizeOperationDeleteFolder, From: filepath.Join(dir, "old")},
+	}, plan.Operations)
+	assert.True(t, plan.HasIssues())
+	assert.FileExists(t, basePath, "planning must not change anything")
+
+	journalPath := filepath.Join(t.TempDir(), "journal.json")
+	result, err := ExecuteOrganizePlan(plan, journalPath, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		basePath:   filepath.Join(dir, "Game", "Game [0100000000010000][v0].nsp"),
+		updatePath: filepath.Join(dir, "Game", "Game [0100000000010800][v65536].nsp"),
+		dlcPath:    filepath.Join(dir, "Game", "Game (Extra Levels)[0100000000011001][v0].nsp"),
+		splitPath:  filepath.Join(splitTo, "00"),
+	}, result.Moves)
+	assert.Len(t, result.Errors, 1)
+	assert.ErrorIs(t, result.Errors[conflictPath], ErrOrganizeIssue)
+	for _, to := range result.Moves {
+		assert.FileExists(t, to)
+	}
+	assert.FileExists(t, filepath.Join(splitTo, "01"))
+	assert.NoDirExists(t, filepath.Join(dir, "old"))
+	assert.FileExists(t, journalPath)
+
+	result, err = UndoOrganize(journalPath, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, splitPath, result.Moves[filepath.Join(splitTo, "00")])
+	for _, path := range []string{basePath, updatePath, dlcPath, splitPath, conflictPath} {
+		assert.FileExists(t, path)
+	}
+	assert.DirExists(t, filepath.Join(dir, "empty"))
+	assert.NoDirExists(t, filepath.Join(dir, "Game"))
+	assert.NoFileExists(t, journalPath)
+
+	_, err = UndoOrganize(journalPath, nil)
+	assert.ErrorIs(t, err, ErrNoOrganizeJournal)
+
+	_, err = PlanOrganizeLibrary(nil, catalog, []string{dir}, OrganizeOptions{RenameFiles: true, FileNameTemplate: "{VERSION}"})
+	assert.ErrorIs(t, err, ErrInvalidOrganizeOptions)
+}
+
+func TestOrganizeJournalPartialUndo(t *testing.T) {
+	dir := t.TempDir()
+	var plan OrganizePlan
+	for _, name := range []string{"a.nsp", "b.nsp"} {
+		from := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(from, []byte(name), 0644))
+		plan.Operations = append(plan.Operations, OrganizeOperation{
+			Type:      OrganizeOperationMove,
+			From:      from,
+			To:        filepath.Join(dir, "moved", name),
+			EntryPath: from,
+		})
+	}
+
+	// the journal lists operations executed so far before the next one starts
+	journalPath := filepath.Join(t.TempDir(), "journal.json")
+	var journaled []int
+	_, err := ExecuteOrganizePlan(plan, journalPath, func(current, total int, message string) {
+		journal, err := readOrganizeJournal(journalPath)
+		if errors.Is(err, ErrNoOrganizeJournal) {
+			journaled = append(journaled, 0)
+			return
+		}
+		assert.NoError(t, err)
+		journaled = append(journaled, len(journal.Operations))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, journaled)
+
+	// the first move cannot be reverted while its source path is taken
+	assert.NoError(t, os.WriteFile(plan.Operations[0].From, []byte("other"), 0644))
+	result, err := UndoOrganize(journalPath, nil)
+	assert.NoError(t, err)
+	assert.Len(t, result.Errors, 1)
+	assert.ErrorIs(t, result.Errors[plan.Operations[0].EntryPath], ErrDestinationExists)
+	assert.FileExists(t, plan.Operations[1].From)
+	journal, err := readOrganizeJournal(journalPath)
+	assert.NoError(t, err)
+	assert.Equal(t, plan.Operations[:1], journal.Operations)
+
+	assert.NoError(t, os.Remove(plan.Operations[0].From))
+	result, err = UndoOrganize(journalPath, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.FileExists(t, plan.Operations[0].From)
+	assert.NoFileExists(t, journalPath)
+}
+
+func TestIsLegalFileName(t *testing.T) {
+	assert.True(t, isLegalFileName("Game [0100000000010000][v0].nsp"))
+	assert.False(t, isLegalFileName("Game: Part 2.nsp"))
+	assert.False(t, isLegalFileName("Game."))
+	assert.False(t, isLegalFileName("con.nsp"))
+	assert.False(t, isLegalFileName(""))
+}
@@ -1,357 +1,236 @@
 package process
 
-//import (
-//	"github.com/FrozenPear42/switch-library-manager/db"
-//	"github.com/FrozenPear42/switch-library-manager/settings"
-//	"go.uber.org/zap"
-//	"io/ioutil"
-//	"os"
-//	"path"
-//	"path/filepath"
-//	"regexp"
-//	"robpike.io/nihongo"
-//	"strconv"
-//	"strings"
-//)
-//
-//var (
-//	folderIllegalCharsRegex = regexp.MustCompile(`[/\\?%*:;=|"<>]`)
-//	nonAscii                = regexp.MustCompile("[a-zA-Z0-9áéíóú@#%&',.\\s-\\[\\]\\(\\)\\+]")
-//	cjk                     = regexp.MustCompile("[\u2f70-\u2FA1\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uff66-\uff9f\\p{Katakana}\\p{Hiragana}\\p{Hangul}]")
-//)
-//
-//func DeleteOldUpdates(baseFolder string, localDB *db.LocalSwitchFilesDB, updateProgress db.ProgressUpdater) {
-//	i := 0
-//	for k, v := range localDB.Skipped {
-//		switch v.ReasonCode {
-//		//case db.REASON_DUPLICATE:
-//		case db.REASON_OLD_UPDATE:
-//			fileToRemove := filepath.Join(k.BaseFolder, k.FileName)
-//			if updateProgress != nil {
-//				updateProgress.UpdateProgress(0, 0, "deleting "+fileToRemove)
-//			}
-//			zap.S().Infof("Deleting file: %v \n", fileToRemove)
-//			err := os.Remove(fileToRemove)
-//			if err != nil {
-//				zap.S().Errorf("Failed to delete file  %v  [%v]\n", fileToRemove, err)
-//				continue
-//			}
-//			i++
-//		}
-//
-//	}
-//
-//	if i != 0 && settings.ReadSettings(baseFolder).OrganizeOptions.DeleteEmptyFolders {
-//		if updateProgress != nil {
-//			updateProgress.UpdateProgress(i, i+1, "deleting empty folders... (can take 1-2min)")
-//		}
-//		err := deleteEmptyFolders(baseFolder)
-//		if err != nil {
-//			zap.S().Errorf("Failed to delete empty folders [%v]\n", err)
-//		}
-//		if updateProgress != nil {
-//			updateProgress.UpdateProgress(i+1, i+1, "deleting empty folders... (can take 1-2min)")
-//		}
-//	}
-//}
-//
-//func OrganizeByFolders(baseFolder string,
-//	localDB *db.LocalSwitchFilesDB,
-//	titlesDB *db.SwitchTitlesDB,
-//	updateProgress db.ProgressUpdater) {
-//
-//	//validate template rules
-//
-//	options := settings.ReadSettings(baseFolder).OrganizeOptions
-//	if !IsOptionsValid(options) {
-//		zap.S().Error("the organize options in settings.json are not valid, please check that the template contains file/folder name")
-//		return
-//	}
-//	i := 0
-//	tasksSize := len(localDB.TitlesMap) + 2
-//	for k, v := range localDB.TitlesMap {
-//		i++
-//		if !v.BaseExist {
-//			continue
-//		}
-//
-//		if updateProgress != nil {
-//			updateProgress.UpdateProgress(i, tasksSize, v.File.ExtendedInfo.FileName)
-//		}
-//
-//		titleName := getTitleName(titlesDB.TitlesMap[k], v)
-//
-//		templateData := map[string]string{}
-//
-//		templateData[TemplateTokenTitleID] = v.File.Metadata.TitleId
-//		//templateData[settings.TemplateTokenType] = "BASE"
-//		templateData[TemplateTokenTitleName] = titleName
-//		templateData[TemplateTokenVersionTXT] = ""
-//		if _, ok := titlesDB.TitlesMap[k]; ok {
-//			templateData[TemplateTokenRegion] = titlesDB.TitlesMap[k].Attributes.Region
-//		}
-//		templateData[TemplateTokenVersion] = "0"
-//
-//		if v.File.Metadata.Ncap != nil {
-//			templateData[TemplateTokenVersionTXT] = v.File.Metadata.Ncap.DisplayVersion
-//		}
-//
-//		var destinationPath = v.File.ExtendedInfo.BaseFolder
-//
-//		//create folder if needed
-//		if options.CreateFolderPerGame {
-//			folderToCreate := getFolderName(options, templateData)
-//			destinationPath = filepath.Join(baseFolder, folderToCreate)
-//			if _, err := os.Stat(destinationPath); os.IsNotExist(err) {
-//				err = os.Mkdir(destinationPath, os.ModePerm)
-//				if err != nil {
-//					zap.S().Errorf("Failed to create folder %v - %v\n", folderToCreate, err)
-//					continue
-//				}
-//			}
-//		}
-//
-//		if v.IsSplit {
-//			//in case of a split file, we only rename the folder and then move all the split
-//			//files with the new folder
-//			files, err := ioutil.ReadDir(v.File.ExtendedInfo.BaseFolder)
-//			if err != nil {
-//				continue
-//			}
-//
-//			for _, file := range files {
-//				if _, err := strconv.Atoi(file.Name()[len(file.Name())-1:]); err == nil {
-//					from := filepath.Join(v.File.ExtendedInfo.BaseFolder, file.Name())
-//					to := filepath.Join(destinationPath, file.Name())
-//					err := moveFile(from, to)
-//					if err != nil {
-//						zap.S().Errorf("Failed to move file [%v]\n", err)
-//						continue
-//					}
-//				}
-//			}
-//			continue
-//
-//		}
-//
-//		//process base title
-//		from := filepath.Join(v.File.ExtendedInfo.BaseFolder, v.File.ExtendedInfo.FileName)
-//		to := filepath.Join(destinationPath, getFileName(options, v.File.ExtendedInfo.FileName, templateData))
-//		err := moveFile(from, to)
-//		if err != nil {
-//			zap.S().Errorf("Failed to move file [%v]\n", err)
-//			continue
-//		}
-//
-//		//process updates
-//		for update, updateInfo := range v.Updates {
-//			if updateInfo.Metadata != nil {
-//				templateData[TemplateTokenTitleID] = updateInfo.Metadata.TitleId
-//			}
-//			templateData[TemplateTokenVersion] = strconv.Itoa(update)
-//			templateData[TemplateTokenType] = "UPD"
-//			if updateInfo.Metadata.Ncap != nil {
-//				templateData[TemplateTokenVersionTXT] = updateInfo.Metadata.Ncap.DisplayVersion
-//			} else {
-//				templateData[TemplateTokenVersionTXT] = ""
-//			}
-//
-//			from = filepath.Join(updateInfo.ExtendedInfo.BaseFolder, updateInfo.ExtendedInfo.FileName)
-//			if options.CreateFolderPerGame {
-//				to = filepath.Join(destinationPath, getFileName(options, updateInfo.ExtendedInfo.FileName, templateData))
-//			} else {
-//				to = filepath.Join(updateInfo.ExtendedInfo.BaseFolder, getFileName(options, updateInfo.ExtendedInfo.FileName, templateData))
-//			}
-//			err := moveFile(from, to)
-//			if err != nil {
-//				zap.S().Errorf("Failed to move file [%v]\n", err)
-//				continue
-//			}
-//		}
-//
-//		//process DLC
-//		for id, dlc := range v.Dlc {
-//			if dlc.Metadata != nil {
-//				templateData[TemplateTokenVersion] = strconv.Itoa(dlc.Metadata.Version)
-//			}
-//			templateData[TemplateTokenType] = "DLC"
-//			templateData[TemplateTokenTitleID] = id
-//			templateData[TemplateTokenDLCName] = getDlcName(titlesDB.TitlesMap[k], dlc)
-//			from = filepath.Join(dlc.ExtendedInfo.BaseFolder, dlc.ExtendedInfo.FileName)
-//			if options.CreateFolderPerGame {
-//				to = filepath.Join(destinationPath, getFileName(options, dlc.ExtendedInfo.FileName, templateData))
-//			} else {
-//				to = filepath.Join(dlc.ExtendedInfo.BaseFolder, getFileName(options, dlc.ExtendedInfo.FileName, templateData))
-//			}
-//			err = moveFile(from, to)
-//			if err != nil {
-//				zap.S().Errorf("Failed to move file [%v]\n", err)
-//				continue
-//			}
-//		}
-//	}
-//
-//	if options.DeleteEmptyFolders {
-//		if updateProgress != nil {
-//			i += 1
-//			updateProgress.UpdateProgress(i, tasksSize, "deleting empty folders... (can take 1-2min)")
-//		}
-//		err := deleteEmptyFolders(baseFolder)
-//		if err != nil {
-//			zap.S().Errorf("Failed to delete empty folders [%v]\n", err)
-//		}
-//		if updateProgress != nil {
-//			i += 1
-//			updateProgress.UpdateProgress(i, tasksSize, "done")
-//		}
-//	} else {
-//		if updateProgress != nil {
-//			i += 2
-//			updateProgress.UpdateProgress(i, tasksSize, "done")
-//		}
-//	}
-//}
-//
-//func IsOptionsValid(options settings.OrganizeOptions) bool {
-//	if options.RenameFiles {
-//		if options.FileNameTemplate == "" {
-//			zap.S().Error("file name template cannot be empty")
-//			return false
-//		}
-//		if !strings.Contains(options.FileNameTemplate, TemplateTokenTitleName) &&
-//			!strings.Contains(options.FileNameTemplate, TemplateTokenTitleID) {
-//			zap.S().Error("file name template needs to contain one of the following - titleId or title name")
-//			return false
-//		}
-//
-//	}
-//
-//	if options.CreateFolderPerGame {
-//		if options.FolderNameTemplate == "" {
-//			zap.S().Error("folder name template cannot be empty")
-//			return false
-//		}
-//		if !strings.Contains(options.FolderNameTemplate, TemplateTokenTitleName) &&
-//			!strings.Contains(options.FolderNameTemplate, TemplateTokenTitleID) {
-//			zap.S().Error("folder name template needs to contain one of the following - titleId or title name")
-//			return false
-//		}
-//	}
-//	return true
-//}
-//
-//func getDlcName(switchTitle *db.SwitchTitle, file db.SwitchFileInfo) string {
-//	if switchTitle == nil {
-//		return ""
-//	}
-//	if dlcAttributes, ok := switchTitle.Dlc[file.Metadata.TitleId]; ok {
-//		name := dlcAttributes.Name
-//		name = strings.ReplaceAll(name, "\n", " ")
-//		return name
-//	}
-//	return ""
-//}
-//
-//func getTitleName(switchTitle *db.SwitchTitle, v *db.SwitchGameFiles) string {
-//	if switchTitle != nil && switchTitle.Attributes.Name != "" {
-//		res := cjk.FindAllString(switchTitle.Attributes.Name, -1)
-//		if len(res) == 0 {
-//			return switchTitle.Attributes.Name
-//		}
-//	}
-//
-//	if v.File.Metadata.Ncap != nil {
-//		name := v.File.Metadata.Ncap.TitleName["AmericanEnglish"].Title
-//		if name != "" {
-//			return name
-//		}
-//	}
-//	//for non eshop games (cartridge only), grab the name from the file
-//	return db.ParseTitleNameFromFileName(v.File.ExtendedInfo.FileName)
-//
-//}
-//
-//func getFolderName(options settings.OrganizeOptions, templateData map[string]string) string {
-//
-//	return applyTemplate(templateData, options.SwitchSafeFileNames, options.FolderNameTemplate)
-//}
-//
-//func getFileName(options settings.OrganizeOptions, originalName string, templateData map[string]string) string {
-//	if !options.RenameFiles {
-//		return originalName
-//	}
-//	ext := path.Ext(originalName)
-//	result := applyTemplate(templateData, options.SwitchSafeFileNames, options.FileNameTemplate)
-//	return result + ext
-//}
-//
-//func moveFile(from string, to string) error {
-//	if from == to {
-//		return nil
-//	}
-//	err := os.Rename(from, to)
-//	return err
-//}
-//
-//func applyTemplate(templateData map[string]string, useSafeNames bool, template string) string {
-//	result := strings.Replace(template, "{"+TemplateTokenTitleName+"}", templateData[TemplateTokenTitleName], 1)
-//	result = strings.Replace(result, "{"+TemplateTokenTitleID+"}", strings.ToUpper(templateData[TemplateTokenTitleID]), 1)
-//	result = strings.Replace(result, "{"+TemplateTokenVersion+"}", templateData[TemplateTokenVersion], 1)
-//	result = strings.Replace(result, "{"+TemplateTokenType+"}", templateData[TemplateTokenType], 1)
-//	result = strings.Replace(result, "{"+TemplateTokenVersionTXT+"}", templateData[TemplateTokenVersionTXT], 1)
-//	result = strings.Replace(result, "{"+TemplateTokenRegion+"}", templateData[TemplateTokenRegion], 1)
-//	//remove title name from dlc name
-//	dlcName := strings.Replace(templateData[TemplateTokenDLCName], templateData[TemplateTokenTitleName], "", 1)
-//	dlcName = strings.TrimSpace(dlcName)
-//	dlcName = strings.TrimPrefix(dlcName, "-")
-//	dlcName = strings.TrimSpace(dlcName)
-//	result = strings.Replace(result, "{"+TemplateTokenDLCName+"}", dlcName, 1)
-//	result = strings.ReplaceAll(result, "[]", "")
-//	result = strings.ReplaceAll(result, "()", "")
-//	result = strings.ReplaceAll(result, "<>", "")
-//	if strings.HasSuffix(result, ".") {
-//		result = result[:len(result)-1]
-//	}
-//
-//	if useSafeNames {
-//		result = nihongo.RomajiString(result)
-//		safe := nonAscii.FindAllString(result, -1)
-//		result = strings.Join(safe, "")
-//	}
-//	result = strings.ReplaceAll(result, "  ", " ")
-//	result = strings.TrimSpace(result)
-//	return folderIllegalCharsRegex.ReplaceAllString(result, "")
-//}
-//
-//func deleteEmptyFolders(path string) error {
-//	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-//		if err != nil {
-//			zap.S().Error("Error while deleting empty folders", err)
-//		}
-//		if info != nil && info.IsDir() {
-//			err = deleteEmptyFolder(path)
-//			if err != nil {
-//				zap.S().Error("Error while deleting empty folders", err)
-//			}
-//		}
-//
-//		return nil
-//	})
-//	return err
-//}
-//
-//func deleteEmptyFolder(path string) error {
-//	files, err := ioutil.ReadDir(path)
-//	if err != nil {
-//		return err
-//	}
-//
-//	if len(files) != 0 {
-//		return nil
-//	}
-//
-//	zap.S().Infof("\nDeleting empty folder [%v]", path)
-//	_ = os.Remove(path)
-//
-//	return nil
-//}
+import (
+	"errors"
+	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"os"
+	"path/filepath"
+	"regexp"
+	"robpike.io/nihongo"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	folderIllegalCharsRegex = regexp.MustCompile(`[/\\?%*:;=|"<>]`)
+	nonAscii                = regexp.MustCompile("[a-zA-Z0-9áéíóú@#%&',.\\s-\\[\\]\\(\\)\\+]")
+	cjk                     = regexp.MustCompile("[\u2f70-\u2FA1\u3040-\u30ff\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\uff66-\uff9f\\p{Katakana}\\p{Hiragana}\\p{Hangul}]")
+	fileNameTagsRegex       = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+)
+
+var (
+	ErrInvalidOrganizeOptions = errors.New("invalid organize options")
+	ErrDestinationExists      = errors.New("destination already exists")
+)
+
+type OrganizeOptions struct {
+	CreateFolderPerGame bool
+	RenameFiles         bool
+	DeleteEmptyFolders  bool
+	SwitchSafeFileNames bool
+	FolderNameTemplate  string
+	FileNameTemplate    string
+}
+
+// Validate checks that enabled templates are present and contain title ID or title name.
+func (o OrganizeOptions) Validate() error {
+	if o.RenameFiles {
+		if o.FileNameTemplate == "" {
+			return fmt.Errorf("%w: file name template cannot be empty", ErrInvalidOrganizeOptions)
+		}
+		if !containsTitleToken(o.FileNameTemplate) {
+			return fmt.Errorf("%w: file name template needs to contain title ID or title name", ErrInvalidOrganizeOptions)
+		}
+	}
+	if o.CreateFolderPerGame {
+		if o.FolderNameTemplate == "" {
+			return fmt.Errorf("%w: folder name template cannot be empty", ErrInvalidOrganizeOptions)
+		}
+		if !containsTitleToken(o.FolderNameTemplate) {
+			return fmt.Errorf("%w: folder name template needs to contain title ID or title name", ErrInvalidOrganizeOptions)
+		}
+	}
+	return nil
+}
+
+func containsTitleToken(template string) bool {
+	return strings.Contains(template, templateTokenPlaceholder(TemplateTokenTitleName)) ||
+		strings.Contains(template, templateTokenPlaceholder(TemplateTokenTitleID))
+}
+
+// organizeTitle groups library files of a single title. Multi-content files are not organized.
+type organizeTitle struct {
+	idPrefix string
+	base     []organizeFile
+	updates  []organizeFile
+	dlcs     []organizeFile
+}
+
+type organizeFile struct {
+	entry        data.LibraryFileEntry
+	templateData map[TemplateToken]string
+}
+
+func groupOrganizeTitles(entries []data.LibraryFileEntry) []*organizeTitle {
+	titles := make(map[string]*organizeTitle)
+	get := func(idPrefix string) *organizeTitle {
+		title, ok := titles[idPrefix]
+		if !ok {
+			title = &organizeTitle{idPrefix: idPrefix}
+			titles[idPrefix] = title
+		}
+		return title
+	}
+
+	for _, entry := range entries {
+		if entry.LibraryGameFileMetadata == nil || entry.IsMultiContent {
+			continue
+		}
+		for _, game := range entry.BaseGames {
+			title := get(game.IDPrefix)
+			title.base = append(title.base, organizeFile{entry: entry, templateData: map[TemplateToken]string{
+				TemplateTokenTitleID:    game.ID,
+				TemplateTokenVersion:    strconv.Itoa(game.Version),
+				TemplateTokenVersionTXT: game.ReadableVersion,
+			}})
+		}
+		for _, update := range entry.Updates {
+			title := get(update.ForIDPrefix)
+			title.updates = append(title.updates, organizeFile{entry: entry, templateData: map[TemplateToken]string{
+				TemplateTokenTitleID:    update.ID,
+				TemplateTokenVersion:    strconv.Itoa(update.Version),
+				TemplateTokenVersionTXT: update.ReadableVersion,
+				TemplateTokenType:       "UPD",
+			}})
+		}
+		for _, dlc := range entry.DLCs {
+			title := get(dlc.ForIDPrefix)
+			title.dlcs = append(title.dlcs, organizeFile{entry: entry, templateData: map[TemplateToken]string{
+				TemplateTokenTitleID: dlc.ID,
+				TemplateTokenVersion: strconv.Itoa(dlc.Version),
+				TemplateTokenType:    "DLC",
+			}})
+		}
+	}
+
+	result := make([]*organizeTitle, 0, len(titles))
+	for _, title := range titles {
+		result = append(result, title)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].idPrefix < result[j].idPrefix
+	})
+	return result
+}
+
+// organizeSourcePath returns path that gets moved for the library file, which is the whole folder for split files.
+func organizeSourcePath(entry data.LibraryFileEntry) string {
+	if entry.IsSplit {
+		return filepath.Dir(entry.FilePath)
+	}
+	return entry.FilePath
+}
+
+// organizeRootDirectory returns the scan directory containing the path, or the path itself if none does.
+func organizeRootDirectory(scanDirectories []string, path string) string {
+	root := ""
+	for _, directory := range scanDirectories {
+		directory = filepath.Clean(directory)
+		if path != directory && !strings.HasPrefix(path, directory+string(os.PathSeparator)) {
+			continue
+		}
+		if len(directory) > len(root) {
+			root = directory
+		}
+	}
+	if root == "" {
+		return path
+	}
+	return root
+}
+
+func organizeTitleName(catalogEntry *storage.CatalogEntry, base organizeFile) string {
+	if catalogEntry != nil && catalogEntry.Name != "" && !cjk.MatchString(catalogEntry.Name) {
+		return catalogEntry.Name
+	}
+	for _, game := range base.entry.BaseGames {
+		if name := game.Name["AmericanEnglish"]; name != "" {
+			return name
+		}
+	}
+	// for non eShop games (cartridge only), grab the name from the file
+	name := filepath.Base(organizeSourcePath(base.entry))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.TrimSpace(fileNameTagsRegex.ReplaceAllString(name, ""))
+}
+
+func organizeDLCName(catalogEntry storage.CatalogEntry, dlcID string) string {
+	for _, dlc := range catalogEntry.DLCs {
+		if strings.EqualFold(dlc.ID, dlcID) {
+			return strings.ReplaceAll(dlc.Name, "\n", " ")
+		}
+	}
+	return ""
+}
+
+func organizeFileName(options OrganizeOptions, originalName string, templateData map[TemplateToken]string) string {
+	if !options.RenameFiles {
+		return originalName
+	}
+	ext := filepath.Ext(originalName)
+	return applyTemplate(templateData, options.SwitchSafeFileNames, options.FileNameTemplate) + ext
+}
+
+func templateTokenPlaceholder(token TemplateToken) string {
+	return "{" + string(token) + "}"
+}
+
+func applyTemplate(templateData map[TemplateToken]string, useSafeNames bool, template string) string {
+	result := strings.Replace(template, templateTokenPlaceholder(TemplateTokenTitleName), templateData[TemplateTokenTitleName], 1)
+	result = strings.Replace(result, templateTokenPlaceholder(TemplateTokenTitleID), strings.ToUpper(templateData[TemplateTokenTitleID]), 1)
+	result = strings.Replace(result, templateTokenPlaceholder(TemplateTokenVersion), templateData[TemplateTokenVersion], 1)
+	result = strings.Replace(result, templateTokenPlaceholder(TemplateTokenType), templateData[TemplateTokenType], 1)
+	result = strings.Replace(result, templateTokenPlaceholder(TemplateTokenVersionTXT), templateData[TemplateTokenVersionTXT], 1)
+	result = strings.Replace(result, templateTokenPlaceholder(TemplateTokenRegion), templateData[TemplateTokenRegion], 1)
+	// remove title name from dlc name
+	dlcName := strings.Replace(templateData[TemplateTokenDLCName], templateData[TemplateTokenTitleName], "", 1)
+	dlcName = strings.TrimSpace(dlcName)
+	dlcName = strings.TrimPrefix(dlcName, "-")
+	dlcName = strings.TrimSpace(dlcName)
+	result = strings.Replace(result, templateTokenPlaceholder(TemplateTokenDLCName), dlcName, 1)
+	result = strings.ReplaceAll(result, "[]", "")
+	result = strings.ReplaceAll(result, "()", "")
+	result = strings.ReplaceAll(result, "<>", "")
+	result = strings.TrimSuffix(result, ".")
+
+	if useSafeNames {
+		result = nihongo.RomajiString(result)
+		safe := nonAscii.FindAllString(result, -1)
+		result = strings.Join(safe, "")
+	}
+	result = strings.ReplaceAll(result, "  ", " ")
+	result = strings.TrimSpace(result)
+	return folderIllegalCharsRegex.ReplaceAllString(result, "")
+}
+
+// moveFile moves a file or a folder, creating destination folder if needed. Existing destination is never overwritten.
+func moveFile(from string, to string) error {
+	if from == to {
+		return nil
+	}
+	if isExistingDestination(from, to) {
+		return fmt.Errorf("%w: %v", ErrDestinationExists, to)
+	}
+	err := os.MkdirAll(filepath.Dir(to), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("could not create folder: %w", err)
+	}
+	err = os.Rename(from, to)
+	if err != nil {
+		return fmt.Errorf("could not move file: %w", err)
+	}
+	return nil
+}
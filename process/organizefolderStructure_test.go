@@ -1,6 +1,7 @@
 package process
 
 import (
+	"github.com/stretchr/testify/assert"
 	"robpike.io/nihongo"
 	"strings"
 	"testing"
@@ -15,3 +16,21 @@ func TestRename(t *testing.T) {
 	name = strings.Join(safe, "")
 	name = nihongo.RomajiString(name)
 }
+
+func TestApplyTemplate(t *testing.T) {
+	template := "{TITLE_NAME} ({DLC_NAME})[{TITLE_ID}][v{VERSION}]"
+	assert.Equal(t, "Game [0100000000010000][v0]", applyTemplate(map[TemplateToken]string{
+		TemplateTokenTitleName: "Game",
+		TemplateTokenTitleID:   "0100000000010000",
+		TemplateTokenVersion:   "0",
+	}, false, template))
+	assert.Equal(t, "Game (Extra Levels)[0100000000011001][v65536]", applyTemplate(map[TemplateToken]string{
+		TemplateTokenTitleName: "Game",
+		TemplateTokenDLCName:   "Game - Extra Levels",
+		TemplateTokenTitleID:   "0100000000011001",
+		TemplateTokenVersion:   "65536",
+	}, false, template))
+	assert.Equal(t, "Game Part 2", applyTemplate(map[TemplateToken]string{
+		TemplateTokenTitleName: "Game: Part 2?",
+	}, true, "{TITLE_NAME}"))
+}
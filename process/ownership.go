@@ -0,0 +1,43 @@
+package process
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/data"
+	"github.com/FrozenPear42/switch-library-manager/storage"
+	"strings"
+)
+
+// LibraryOwnership holds uppercase title ID prefixes of base games present in the library. Incomplete titles are
+// owned titles with a newer update or DLC available in the catalog.
+type LibraryOwnership struct {
+	Owned      map[string]struct{}
+	Incomplete map[string]struct{}
+}
+
+// ScanLibraryOwnership compares the library with the catalog for ownership catalog filters.
+// DLCs with IDs present in ignoreIDs are not reported as missing.
+func ScanLibraryOwnership(entries []data.LibraryFileEntry, catalog storage.SwitchDatabaseCatalog, ignoreIDs []string) (LibraryOwnership, error) {
+	ignored := newIgnoredIDs(ignoreIDs)
+	ownership := LibraryOwnership{
+		Owned:      make(map[string]struct{}),
+		Incomplete: make(map[string]struct{}),
+	}
+	for _, title := range groupLibraryTitles(entries) {
+		if len(title.BaseGames) == 0 {
+			continue
+		}
+		prefix := strings.ToUpper(title.IDPrefix)
+		ownership.Owned[prefix] = struct{}{}
+
+		catalogEntry, err := catalog.GetCatalogEntryByIDPrefix(title.IDPrefix)
+		if err != nil {
+			continue
+		}
+		_, missing := title.compareDLCs(catalogEntry, ignored)
+		latestVersion, _ := latestCatalogVersion(catalogEntry)
+		localVersion, _ := title.localVersion()
+		if len(missing) > 0 || localVersion < latestVersion {
+			ownership.Incomplete[prefix] = struct{}{}
+		}
+	}
+	return ownership, nil
+}
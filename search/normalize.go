@@ -0,0 +1,72 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+var diacriticsReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a", "ā", "a", "ă", "a", "ą", "a", "æ", "ae",
+	"ć", "c", "č", "c", "ç", "c",
+	"ď", "d", "đ", "d", "ð", "d",
+	"é", "e", "è", "e", "ê", "e", "ë", "e", "ē", "e", "ė", "e", "ę", "e", "ě", "e",
+	"ğ", "g",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ī", "i", "į", "i", "ı", "i",
+	"ł", "l", "ľ", "l",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o", "ō", "o", "ő", "o", "œ", "oe",
+	"ř", "r",
+	"ś", "s", "š", "s", "ş", "s", "ß", "ss",
+	"ť", "t", "ţ", "t", "þ", "th",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ū", "u", "ů", "u", "ű", "u", "ų", "u",
+	"ý", "y", "ÿ", "y",
+	"ź", "z", "ż", "z", "ž", "z",
+)
+
+// Normalize lowercases the text, strips diacritics and replaces punctuation and symbols such as ™ or ® with
+// single spaces, so "Pokémon™: Let's Go" and "pokemon let s go" are equal.
+func Normalize(text string) string {
+	text = diacriticsReplacer.Replace(strings.ToLower(text))
+	var builder strings.Builder
+	builder.Grow(len(text))
+	separator := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			separator = true
+			continue
+		}
+		if separator && builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		separator = false
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// Tokenize splits normalized text into searchable tokens. Scripts written without spaces (Chinese, Japanese
+// and Korean) are split into single characters.
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range strings.Fields(Normalize(text)) {
+		start := 0
+		for offset, r := range word {
+			if !isIdeographic(r) {
+				continue
+			}
+			if start < offset {
+				tokens = append(tokens, word[start:offset])
+			}
+			tokens = append(tokens, string(r))
+			start = offset + len(string(r))
+		}
+		if start < len(word) {
+			tokens = append(tokens, word[start:])
+		}
+	}
+	return tokens
+}
+
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,234 @@
+// Package search implements ranked, typo tolerant full-text search shared by the catalog, the library
+// and the NUT server.
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	exactMatchQuality  = 1.0
+	prefixMatchQuality = 0.7
+	// typoMatchQuality is divided by the number of typos
+	typoMatchQuality = 0.6
+
+	// titleMatchBonus is added when the first field of a document is equal to the query, half of it when the
+	// first field starts with the query
+	titleMatchBonus = 2.0
+)
+
+// Field is a searchable text with a weight used for ranking. Higher weight ranks matches in the field higher.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Document is a searchable item, the first field is treated as its title.
+type Document struct {
+	Fields []Field
+}
+
+// Result is a document matching a query, Document is its position in documents passed to NewIndex.
+type Result struct {
+	Document int
+	Score    float64
+}
+
+type posting struct {
+	document int
+	weight   float64
+}
+
+// Index is an immutable inverted index of documents, safe for concurrent use.
+type Index struct {
+	titles []string
+	terms  map[string][]posting
+	// vocabulary is sorted for prefix lookups
+	vocabulary []string
+	// termsByLength groups terms by length in runes for typo lookups
+	termsByLength map[int][]string
+}
+
+func NewIndex(documents []Document) *Index {
+	index := &Index{
+		titles:        make([]string, len(documents)),
+		terms:         make(map[string][]posting),
+		termsByLength: make(map[int][]string),
+	}
+	for position, document := range documents {
+		weights := make(map[string]float64)
+		for i, field := range document.Fields {
+			if i == 0 {
+				index.titles[position] = Normalize(field.Text)
+			}
+			for _, token := range Tokenize(field.Text) {
+				if field.Weight > weights[token] {
+					weights[token] = field.Weight
+				}
+			}
+		}
+		for token, weight := range weights {
+			index.terms[token] = append(index.terms[token], posting{document: position, weight: weight})
+		}
+	}
+
+	index.vocabulary = make([]string, 0, len(index.terms))
+	for term := range index.terms {
+		index.vocabulary = append(index.vocabulary, term)
+		length := utf8.RuneCountInString(term)
+		index.termsByLength[length] = append(index.termsByLength[length], term)
+	}
+	sort.Strings(index.vocabulary)
+	return index
+}
+
+// Search returns documents matching every token of the query, allowing prefixes and typos, ordered by score
+// and position. Empty query matches nothing.
+func (i *Index) Search(query string) []Result {
+	tokens := Tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var scores map[int]float64
+	for n, token := range tokens {
+		tokenScores := make(map[int]float64)
+		for term, quality := range i.matchTerms(token) {
+			for _, p := range i.terms[term] {
+				if score := quality * p.weight; score > tokenScores[p.document] {
+					tokenScores[p.document] = score
+				}
+			}
+		}
+		if n == 0 {
+			scores = tokenScores
+			continue
+		}
+		for document, score := range scores {
+			tokenScore, ok := tokenScores[document]
+			if !ok {
+				delete(scores, document)
+				continue
+			}
+			scores[document] = score + tokenScore
+		}
+	}
+
+	normalizedQuery := strings.Join(tokens, " ")
+	results := make([]Result, 0, len(scores))
+	for document, score := range scores {
+		if title := i.titles[document]; title == normalizedQuery {
+			score += titleMatchBonus
+		} else if strings.HasPrefix(title, normalizedQuery) {
+			score += titleMatchBonus / 2
+		}
+		results = append(results, Result{Document: document, Score: score})
+	}
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Document < results[b].Document
+	})
+	return results
+}
+
+// matchTerms returns indexed terms matching the token with match quality. Terms may start with the token or
+// differ by a number of typos depending on token length.
+func (i *Index) matchTerms(token string) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := i.terms[token]; ok {
+		matches[token] = exactMatchQuality
+	}
+
+	length := utf8.RuneCountInString(token)
+	if length > 1 {
+		start := sort.SearchStrings(i.vocabulary, token)
+		for _, term := range i.vocabulary[start:] {
+			if !strings.HasPrefix(term, token) {
+				break
+			}
+			if _, ok := matches[term]; !ok {
+				matches[term] = prefixMatchQuality
+			}
+		}
+	}
+
+	maxTypos := allowedTypos(length)
+	for termLength := length - maxTypos; termLength <= length+maxTypos; termLength++ {
+		for _, term := range i.termsByLength[termLength] {
+			if _, ok := matches[term]; ok {
+				continue
+			}
+			if distance := editDistance(token, term, maxTypos); distance <= maxTypos {
+				matches[term] = typoMatchQuality / float64(distance)
+			}
+		}
+	}
+	return matches
+}
+
+func allowedTypos(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns Damerau-Levenshtein (optimal string alignment) distance of a and b, or max+1 if
+// it is larger than max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = minInt(current[j], previous2[j-2]+1)
+			}
+			rowMin = minInt(rowMin, current[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
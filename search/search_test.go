@@ -0,0 +1,88 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "pokemon let s go pikachu", Normalize("Pokémon™: Let's Go, Pikachu!"))
+	assert.Equal(t, "zelda 2", Normalize("  ZELDA -- 2 "))
+	assert.Equal(t, "", Normalize("™®"))
+	assert.Equal(t, "okami hd", Normalize("Ōkami® HD"))
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"super", "mario", "odyssey"}, Tokenize("Super Mario Odyssey™"))
+	assert.Equal(t, []string{"ス", "ー", "パ", "ー", "マ", "リ", "オ", "2"}, Tokenize("スーパーマリオ2"))
+	assert.Nil(t, Tokenize(" - "))
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		max      int
+		distance int
+	}{
+		{"zelda", "zelda", 2, 0},
+		{"zelda", "zleda", 2, 1},
+		{"zelda", "zeld", 2, 1},
+		{"pokemon", "pokmeon", 2, 1},
+		{"pokemon", "pikachu", 2, 3},
+		{"mario", "mario kart", 2, 3},
+		{"ゼルダ", "ゼルダ", 0, 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.distance, editDistance(test.a, test.b, test.max), "%v %v", test.a, test.b)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := NewIndex([]Document{
+		{Fields: []Field{{Text: "The Legend of Zelda: Breath of the Wild", Weight: 3}, {Text: "Nintendo", Weight: 1}}},
+		{Fields: []Field{{Text: "Pokémon™ Sword", Weight: 3}, {Text: "The Pokémon Company", Weight: 1}}},
+		{Fields: []Field{{Text: "Pokémon™ Shield", Weight: 3}, {Text: "Nintendo", Weight: 1}}},
+		{Fields: []Field{{Text: "Hyrule Warriors", Weight: 3}, {Text: "A Zelda spin-off", Weight: 0.5}}},
+		{Fields: []Field{{Text: "ゼルダの伝説", Weight: 3}, {Text: "Legend of Zelda", Weight: 3}}},
+	})
+
+	documents := func(results []Result) []int {
+		positions := make([]int, 0, len(results))
+		for _, result := range results {
+			positions = append(positions, result.Document)
+		}
+		return positions
+	}
+
+	tests := []struct {
+		query     string
+		documents []int
+	}{
+		{"zelda", []int{0, 4, 3}},
+		{"POKEMON", []int{1, 2}},
+		{"pokemon shield", []int{2}},
+		{"pokmeon shild", []int{2}},
+		{"pok", []int{1, 2}},
+		{"nintendo", []int{0, 2}},
+		{"pokemon sword", []int{1}},
+		{"ゼルダ", []int{4}},
+		{"legend zelda", []int{0, 4}},
+		{"mario", []int{}},
+		{"", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			results := index.Search(test.query)
+			if test.documents == nil {
+				assert.Nil(t, results)
+				return
+			}
+			assert.Equal(t, test.documents, documents(results))
+		})
+	}
+
+	// exact title ranks above other matches
+	results := index.Search("hyrule warriors")
+	assert.Equal(t, []int{3}, documents(results))
+	assert.Greater(t, results[0].Score, 6.0)
+}
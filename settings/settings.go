@@ -3,13 +3,17 @@ package settings
 import (
 	"errors"
 	"fmt"
+	"github.com/FrozenPear42/switch-library-manager/data"
 	"github.com/FrozenPear42/switch-library-manager/process"
 	"github.com/FrozenPear42/switch-library-manager/utils"
 	"github.com/creasty/defaults"
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v2"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 type OrganizeOptions struct {
@@ -33,24 +37,77 @@ func (o *OrganizeOptions) SetDefaults() {
 	}
 }
 
+// ProcessOptions returns organize options in the form used by process.OrganizeLibrary.
+func (o OrganizeOptions) ProcessOptions() process.OrganizeOptions {
+	return process.OrganizeOptions{
+		CreateFolderPerGame: o.CreateFolderPerGame,
+		RenameFiles:         o.RenameFiles,
+		DeleteEmptyFolders:  o.DeleteEmptyFolders,
+		SwitchSafeFileNames: o.SwitchSafeFileNames,
+		FolderNameTemplate:  o.FolderNameTemplate,
+		FileNameTemplate:    o.FileNameTemplate,
+	}
+}
+
+// CatalogSourceSettings configures an additional catalog source, see data.NewCatalogSource.
+type CatalogSourceSettings struct {
+	Name             string `yaml:"name"`
+	Type             string `yaml:"type"`
+	Location         string `yaml:"location"`
+	VersionsLocation string `yaml:"versionsLocation"`
+	// Priority decides whose fields win, the main catalog has priority 0
+	Priority int `yaml:"priority"`
+}
+
 type NUTSettings struct {
 	Host string `yaml:"host" default:""`
 	Port int    `yaml:"port" default:"9000"`
+	// Users required to authenticate with HTTP Basic auth, the server is open when empty
+	Users        []NUTUserSettings    `yaml:"users" default:"[]"`
+	TinfoilIndex TinfoilIndexSettings `yaml:"tinfoilIndex"`
+}
+
+// TinfoilIndexSettings are passed to Tinfoil in the index served at the server root.
+type TinfoilIndexSettings struct {
+	// MessageOfTheDay is shown by Tinfoil after the index loads
+	MessageOfTheDay string `yaml:"messageOfTheDay" default:""`
+	// Referrer is sent by Tinfoil as Referer header of requests for the listed files
+	Referrer string `yaml:"referrer" default:""`
+	// Version is the minimum Tinfoil version required to load the index
+	Version string `yaml:"version" default:""`
+	// Headers are extra "Name: value" headers Tinfoil sends with requests for the listed files
+	Headers []string `yaml:"headers" default:"[]"`
+}
+
+type NUTUserSettings struct {
+	Username string `yaml:"username"`
+	// PasswordHash is bcrypt hash of the password, see slm-cli hash-password
+	PasswordHash string `yaml:"passwordHash"`
+	// AllowedTitleIDs and AllowedDirectories limit titles the user can list and download, empty allows everything
+	AllowedTitleIDs    []string `yaml:"allowedTitleIDs"`
+	AllowedDirectories []string `yaml:"allowedDirectories"`
 }
 
 type AppSettings struct {
-	Debug             bool            `yaml:"debug" default:"false"`
-	IgnoreDLCTitleIDs []string        `yaml:"ignoreDLCTitleIDs" default:"[\"test\"]"`
-	ProdKeysPath      string          `yaml:"prodKeysPath" default:"-"`
-	AppDataDirectory  string          `yaml:"appDataDirectory" default:"-"`
-	ScanDirectories   []string        `yaml:"scanDirectories" default:"[]"`
-	ScanRecursive     bool            `yaml:"scanRecursive" default:"true"`
-	TitlesFileName    string          `yaml:"titlesFileName" default:"titles.json"`
-	VersionsFileName  string          `yaml:"versionsFileName" default:"versions.json"`
-	TitlesEndpoint    string          `yaml:"titlesEndpoint" default:"https://tinfoil.media/repo/db/titles.json"`
-	VersionsEndpoint  string          `yaml:"versionsEndpoint" default:"https://tinfoil.media/repo/db/versions.json"`
-	OrganizeOptions   OrganizeOptions `yaml:"organizeOptions"`
-	NUTSettings       NUTSettings     `yaml:"nut"`
+	Debug             bool     `yaml:"debug" default:"false"`
+	IgnoreDLCTitleIDs []string `yaml:"ignoreDLCTitleIDs" default:"[\"test\"]"`
+	ProdKeysPath      string   `yaml:"prodKeysPath" default:"-"`
+	AppDataDirectory  string   `yaml:"appDataDirectory" default:"-"`
+	ScanDirectories   []string `yaml:"scanDirectories" default:"[]"`
+	ScanRecursive     bool     `yaml:"scanRecursive" default:"true"`
+	ScanWorkers       int      `yaml:"scanWorkers" default:"4"`
+	WatchDirectories  bool     `yaml:"watchDirectories" default:"true"`
+	// OfflineCatalog builds the catalog from TitlesFileName and VersionsFileName instead of endpoints
+	OfflineCatalog           bool                    `yaml:"offlineCatalog" default:"false"`
+	TitlesFileName           string                  `yaml:"titlesFileName" default:"titles.json"`
+	VersionsFileName         string                  `yaml:"versionsFileName" default:"versions.json"`
+	TitlesEndpoint           string                  `yaml:"titlesEndpoint" default:"https://tinfoil.media/repo/db/titles.json"`
+	VersionsEndpoint         string                  `yaml:"versionsEndpoint" default:"https://tinfoil.media/repo/db/versions.json"`
+	AdditionalCatalogSources []CatalogSourceSettings `yaml:"additionalCatalogSources" default:"[]"`
+	// CatalogUpdateInterval is how often the catalog is refreshed in background, zero disables updates
+	CatalogUpdateInterval time.Duration   `yaml:"catalogUpdateInterval" default:"6h"`
+	OrganizeOptions       OrganizeOptions `yaml:"organizeOptions"`
+	NUTSettings           NUTSettings     `yaml:"nut"`
 }
 
 func (o *AppSettings) SetDefaults() {
@@ -62,6 +119,49 @@ func (o *AppSettings) SetDefaults() {
 	}
 }
 
+// CatalogSources returns titles and versions sources for the catalog. In offline mode local files are used instead of
+// endpoints, relative file names are resolved against the app data directory.
+func (o AppSettings) CatalogSources() (string, string) {
+	if o.OfflineCatalog {
+		return o.appDataPath(o.TitlesFileName), o.appDataPath(o.VersionsFileName)
+	}
+	return o.TitlesEndpoint, o.VersionsEndpoint
+}
+
+// AdditionalSources creates additional catalog sources, relative file locations are resolved against the app data
+// directory.
+func (o AppSettings) AdditionalSources() ([]data.PrioritizedCatalogSource, error) {
+	sources := make([]data.PrioritizedCatalogSource, 0, len(o.AdditionalCatalogSources))
+	for _, sourceSettings := range o.AdditionalCatalogSources {
+		name := sourceSettings.Name
+		if name == "" {
+			name = sourceSettings.Location
+		}
+		source, err := data.NewCatalogSource(data.CatalogSourceType(sourceSettings.Type), name,
+			o.sourceLocation(sourceSettings.Location), o.sourceLocation(sourceSettings.VersionsLocation))
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog source %v: %w", name, err)
+		}
+		sources = append(sources, data.PrioritizedCatalogSource{CatalogSource: source, Priority: sourceSettings.Priority})
+	}
+	return sources, nil
+}
+
+// sourceLocation resolves relative file paths, URLs are returned as they are.
+func (o AppSettings) sourceLocation(location string) string {
+	if location == "" || strings.Contains(location, "://") {
+		return location
+	}
+	return o.appDataPath(location)
+}
+
+func (o AppSettings) appDataPath(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(o.AppDataDirectory, fileName)
+}
+
 var (
 	ErrConfigurationFileNotFound = errors.New("configuration file not found")
 )
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/timshannon/bolthold"
+	"go.etcd.io/bbolt"
+	"strings"
+)
+
+const (
+	catalogMetadataKey = "metadata"
+)
+
+var (
+	ErrCatalogEntryNotFound = errors.New("not found")
+)
+
+func (d *Database) GetCatalogMetadata() (CatalogMetadata, error) {
+	var metadata CatalogMetadata
+	err := d.db.Get(catalogMetadataKey, &metadata)
+	if err != nil {
+		if errors.Is(err, bolthold.ErrNotFound) {
+			return CatalogMetadata{}, nil
+		}
+		return metadata, err
+	}
+	return metadata, nil
+}
+
+func (d *Database) UpdateCatalogMetadata(metadata CatalogMetadata) error {
+	return d.db.Upsert(catalogMetadataKey, metadata)
+}
+
+func (d *Database) AddCatalogEntries(entries map[string]CatalogEntry) error {
+	d.catalogMutex.Lock()
+	defer d.catalogMutex.Unlock()
+	// index is rebuilt on the next query
+	d.catalogIndex = nil
+
+	errs := make(map[string]error)
+	for _, entry := range entries {
+		key := catalogEntryStorageKey(entry)
+		err := d.db.Upsert(key, entry)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("could not add some entries: %v", errs)
+	}
+	return nil
+}
+
+func (d *Database) ReplaceCatalog(entries map[string]CatalogEntry, metadata CatalogMetadata) error {
+	d.catalogMutex.Lock()
+	defer d.catalogMutex.Unlock()
+
+	err := d.db.Bolt().Update(func(tx *bbolt.Tx) error {
+		err := d.db.TxDeleteMatching(tx, &CatalogEntry{}, nil)
+		if err != nil {
+			return fmt.Errorf("could not clear catalog: %w", err)
+		}
+		for _, entry := range entries {
+			key := catalogEntryStorageKey(entry)
+			err = d.db.TxUpsert(tx, key, entry)
+			if err != nil {
+				return fmt.Errorf("could not add entry %v: %w", key, err)
+			}
+		}
+		err = d.db.TxUpsert(tx, catalogMetadataKey, metadata)
+		if err != nil {
+			return fmt.Errorf("could not update metadata: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		d.catalogIndex = nil
+		return err
+	}
+
+	values := make([]CatalogEntry, 0, len(entries))
+	for _, entry := range entries {
+		values = append(values, entry)
+	}
+	d.catalogIndex = newCatalogIndex(values)
+	return nil
+}
+
+func (d *Database) ClearCatalog() error {
+	d.catalogMutex.Lock()
+	defer d.catalogMutex.Unlock()
+	d.catalogIndex = nil
+	return d.db.DeleteMatching(&CatalogEntry{}, nil)
+}
+
+func (d *Database) GetCatalogEntryByID(id string) (CatalogEntry, bool, error) {
+	d.catalogMutex.RLock()
+	defer d.catalogMutex.RUnlock()
+
+	index, err := d.getCatalogIndex()
+	if err != nil {
+		return CatalogEntry{}, false, err
+	}
+	position, ok := index.lookupID(id)
+	if !ok {
+		return CatalogEntry{}, false, nil
+	}
+	entries, err := d.loadCatalogEntries(index, []int{position})
+	if err != nil {
+		return CatalogEntry{}, false, err
+	}
+	if len(entries) == 0 {
+		return CatalogEntry{}, false, nil
+	}
+	return entries[0], true, nil
+}
+
+func (d *Database) GetCatalogEntryByIDPrefix(idPrefix string) (CatalogEntry, error) {
+	d.catalogMutex.RLock()
+	defer d.catalogMutex.RUnlock()
+
+	index, err := d.getCatalogIndex()
+	if err != nil {
+		return CatalogEntry{}, err
+	}
+	positions := index.idPrefixRange(idPrefix)
+	if len(positions) == 0 {
+		position, ok := index.contentOnlyEntry(idPrefix)
+		if !ok {
+			return CatalogEntry{}, ErrCatalogEntryNotFound
+		}
+		positions = []int{position}
+	}
+	entries, err := d.loadCatalogEntries(index, positions[:1])
+	if err != nil {
+		return CatalogEntry{}, err
+	}
+	if len(entries) == 0 {
+		return CatalogEntry{}, ErrCatalogEntryNotFound
+	}
+	return entries[0], nil
+}
+
+// GetCatalogEntries returns a page of entries matching filters, starting after the entry the cursor points at.
+// Empty cursor starts at the first entry, page size 0 returns all the remaining entries.
+func (d *Database) GetCatalogEntries(filters *CatalogFilters, pageSize int, cursor string) (Page[CatalogEntry], error) {
+	d.catalogMutex.RLock()
+	defer d.catalogMutex.RUnlock()
+
+	index, err := d.getCatalogIndex()
+	if err != nil {
+		return Page[CatalogEntry]{}, err
+	}
+
+	result := index.query(filters)
+	count := len(result.positions)
+
+	start, err := index.startAfter(result, cursor)
+	if err != nil {
+		return Page[CatalogEntry]{}, err
+	}
+	end := count
+	if pageSize > 0 {
+		end = min(start+pageSize, count)
+	}
+
+	data, err := d.loadCatalogEntries(index, result.positions[start:end])
+	if err != nil {
+		return Page[CatalogEntry]{}, err
+	}
+	page := Page[CatalogEntry]{
+		Data:       data,
+		TotalCount: count,
+		IsLastPage: end >= count,
+	}
+	if !page.IsLastPage {
+		page.NextCursor = index.cursorAt(result, end-1).encode()
+	}
+	return page, nil
+}
+
+// getCatalogIndex returns catalog index, building it if needed. Has to be called with catalogMutex read locked.
+func (d *Database) getCatalogIndex() (*catalogIndex, error) {
+	if d.catalogIndex != nil {
+		return d.catalogIndex, nil
+	}
+
+	// upgrade to write lock to build the index, other readers may have built it in the meantime
+	d.catalogMutex.RUnlock()
+	d.catalogMutex.Lock()
+	defer func() {
+		d.catalogMutex.Unlock()
+		d.catalogMutex.RLock()
+	}()
+	if d.catalogIndex != nil {
+		return d.catalogIndex, nil
+	}
+
+	var entries []CatalogEntry
+	err := d.db.Find(&entries, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not load catalog: %w", err)
+	}
+	d.catalogIndex = newCatalogIndex(entries)
+	return d.catalogIndex, nil
+}
+
+// loadCatalogEntries reads entries at index positions from the database, preserving order.
+func (d *Database) loadCatalogEntries(index *catalogIndex, positions []int) ([]CatalogEntry, error) {
+	result := make([]CatalogEntry, 0, len(positions))
+	err := d.db.Bolt().View(func(tx *bbolt.Tx) error {
+		for _, position := range positions {
+			var entry CatalogEntry
+			err := d.db.TxGet(tx, index.entries[position].key, &entry)
+			if err != nil {
+				if errors.Is(err, bolthold.ErrNotFound) {
+					continue
+				}
+				return err
+			}
+			result = append(result, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read catalog entries: %w", err)
+	}
+	return result, nil
+}
+
+// catalogEntryStorageKey returns database key of the entry. Entries without base title data are stored under
+// the key of their update or DLC.
+func catalogEntryStorageKey(entry CatalogEntry) string {
+	id := entry.ID
+	if id == "" {
+		id = entry.RecentUpdate.ID
+	}
+	if id == "" && len(entry.DLCs) > 0 {
+		id = entry.DLCs[0].ID
+	}
+	return catalogEntryKey(id)
+}
+
+// catalogEntryKey returns database key of the entry, which is the title ID without the last 4 characters.
+func catalogEntryKey(id string) string {
+	id = strings.ToUpper(id)
+	if len(id) < 4 {
+		return id
+	}
+	return id[:len(id)-4]
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const benchmarkCatalogSize = 20000
+
+func testCatalogEntry(id, name, region string) CatalogEntry {
+	return CatalogEntry{CatalogEntryData: CatalogEntryData{ID: id, Name: name, Region: region}}
+}
+
+func withPublisher(entry CatalogEntry, publisher string) CatalogEntry {
+	entry.Publisher = publisher
+	return entry
+}
+
+func newTestCatalogDatabase(t testing.TB, entries ...CatalogEntry) *Database {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	assert.Nil(t, db.ReplaceCatalog(entriesByID(entries), CatalogMetadata{}))
+	return db
+}
+
+func catalogEntryIDs(entries []CatalogEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func TestGetCatalogEntries(t *testing.T) {
+	db := newTestCatalogDatabase(t,
+		withPublisher(testCatalogEntry("0100000000030000", "Zelda", "US"), "Nintendo"),
+		withPublisher(testCatalogEntry("0100000000010000", "Pokémon Sword", "EU"), "Nintendo"),
+		testCatalogEntry("0100000000020000", "Animal Crossing", "US"),
+		testCatalogEntry("0100000000110000", "Pokemon Shield", "JP"),
+	)
+	defer db.Close()
+
+	name := func(v string) *string { return &v }
+	tests := []struct {
+		name    string
+		filters *CatalogFilters
+		ids     []string
+	}{
+		{"no filters", nil, []string{"0100000000010000", "0100000000020000", "0100000000030000", "0100000000110000"}},
+		{"sort by name", &CatalogFilters{SortBy: CatalogFiltersSortByName}, []string{"0100000000020000", "0100000000110000", "0100000000010000", "0100000000030000"}},
+		{"normalized name", &CatalogFilters{Name: name("POKEMON")}, []string{"0100000000010000", "0100000000110000"}},
+		{"name with typo by relevance", &CatalogFilters{Name: name("pokmon shild")}, []string{"0100000000110000"}},
+		{"name sorted by name", &CatalogFilters{Name: name("pokemon"), SortBy: CatalogFiltersSortByName}, []string{"0100000000110000", "0100000000010000"}},
+		{"publisher", &CatalogFilters{Name: name("nintendo")}, []string{"0100000000010000", "0100000000030000"}},
+		{"id prefix", &CatalogFilters{ID: name("01000000000")}, []string{"0100000000010000", "0100000000020000", "0100000000030000"}},
+		{"region", &CatalogFilters{Region: []string{"us", "JP"}}, []string{"0100000000020000", "0100000000030000", "0100000000110000"}},
+		{"region sorted by name", &CatalogFilters{Region: []string{"US"}, SortBy: CatalogFiltersSortByName}, []string{"0100000000020000", "0100000000030000"}},
+		{"combined", &CatalogFilters{ID: name("0100000000"), Region: []string{"EU", "JP"}, Name: name("shield")}, []string{"0100000000110000"}},
+		{"no match", &CatalogFilters{Name: name("mario")}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := db.GetCatalogEntries(test.filters, 0, "")
+			assert.Nil(t, err)
+			assert.Equal(t, test.ids, catalogEntryIDs(page.Data))
+			assert.Equal(t, len(test.ids), page.TotalCount)
+			assert.True(t, page.IsLastPage)
+		})
+	}
+
+	// sorting by name does not change order of later queries
+	page, err := db.GetCatalogEntries(nil, 2, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0100000000010000", "0100000000020000"}, catalogEntryIDs(page.Data))
+	assert.NotEmpty(t, page.NextCursor)
+	assert.False(t, page.IsLastPage)
+
+	page, err = db.GetCatalogEntries(nil, 2, page.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0100000000030000", "0100000000110000"}, catalogEntryIDs(page.Data))
+	assert.Empty(t, page.NextCursor)
+	assert.True(t, page.IsLastPage)
+}
+
+func TestGetCatalogEntriesFilters(t *testing.T) {
+	entry := func(id, publisher, developer string, category, languages []string, size int, releaseDate string, isDemo bool) CatalogEntry {
+		return CatalogEntry{CatalogEntryData: CatalogEntryData{
+			ID: id, Name: id, Publisher: publisher, Developer: developer, Category: category, Languages: languages,
+			Size: size, ReleaseDate: releaseDate, IsDemo: isDemo,
+		}}
+	}
+	db := newTestCatalogDatabase(t,
+		entry("0100000000010000", "Nintendo", "Game Freak", []string{"RPG"}, []string{"en", "ja"}, 300, "2019-11-15", false),
+		entry("0100000000020000", "Nintendo", "Monolith Soft", []string{"RPG", "Action"}, []string{"en"}, 100, "2017-12-01", false),
+		entry("0100000000030000", "Square Enix", "Square Enix", []string{"Action"}, []string{"ja"}, 200, "", true),
+		entry("0100000000040000", "Team Cherry", "Team Cherry", []string{"Platformer"}, []string{"en", "de"}, 50, "2018-06-12", false),
+	)
+	defer db.Close()
+
+	str := func(v string) *string { return &v }
+	num := func(v int) *int { return &v }
+	flag := func(v bool) *bool { return &v }
+	owned := map[string]struct{}{"010000000001": {}, "010000000003": {}}
+	incomplete := map[string]struct{}{"010000000003": {}}
+
+	tests := []struct {
+		name    string
+		filters CatalogFilters
+		ids     []string
+	}{
+		{"publisher", CatalogFilters{Publisher: str("nintendo")}, []string{"0100000000010000", "0100000000020000"}},
+		{"developer", CatalogFilters{Developer: str("enix")}, []string{"0100000000030000"}},
+		{"category", CatalogFilters{Category: []string{"action", "platformer"}}, []string{"0100000000020000", "0100000000030000", "0100000000040000"}},
+		{"language", CatalogFilters{Language: []string{"JA"}}, []string{"0100000000010000", "0100000000030000"}},
+		{"size range", CatalogFilters{MinSize: num(100), MaxSize: num(200)}, []string{"0100000000020000", "0100000000030000"}},
+		{"released after", CatalogFilters{ReleasedAfter: str("2018-01-01")}, []string{"0100000000010000", "0100000000040000"}},
+		{"released before partial date", CatalogFilters{ReleasedBefore: str("2018-06")}, []string{"0100000000020000", "0100000000040000"}},
+		{"demo", CatalogFilters{IsDemo: flag(true)}, []string{"0100000000030000"}},
+		{"not demo", CatalogFilters{IsDemo: flag(false)}, []string{"0100000000010000", "0100000000020000", "0100000000040000"}},
+		{"owned", CatalogFilters{Ownership: CatalogOwnershipOwned, OwnedTitles: owned}, []string{"0100000000010000", "0100000000030000"}},
+		{"not owned", CatalogFilters{Ownership: CatalogOwnershipNotOwned, OwnedTitles: owned}, []string{"0100000000020000", "0100000000040000"}},
+		{"missing content", CatalogFilters{Ownership: CatalogOwnershipMissingContent, IncompleteTitles: incomplete}, []string{"0100000000030000"}},
+		{"sort by size", CatalogFilters{SortBy: CatalogFiltersSortBySize}, []string{"0100000000040000", "0100000000020000", "0100000000030000", "0100000000010000"}},
+		{"sort by release date descending", CatalogFilters{SortBy: CatalogFiltersSortByReleaseDate, SortDescending: true}, []string{"0100000000010000", "0100000000040000", "0100000000020000", "0100000000030000"}},
+		{"sort by publisher", CatalogFilters{SortBy: CatalogFiltersSortByPublisher, Language: []string{"en"}}, []string{"0100000000010000", "0100000000020000", "0100000000040000"}},
+		{"sort by developer", CatalogFilters{SortBy: CatalogFiltersSortByDeveloper}, []string{"0100000000010000", "0100000000020000", "0100000000030000", "0100000000040000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := db.GetCatalogEntries(&test.filters, 0, "")
+			assert.Nil(t, err)
+			assert.Equal(t, test.ids, catalogEntryIDs(page.Data))
+		})
+	}
+
+	page, err := db.GetCatalogEntries(nil, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"RPG", "Action"}, page.Data[1].Category)
+	assert.Equal(t, 100, page.Data[1].Size)
+}
+
+func TestGetCatalogEntryByID(t *testing.T) {
+	entry := testCatalogEntry("0100000000010000", "Game", "US")
+	entry.RecentUpdate = CatalogEntryRecentUpdate{ID: "0100000000010800", Version: 65536}
+	entry.DLCs = []CatalogEntryDLC{{CatalogEntryData: CatalogEntryData{ID: "0100000000011001"}}}
+	db := newTestCatalogDatabase(t, entry, testCatalogEntry("0100000000020000", "Other", "US"))
+	defer db.Close()
+
+	for _, id := range []string{"0100000000010000", "0100000000010800", "0100000000011001"} {
+		found, ok, err := db.GetCatalogEntryByID(id)
+		assert.Nil(t, err)
+		assert.True(t, ok, id)
+		assert.Equal(t, "Game", found.Name)
+	}
+	_, ok, err := db.GetCatalogEntryByID("0100000000030000")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	found, err := db.GetCatalogEntryByIDPrefix("010000000002")
+	assert.Nil(t, err)
+	assert.Equal(t, "Other", found.Name)
+	_, err = db.GetCatalogEntryByIDPrefix("010000000003")
+	assert.ErrorIs(t, err, ErrCatalogEntryNotFound)
+}
+
+func TestCatalogIndexRebuild(t *testing.T) {
+	db := newTestCatalogDatabase(t, testCatalogEntry("0100000000010000", "Game", "US"))
+	defer db.Close()
+
+	// entries added without replacing the catalog are visible after the index is rebuilt
+	err := db.AddCatalogEntries(map[string]CatalogEntry{
+		"0100000000020000": testCatalogEntry("0100000000020000", "Other", "EU"),
+	})
+	assert.Nil(t, err)
+	entry, err := db.GetCatalogEntryByIDPrefix("010000000002")
+	assert.Nil(t, err)
+	assert.Equal(t, "Other", entry.Name)
+
+	// index is loaded from disk when the database is reopened
+	path := db.path
+	assert.Nil(t, db.Close())
+	db, err = NewDatabase(path)
+	assert.Nil(t, err)
+	page, err := db.GetCatalogEntries(&CatalogFilters{Region: []string{"EU"}}, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"0100000000020000"}, catalogEntryIDs(page.Data))
+
+	assert.Nil(t, db.ClearCatalog())
+	page, err = db.GetCatalogEntries(nil, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, page.TotalCount)
+}
+
+func TestCatalogConcurrentQueries(t *testing.T) {
+	db := newTestCatalogDatabase(t, generateTestCatalog(200)...)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				sortBy := CatalogFiltersSortByID
+				if (i+j)%2 == 0 {
+					sortBy = CatalogFiltersSortByName
+				}
+				page, err := db.GetCatalogEntries(&CatalogFilters{SortBy: sortBy}, 10, "")
+				assert.Nil(t, err)
+				assert.Len(t, page.Data, 10)
+				if i == 0 && j%5 == 0 {
+					// replacing the catalog while others read keeps results consistent
+					assert.Nil(t, db.ReplaceCatalog(entriesByID(generateTestCatalog(200)), CatalogMetadata{}))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	page, err := db.GetCatalogEntries(nil, 0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, page.TotalCount)
+}
+
+func generateTestCatalog(size int) []CatalogEntry {
+	regions := []string{"US", "EU", "JP", "KR"}
+	entries := make([]CatalogEntry, 0, size)
+	for i := 0; i < size; i++ {
+		// names are spread so that sorting by name differs from sorting by ID
+		name := fmt.Sprintf("Game %05d Edition", (i*7919)%size)
+		entries = append(entries, testCatalogEntry(fmt.Sprintf("0100%08X0000", i+1), name, regions[i%len(regions)]))
+	}
+	return entries
+}
+
+func entriesByID(entries []CatalogEntry) map[string]CatalogEntry {
+	result := make(map[string]CatalogEntry, len(entries))
+	for _, entry := range entries {
+		result[entry.ID] = entry
+	}
+	return result
+}
+
+func BenchmarkGetCatalogEntries(b *testing.B) {
+	db := newTestCatalogDatabase(b, generateTestCatalog(benchmarkCatalogSize)...)
+	defer db.Close()
+
+	name := "edition 00042"
+	typo := "edtion"
+	idPrefix := "0100000012"
+	benchmarks := []struct {
+		name    string
+		filters *CatalogFilters
+	}{
+		{"all", nil},
+		{"sort by name", &CatalogFilters{SortBy: CatalogFiltersSortByName}},
+		{"name", &CatalogFilters{Name: &name}},
+		{"name with typo", &CatalogFilters{Name: &typo}},
+		{"region", &CatalogFilters{Region: []string{"EU", "JP"}, SortBy: CatalogFiltersSortByName}},
+		{"id prefix", &CatalogFilters{ID: &idPrefix}},
+	}
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			first, err := db.GetCatalogEntries(benchmark.filters, 100, "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := db.GetCatalogEntries(benchmark.filters, 50, first.NextCursor)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetCatalogEntryByID(b *testing.B) {
+	db := newTestCatalogDatabase(b, generateTestCatalog(benchmarkCatalogSize)...)
+	defer db.Close()
+
+	b.Run("id", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, err := db.GetCatalogEntryByID(fmt.Sprintf("0100%08X0000", i%benchmarkCatalogSize+1))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("id prefix", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := db.GetCatalogEntryByIDPrefix(fmt.Sprintf("0100%08X", i%benchmarkCatalogSize+1))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
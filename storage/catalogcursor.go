@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidCatalogCursor = errors.New("invalid catalog cursor")
+)
+
+// catalogCursor points at the last entry of a page by its sort key and ID, so the next page starts right after it
+// even if entries were added, removed or the catalog was rebuilt in the meantime.
+type catalogCursor struct {
+	Order      CatalogFiltersSortBy `json:"o"`
+	Descending bool                 `json:"d,omitempty"`
+	// Key is the sort key of text orders, Number is size or relevance score
+	Key    string  `json:"k,omitempty"`
+	Number float64 `json:"n,omitempty"`
+	ID     string  `json:"i"`
+}
+
+func (c catalogCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCatalogCursor(cursor string) (catalogCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return catalogCursor{}, fmt.Errorf("%w: %w", ErrInvalidCatalogCursor, err)
+	}
+	var result catalogCursor
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return catalogCursor{}, fmt.Errorf("%w: %w", ErrInvalidCatalogCursor, err)
+	}
+	if result.ID == "" {
+		return catalogCursor{}, fmt.Errorf("%w: missing ID", ErrInvalidCatalogCursor)
+	}
+	return result, nil
+}
+
+// cursorAt returns cursor pointing at the n-th entry of the result.
+func (i *catalogIndex) cursorAt(result catalogQueryResult, n int) catalogCursor {
+	entry := i.entries[result.positions[n]]
+	cursor := catalogCursor{
+		Order:      result.order,
+		Descending: result.descending,
+		Key:        entry.sortKey(result.order),
+		ID:         entry.id,
+	}
+	switch result.order {
+	case CatalogFiltersSortBySize:
+		cursor.Number = float64(entry.size)
+	case CatalogFiltersSortByRelevance:
+		cursor.Number = result.scores[n]
+	}
+	return cursor
+}
+
+// compareCursor returns a negative number if the n-th entry of the result goes before the cursor, zero if it is
+// the cursor entry and a positive number if it goes after it.
+func (i *catalogIndex) compareCursor(result catalogQueryResult, n int, cursor catalogCursor) int {
+	entry := i.entries[result.positions[n]]
+	var comparison int
+	switch result.order {
+	case CatalogFiltersSortByRelevance:
+		// best matches go first
+		comparison = compareNumbers(cursor.Number, result.scores[n])
+	case CatalogFiltersSortBySize:
+		comparison = compareNumbers(float64(entry.size), cursor.Number)
+	default:
+		comparison = strings.Compare(entry.sortKey(result.order), cursor.Key)
+	}
+	if comparison == 0 {
+		comparison = strings.Compare(entry.id, cursor.ID)
+	}
+	if result.descending {
+		return -comparison
+	}
+	return comparison
+}
+
+// startAfter returns position in the result of the first entry after the cursor. Empty cursor starts at the
+// beginning, cursor of a different order is rejected.
+func (i *catalogIndex) startAfter(result catalogQueryResult, cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	decoded, err := decodeCatalogCursor(cursor)
+	if err != nil {
+		return 0, err
+	}
+	if decoded.Order != result.order || decoded.Descending != result.descending {
+		return 0, fmt.Errorf("%w: cursor does not match requested order", ErrInvalidCatalogCursor)
+	}
+	return sort.Search(len(result.positions), func(n int) bool {
+		return i.compareCursor(result, n, decoded) > 0
+	}), nil
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// generateCursorTestCatalog returns entries with many equal sort keys, so that pages split runs of ties.
+func generateCursorTestCatalog(size int) []CatalogEntry {
+	names := []string{"Pokemon", "Zelda", "Mario", "Metroid"}
+	publishers := []string{"Nintendo", "Pokemon Company", "Sega"}
+	entries := make([]CatalogEntry, 0, size)
+	for i := 0; i < size; i++ {
+		entry := testCatalogEntry(fmt.Sprintf("0100%08X0000", i+1), fmt.Sprintf("%v %v", names[i%len(names)], i%7), "US")
+		entry.Publisher = publishers[i%len(publishers)]
+		entry.Developer = publishers[(i/2)%len(publishers)]
+		entry.Size = (i % 5) * 1000
+		entry.ReleaseDate = fmt.Sprintf("2020-0%v-01", 1+i%9)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// readAllPages pages through the catalog, calling onPage after every page but the last one.
+func readAllPages(t *testing.T, db *Database, filters *CatalogFilters, pageSize int, onPage func(page int)) []string {
+	var ids []string
+	cursor := ""
+	for page := 0; ; page++ {
+		result, err := db.GetCatalogEntries(filters, pageSize, cursor)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(result.Data), pageSize)
+		ids = append(ids, catalogEntryIDs(result.Data)...)
+		if result.IsLastPage {
+			assert.Empty(t, result.NextCursor)
+			return ids
+		}
+		assert.NotEmpty(t, result.NextCursor)
+		cursor = result.NextCursor
+		if onPage != nil {
+			onPage(page)
+		}
+	}
+}
+
+func TestGetCatalogEntriesCursor(t *testing.T) {
+	db := newTestCatalogDatabase(t, generateCursorTestCatalog(50)...)
+	defer db.Close()
+
+	query := func(v string) *string { return &v }
+	tests := []struct {
+		name    string
+		filters *CatalogFilters
+	}{
+		{"no filters", nil},
+		{"id", &CatalogFilters{SortBy: CatalogFiltersSortByID}},
+		{"id descending", &CatalogFilters{SortBy: CatalogFiltersSortByID, SortDescending: true}},
+		{"name", &CatalogFilters{SortBy: CatalogFiltersSortByName}},
+		{"name descending", &CatalogFilters{SortBy: CatalogFiltersSortByName, SortDescending: true}},
+		{"publisher", &CatalogFilters{SortBy: CatalogFiltersSortByPublisher}},
+		{"developer descending", &CatalogFilters{SortBy: CatalogFiltersSortByDeveloper, SortDescending: true}},
+		{"release date descending", &CatalogFilters{SortBy: CatalogFiltersSortByReleaseDate, SortDescending: true}},
+		{"size", &CatalogFilters{SortBy: CatalogFiltersSortBySize}},
+		{"size descending", &CatalogFilters{SortBy: CatalogFiltersSortBySize, SortDescending: true}},
+		{"relevance", &CatalogFilters{Name: query("pokemon")}},
+		{"query sorted by size", &CatalogFilters{Name: query("pokemon"), SortBy: CatalogFiltersSortBySize}},
+		{"region sorted by name", &CatalogFilters{Region: []string{"US"}, SortBy: CatalogFiltersSortByName}},
+	}
+	for _, test := range tests {
+		for _, pageSize := range []int{1, 3, 7, 50} {
+			t.Run(fmt.Sprintf("%v by %v", test.name, pageSize), func(t *testing.T) {
+				all, err := db.GetCatalogEntries(test.filters, 0, "")
+				assert.Nil(t, err)
+				assert.NotEmpty(t, all.Data)
+
+				assert.Equal(t, catalogEntryIDs(all.Data), readAllPages(t, db, test.filters, pageSize, nil))
+			})
+		}
+	}
+}
+
+func TestGetCatalogEntriesCursorAfterRefresh(t *testing.T) {
+	entries := generateCursorTestCatalog(50)
+	tests := []struct {
+		name    string
+		filters *CatalogFilters
+	}{
+		{"name", &CatalogFilters{SortBy: CatalogFiltersSortByName}},
+		{"size descending", &CatalogFilters{SortBy: CatalogFiltersSortBySize, SortDescending: true}},
+		{"publisher", &CatalogFilters{SortBy: CatalogFiltersSortByPublisher}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := newTestCatalogDatabase(t, entries...)
+			defer db.Close()
+
+			before, err := db.GetCatalogEntries(test.filters, 0, "")
+			assert.Nil(t, err)
+			beforeIDs := catalogEntryIDs(before.Data)
+
+			// after two pages drop the last read entry, one read and one unread entry, and add new entries
+			removed := map[string]struct{}{beforeIDs[9]: {}, beforeIDs[2]: {}, beforeIDs[30]: {}}
+			refreshed := make([]CatalogEntry, 0, len(entries))
+			for _, entry := range entries {
+				if _, ok := removed[entry.ID]; !ok {
+					refreshed = append(refreshed, entry)
+				}
+			}
+			refreshed = append(refreshed, generateCursorTestCatalog(60)[50:]...)
+
+			ids := readAllPages(t, db, test.filters, 5, func(page int) {
+				if page == 1 {
+					assert.Nil(t, db.ReplaceCatalog(entriesByID(refreshed), CatalogMetadata{}))
+				}
+			})
+
+			after, err := db.GetCatalogEntries(test.filters, 0, "")
+			assert.Nil(t, err)
+			afterIDs := catalogEntryIDs(after.Data)
+
+			// titles read before the refresh followed by the rest of the refreshed catalog
+			assert.Equal(t, beforeIDs[:10], ids[:10])
+			assert.Equal(t, afterIDs[len(afterIDs)-len(ids)+10:], ids[10:])
+
+			seen := make(map[string]struct{}, len(ids))
+			for _, id := range ids {
+				assert.NotContains(t, seen, id, "duplicate %v", id)
+				seen[id] = struct{}{}
+			}
+			for _, entry := range refreshed {
+				if indexOf(beforeIDs, entry.ID) >= 0 {
+					assert.Contains(t, seen, entry.ID, "skipped %v", entry.ID)
+				}
+			}
+			assert.NotContains(t, seen, beforeIDs[30])
+		})
+	}
+}
+
+func TestGetCatalogEntriesInvalidCursor(t *testing.T) {
+	db := newTestCatalogDatabase(t, generateCursorTestCatalog(10)...)
+	defer db.Close()
+
+	page, err := db.GetCatalogEntries(&CatalogFilters{SortBy: CatalogFiltersSortByName}, 2, "")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		filters *CatalogFilters
+		cursor  string
+	}{
+		{"not base64", nil, "not a cursor!"},
+		{"not json", nil, base64.RawURLEncoding.EncodeToString([]byte("cursor"))},
+		{"missing id", nil, catalogCursor{Order: CatalogFiltersSortByID}.encode()},
+		{"different order", &CatalogFilters{SortBy: CatalogFiltersSortBySize}, page.NextCursor},
+		{"different direction", &CatalogFilters{SortBy: CatalogFiltersSortByName, SortDescending: true}, page.NextCursor},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := db.GetCatalogEntries(test.filters, 2, test.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCatalogCursor)
+		})
+	}
+}
+
+func indexOf(ids []string, id string) int {
+	for i, current := range ids {
+		if current == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,413 @@
+package storage
+
+import (
+	"github.com/FrozenPear42/switch-library-manager/search"
+	"sort"
+	"strings"
+)
+
+// catalogIndexEntry holds fields used by queries, full entries are read from the database.
+type catalogIndexEntry struct {
+	key         string
+	id          string
+	name        string
+	region      string
+	publisher   string
+	developer   string
+	categories  []string
+	languages   []string
+	size        int
+	releaseDate string
+	isDemo      bool
+}
+
+// catalogIndex is an immutable index of catalog entries, it is replaced as a whole when catalog changes.
+type catalogIndex struct {
+	entries []catalogIndexEntry
+	// byID and byName are entry positions sorted by ID and normalized name
+	byID   []int
+	byName []int
+	// nameRank is position of every entry in byName
+	nameRank []int
+	// contentIDs maps base, update and DLC IDs to entry positions
+	contentIDs map[string]int
+	// contentOnlyKeys maps keys of entries without base title data to their positions, such entries are found
+	// by content IDs and keys only and are never listed
+	contentOnlyKeys map[string]int
+	// regions maps lowercase regions to entry positions sorted by ID
+	regions map[string][]int
+	// search indexes names, publishers and descriptions, its documents are entries in byID order
+	search *search.Index
+}
+
+func newCatalogIndex(entries []CatalogEntry) *catalogIndex {
+	index := &catalogIndex{
+		entries:         make([]catalogIndexEntry, 0, len(entries)),
+		contentIDs:      make(map[string]int, len(entries)),
+		contentOnlyKeys: make(map[string]int),
+		regions:         make(map[string][]int),
+	}
+	indexed := make([]CatalogEntry, 0, len(entries))
+	var contentOnly []CatalogEntry
+	for _, entry := range entries {
+		if entry.ID == "" {
+			contentOnly = append(contentOnly, entry)
+			continue
+		}
+		indexed = append(indexed, entry)
+		position := len(index.entries)
+		id := strings.ToUpper(entry.ID)
+		index.entries = append(index.entries, catalogIndexEntry{
+			key:         catalogEntryStorageKey(entry),
+			id:          id,
+			name:        search.Normalize(entry.Name),
+			region:      strings.ToLower(entry.Region),
+			publisher:   search.Normalize(entry.Publisher),
+			developer:   search.Normalize(entry.Developer),
+			categories:  lowerStrings(entry.Category),
+			languages:   lowerStrings(entry.Languages),
+			size:        entry.Size,
+			releaseDate: entry.ReleaseDate,
+			isDemo:      entry.IsDemo,
+		})
+		index.contentIDs[id] = position
+		if entry.RecentUpdate.ID != "" {
+			index.contentIDs[strings.ToUpper(entry.RecentUpdate.ID)] = position
+		}
+		for _, dlc := range entry.DLCs {
+			index.contentIDs[strings.ToUpper(dlc.ID)] = position
+		}
+	}
+
+	index.byID = make([]int, len(index.entries))
+	index.byName = make([]int, len(index.entries))
+	for i := range index.byID {
+		index.byID[i] = i
+		index.byName[i] = i
+	}
+	sort.Slice(index.byID, func(i, j int) bool {
+		return index.entries[index.byID[i]].id < index.entries[index.byID[j]].id
+	})
+	sort.Slice(index.byName, func(i, j int) bool {
+		a, b := index.entries[index.byName[i]], index.entries[index.byName[j]]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.id < b.id
+	})
+	index.nameRank = make([]int, len(index.entries))
+	for rank, position := range index.byName {
+		index.nameRank[position] = rank
+	}
+	documents := make([]search.Document, 0, len(index.byID))
+	for _, position := range index.byID {
+		region := index.entries[position].region
+		index.regions[region] = append(index.regions[region], position)
+
+		entry := indexed[position]
+		documents = append(documents, search.Document{Fields: []search.Field{
+			{Text: entry.Name, Weight: 3},
+			{Text: entry.Publisher, Weight: 1},
+			{Text: entry.Description, Weight: 0.5},
+		}})
+	}
+	index.search = search.NewIndex(documents)
+
+	// content only entries follow listed ones, so that listing indexes above do not include them
+	for _, entry := range contentOnly {
+		key := catalogEntryStorageKey(entry)
+		if key == "" {
+			continue
+		}
+		position := len(index.entries)
+		index.entries = append(index.entries, catalogIndexEntry{key: key})
+		index.contentOnlyKeys[key] = position
+		if entry.RecentUpdate.ID != "" {
+			index.contentIDs[strings.ToUpper(entry.RecentUpdate.ID)] = position
+		}
+		for _, dlc := range entry.DLCs {
+			index.contentIDs[strings.ToUpper(dlc.ID)] = position
+		}
+	}
+	return index
+}
+
+func (i *catalogIndex) lookupID(id string) (int, bool) {
+	position, ok := i.contentIDs[strings.ToUpper(id)]
+	return position, ok
+}
+
+// contentOnlyEntry returns position of the entry without base title data stored under the key.
+func (i *catalogIndex) contentOnlyEntry(key string) (int, bool) {
+	position, ok := i.contentOnlyKeys[strings.ToUpper(key)]
+	return position, ok
+}
+
+// idPrefixRange returns positions of entries with ID starting with prefix, sorted by ID. Returned slice
+// must not be modified.
+func (i *catalogIndex) idPrefixRange(prefix string) []int {
+	prefix = strings.ToUpper(prefix)
+	start := sort.Search(len(i.byID), func(n int) bool {
+		return i.entries[i.byID[n]].id >= prefix
+	})
+	end := start
+	for end < len(i.byID) && strings.HasPrefix(i.entries[i.byID[end]].id, prefix) {
+		end++
+	}
+	return i.byID[start:end]
+}
+
+// catalogQueryResult holds positions of entries matching a query in their order.
+type catalogQueryResult struct {
+	positions []int
+	// scores are relevance scores of positions, set only when ordered by relevance
+	scores     []float64
+	order      CatalogFiltersSortBy
+	descending bool
+}
+
+// query returns entries matching filters in requested order, ties are ordered by ID. Entries matching a name
+// query are ordered by relevance unless other order is requested. Returned slices are never shared with the index.
+func (i *catalogIndex) query(filters *CatalogFilters) catalogQueryResult {
+	if filters == nil {
+		return catalogQueryResult{positions: append([]int(nil), i.byID...), order: CatalogFiltersSortByID}
+	}
+
+	order := filters.SortBy
+	if order == "" || order == CatalogFiltersSortByRelevance {
+		order = CatalogFiltersSortByID
+	}
+
+	// pick the narrowest index as the candidate set and remember its order
+	var candidates []int
+	var scores map[int]float64
+	var candidatesOrder CatalogFiltersSortBy
+	switch {
+	case filters.Name != nil && search.Normalize(*filters.Name) != "":
+		results := i.search.Search(*filters.Name)
+		candidates = make([]int, 0, len(results))
+		scores = make(map[int]float64, len(results))
+		for _, result := range results {
+			position := i.byID[result.Document]
+			candidates = append(candidates, position)
+			scores[position] = result.Score
+		}
+		candidatesOrder = CatalogFiltersSortByRelevance
+		if filters.SortBy == "" || filters.SortBy == CatalogFiltersSortByRelevance {
+			order = CatalogFiltersSortByRelevance
+		}
+	case filters.ID != nil:
+		candidates = i.idPrefixRange(*filters.ID)
+		candidatesOrder = CatalogFiltersSortByID
+	case len(filters.Region) > 0:
+		candidates = i.regionPositions(filters.Region)
+		candidatesOrder = CatalogFiltersSortByID
+	case order == CatalogFiltersSortByName:
+		candidates = i.byName
+		candidatesOrder = CatalogFiltersSortByName
+	default:
+		candidates = i.byID
+		candidatesOrder = CatalogFiltersSortByID
+	}
+
+	matches := newCatalogMatcher(filters)
+	result := catalogQueryResult{
+		positions: make([]int, 0, len(candidates)),
+		order:     order,
+		// relevance has a single, best match first, order
+		descending: filters.SortDescending && order != CatalogFiltersSortByRelevance,
+	}
+	for _, position := range candidates {
+		if matches(i.entries[position]) {
+			result.positions = append(result.positions, position)
+		}
+	}
+
+	if order != candidatesOrder {
+		less := i.orderLess(order)
+		sort.Slice(result.positions, func(a, b int) bool {
+			return less(result.positions[a], result.positions[b])
+		})
+	}
+	if result.descending {
+		for a, b := 0, len(result.positions)-1; a < b; a, b = a+1, b-1 {
+			result.positions[a], result.positions[b] = result.positions[b], result.positions[a]
+		}
+	}
+	if order == CatalogFiltersSortByRelevance {
+		result.scores = make([]float64, 0, len(result.positions))
+		for _, position := range result.positions {
+			result.scores = append(result.scores, scores[position])
+		}
+	}
+	return result
+}
+
+// orderLess compares entry positions in the given order, ties are ordered by ID. Relevance is not known
+// to the index, so it is not supported.
+func (i *catalogIndex) orderLess(order CatalogFiltersSortBy) func(a, b int) bool {
+	switch order {
+	case CatalogFiltersSortByName:
+		return func(a, b int) bool {
+			return i.nameRank[a] < i.nameRank[b]
+		}
+	case CatalogFiltersSortBySize:
+		return func(a, b int) bool {
+			if i.entries[a].size != i.entries[b].size {
+				return i.entries[a].size < i.entries[b].size
+			}
+			return i.entries[a].id < i.entries[b].id
+		}
+	}
+	return func(a, b int) bool {
+		keyA, keyB := i.entries[a].sortKey(order), i.entries[b].sortKey(order)
+		if keyA != keyB {
+			return keyA < keyB
+		}
+		return i.entries[a].id < i.entries[b].id
+	}
+}
+
+// sortKey returns the text entries are ordered by, empty for orders by ID, size or relevance.
+func (e catalogIndexEntry) sortKey(order CatalogFiltersSortBy) string {
+	switch order {
+	case CatalogFiltersSortByName:
+		return e.name
+	case CatalogFiltersSortByPublisher:
+		return e.publisher
+	case CatalogFiltersSortByDeveloper:
+		return e.developer
+	case CatalogFiltersSortByReleaseDate:
+		return e.releaseDate
+	}
+	return ""
+}
+
+// newCatalogMatcher returns a predicate checking filters other than the name query, which is handled by search.
+func newCatalogMatcher(filters *CatalogFilters) func(entry catalogIndexEntry) bool {
+	var predicates []func(entry catalogIndexEntry) bool
+	add := func(predicate func(entry catalogIndexEntry) bool) {
+		predicates = append(predicates, predicate)
+	}
+
+	if filters.ID != nil {
+		idPrefix := strings.ToUpper(*filters.ID)
+		add(func(entry catalogIndexEntry) bool { return strings.HasPrefix(entry.id, idPrefix) })
+	}
+	if len(filters.Region) > 0 {
+		regions := stringSet(lowerStrings(filters.Region))
+		add(func(entry catalogIndexEntry) bool { return regions.contains(entry.region) })
+	}
+	if filters.Publisher != nil {
+		publisher := search.Normalize(*filters.Publisher)
+		add(func(entry catalogIndexEntry) bool { return strings.Contains(entry.publisher, publisher) })
+	}
+	if filters.Developer != nil {
+		developer := search.Normalize(*filters.Developer)
+		add(func(entry catalogIndexEntry) bool { return strings.Contains(entry.developer, developer) })
+	}
+	if len(filters.Category) > 0 {
+		categories := stringSet(lowerStrings(filters.Category))
+		add(func(entry catalogIndexEntry) bool { return categories.containsAny(entry.categories) })
+	}
+	if len(filters.Language) > 0 {
+		languages := stringSet(lowerStrings(filters.Language))
+		add(func(entry catalogIndexEntry) bool { return languages.containsAny(entry.languages) })
+	}
+	if filters.MinSize != nil {
+		minSize := *filters.MinSize
+		add(func(entry catalogIndexEntry) bool { return entry.size >= minSize })
+	}
+	if filters.MaxSize != nil {
+		maxSize := *filters.MaxSize
+		add(func(entry catalogIndexEntry) bool { return entry.size <= maxSize })
+	}
+	if filters.ReleasedAfter != nil {
+		after := *filters.ReleasedAfter
+		add(func(entry catalogIndexEntry) bool { return entry.releaseDate != "" && entry.releaseDate >= after })
+	}
+	if filters.ReleasedBefore != nil {
+		// partial dates such as "2020-05" include the whole period
+		before := *filters.ReleasedBefore
+		add(func(entry catalogIndexEntry) bool {
+			return entry.releaseDate != "" && (entry.releaseDate <= before || strings.HasPrefix(entry.releaseDate, before))
+		})
+	}
+	if filters.IsDemo != nil {
+		isDemo := *filters.IsDemo
+		add(func(entry catalogIndexEntry) bool { return entry.isDemo == isDemo })
+	}
+	switch filters.Ownership {
+	case CatalogOwnershipOwned:
+		add(func(entry catalogIndexEntry) bool { return containsKey(filters.OwnedTitles, entry.key) })
+	case CatalogOwnershipNotOwned:
+		add(func(entry catalogIndexEntry) bool { return !containsKey(filters.OwnedTitles, entry.key) })
+	case CatalogOwnershipMissingContent:
+		add(func(entry catalogIndexEntry) bool { return containsKey(filters.IncompleteTitles, entry.key) })
+	}
+
+	return func(entry catalogIndexEntry) bool {
+		for _, predicate := range predicates {
+			if !predicate(entry) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type stringSet []string
+
+func (s stringSet) contains(value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s stringSet) containsAny(values []string) bool {
+	for _, value := range values {
+		if s.contains(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKey(set map[string]struct{}, key string) bool {
+	_, ok := set[key]
+	return ok
+}
+
+func lowerStrings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strings.ToLower(strings.TrimSpace(value)))
+	}
+	return result
+}
+
+// regionPositions returns positions of entries from any of the regions, sorted by ID.
+func (i *catalogIndex) regionPositions(regions []string) []int {
+	seen := make(map[string]struct{}, len(regions))
+	var result []int
+	for _, region := range regions {
+		region = strings.ToLower(region)
+		if _, ok := seen[region]; ok {
+			continue
+		}
+		seen[region] = struct{}{}
+		result = append(result, i.regions[region]...)
+	}
+	if len(seen) > 1 {
+		sort.Slice(result, func(a, b int) bool {
+			return i.entries[result[a]].id < i.entries[result[b]].id
+		})
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/timshannon/bolthold"
+	"sort"
+)
+
+func (d *Database) UpsertConsoleReport(report ConsoleReport) error {
+	err := d.db.Upsert(report.ConsoleID, report)
+	if err != nil {
+		return fmt.Errorf("could not upsert report of console %v: %w", report.ConsoleID, err)
+	}
+	return nil
+}
+
+// GetConsoleReports returns reports of all consoles ordered by console ID.
+func (d *Database) GetConsoleReports() ([]ConsoleReport, error) {
+	var reports []ConsoleReport
+	err := d.db.Find(&reports, nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].ConsoleID < reports[j].ConsoleID
+	})
+	return reports, nil
+}
+
+func (d *Database) DeleteConsoleReport(consoleID string) error {
+	err := d.db.Delete(consoleID, ConsoleReport{})
+	if err != nil && !errors.Is(err, bolthold.ErrNotFound) {
+		return fmt.Errorf("could not delete report of console %v: %w", consoleID, err)
+	}
+	return nil
+}
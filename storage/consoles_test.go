@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConsoleReports(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	reportedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	err = db.UpsertConsoleReport(ConsoleReport{ConsoleID: "living-room", ReportedAt: reportedAt, Titles: []ConsoleReportTitle{{ID: "0100000000010000"}}})
+	assert.Nil(t, err)
+	err = db.UpsertConsoleReport(ConsoleReport{ConsoleID: "bedroom", User: "kids", Titles: []ConsoleReportTitle{{ID: "0100000000020000"}}})
+	assert.Nil(t, err)
+	err = db.UpsertConsoleReport(ConsoleReport{ConsoleID: "living-room", ReportedAt: reportedAt, Titles: []ConsoleReportTitle{{ID: "0100000000010000", Version: 65536}}})
+	assert.Nil(t, err)
+
+	reports, err := db.GetConsoleReports()
+	assert.Nil(t, err)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, "bedroom", reports[0].ConsoleID)
+	assert.Equal(t, "kids", reports[0].User)
+	assert.Equal(t, "living-room", reports[1].ConsoleID)
+	assert.True(t, reportedAt.Equal(reports[1].ReportedAt))
+	assert.Equal(t, []ConsoleReportTitle{{ID: "0100000000010000", Version: 65536}}, reports[1].Titles)
+
+	err = db.DeleteConsoleReport("bedroom")
+	assert.Nil(t, err)
+	err = db.DeleteConsoleReport("missing")
+	assert.Nil(t, err)
+
+	reports, err = db.GetConsoleReports()
+	assert.Nil(t, err)
+	assert.Len(t, reports, 1)
+}
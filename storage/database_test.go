@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewDatabaseInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewDatabase(path)
+	assert.Nil(t, err)
+
+	_, err = NewDatabase(path)
+	assert.ErrorIs(t, err, ErrDatabaseInUse)
+
+	assert.Nil(t, db.Close())
+	db, err = NewDatabase(path)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+}
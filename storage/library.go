@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"github.com/timshannon/bolthold"
+	"go.etcd.io/bbolt"
+	"strings"
+)
+
+func (d *Database) UpsertLibraryEntries(entries []LibraryEntry) error {
+	return d.db.Bolt().Update(func(tx *bbolt.Tx) error {
+		for _, entry := range entries {
+			err := d.db.TxUpsert(tx, entry.FilePath, entry)
+			if err != nil {
+				return fmt.Errorf("could not upsert entry %v: %w", entry.FilePath, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (d *Database) DeleteLibraryEntries(filePaths []string) error {
+	return d.db.Bolt().Update(func(tx *bbolt.Tx) error {
+		for _, filePath := range filePaths {
+			err := d.db.TxDelete(tx, filePath, LibraryEntry{})
+			if err != nil && !errors.Is(err, bolthold.ErrNotFound) {
+				return fmt.Errorf("could not delete entry %v: %w", filePath, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (d *Database) GetLibraryEntries() ([]LibraryEntry, error) {
+	var entries []LibraryEntry
+	err := d.db.Find(&entries, nil)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (d *Database) GetLibraryEntry(filePath string) (LibraryEntry, bool, error) {
+	var entry LibraryEntry
+	err := d.db.Get(filePath, &entry)
+	if err != nil {
+		if errors.Is(err, bolthold.ErrNotFound) {
+			return LibraryEntry{}, false, nil
+		}
+		return LibraryEntry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (d *Database) GetLibraryEntriesByTitleID(titleID string) ([]LibraryEntry, error) {
+	var entries []LibraryEntry
+	query := bolthold.Where("TitleIDs").Contains(strings.ToUpper(titleID)).Index("TitleIDs")
+	err := d.db.Find(&entries, query)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (d *Database) ClearLibrary() error {
+	return d.db.DeleteMatching(&LibraryEntry{}, nil)
+}
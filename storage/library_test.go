@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryEntries(t *testing.T) {
+	db, err := NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = db.UpsertLibraryEntries([]LibraryEntry{
+		{FilePath: "/a.nsp", FileSize: 1, TitleIDs: []string{"0100000000010000"}},
+		{FilePath: "/b.nsp", FileSize: 2, TitleIDs: []string{"0100000000010800", "0100000000011001"}},
+	})
+	assert.Nil(t, err)
+
+	entries, err := db.GetLibraryEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = db.GetLibraryEntriesByTitleID("0100000000011001")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "/b.nsp", entries[0].FilePath)
+
+	err = db.UpsertLibraryEntries([]LibraryEntry{
+		{FilePath: "/b.nsp", FileSize: 3, TitleIDs: []string{"0100000000010800"}},
+	})
+	assert.Nil(t, err)
+
+	entries, err = db.GetLibraryEntriesByTitleID("0100000000011001")
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+
+	entry, ok, err := db.GetLibraryEntry("/b.nsp")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, entry.FileSize)
+
+	err = db.DeleteLibraryEntries([]string{"/a.nsp", "/missing.nsp"})
+	assert.Nil(t, err)
+
+	_, ok, err = db.GetLibraryEntry("/a.nsp")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	err = db.ClearLibrary()
+	assert.Nil(t, err)
+	entries, err = db.GetLibraryEntries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 0)
+}
@@ -1,8 +1,14 @@
 package storage
 
+import "time"
+
 type CatalogMetadata struct {
-	VersionsETag string
-	TitlesETag   string
+	// FormatVersion is version of the stored entries format, catalog is rebuilt when it changes
+	FormatVersion int
+	VersionsETag  string
+	TitlesETag    string
+	// SourcesFingerprint identifies data of additional catalog sources used in the last build
+	SourcesFingerprint string
 }
 
 type CatalogEntryData struct {
@@ -17,6 +23,11 @@ type CatalogEntryData struct {
 	Key         string
 	ReleaseDate string
 	Publisher   string
+	Developer   string
+	Category    []string
+	Languages   []string
+	// Size is install size in bytes
+	Size        int
 	IsDemo      bool
 	Screenshots []string
 }
@@ -49,18 +60,111 @@ type CatalogFiltersSortBy string
 const (
 	CatalogFiltersSortByName CatalogFiltersSortBy = "name"
 	CatalogFiltersSortByID   CatalogFiltersSortBy = "id"
+	// CatalogFiltersSortByRelevance orders entries by how well they match the name query, it is the default
+	// order of name queries
+	CatalogFiltersSortByRelevance   CatalogFiltersSortBy = "relevance"
+	CatalogFiltersSortByPublisher   CatalogFiltersSortBy = "publisher"
+	CatalogFiltersSortByDeveloper   CatalogFiltersSortBy = "developer"
+	CatalogFiltersSortByReleaseDate CatalogFiltersSortBy = "releaseDate"
+	CatalogFiltersSortBySize        CatalogFiltersSortBy = "size"
+)
+
+type CatalogOwnership string
+
+const (
+	CatalogOwnershipOwned    CatalogOwnership = "owned"
+	CatalogOwnershipNotOwned CatalogOwnership = "notOwned"
+	// CatalogOwnershipMissingContent matches owned titles with missing updates or DLC
+	CatalogOwnershipMissingContent CatalogOwnership = "missingContent"
 )
 
 type CatalogFilters struct {
 	SortBy CatalogFiltersSortBy
+	// SortDescending reverses the order, it does not apply to relevance
+	SortDescending bool
+	// Name is a search query matched against names, publishers and descriptions
 	Name   *string
 	ID     *string
 	Region []string
+	// Publisher and Developer match entries containing the text, ignoring case and diacritics
+	Publisher *string
+	Developer *string
+	// Category and Language match entries with any of the values, ignoring case
+	Category []string
+	Language []string
+	// MinSize and MaxSize are inclusive limits of install size in bytes
+	MinSize *int
+	MaxSize *int
+	// ReleasedAfter and ReleasedBefore are inclusive limits of release date in ISO format, entries without
+	// release date do not match
+	ReleasedAfter  *string
+	ReleasedBefore *string
+	IsDemo         *bool
+	// Ownership matches entries by uppercase title ID prefixes in OwnedTitles and IncompleteTitles, which are
+	// filled by the caller as storage does not know the library
+	Ownership        CatalogOwnership
+	OwnedTitles      map[string]struct{}
+	IncompleteTitles map[string]struct{}
 }
 
 type Page[DataType any] struct {
-	Data       []DataType
-	NextCursor int
+	Data []DataType
+	// NextCursor is an opaque cursor of the next page, empty on the last page
+	NextCursor string
 	TotalCount int
 	IsLastPage bool
 }
+
+type LibraryEntryGame struct {
+	IDPrefix        string
+	ID              string
+	Version         int
+	Name            map[string]string
+	ReadableVersion string
+	ISBN            string
+}
+
+type LibraryEntryDLC struct {
+	ForIDPrefix string
+	ID          string
+	Version     int
+}
+
+type LibraryEntryUpdate struct {
+	ForIDPrefix     string
+	ID              string
+	Version         int
+	ReadableVersion string
+}
+
+type LibraryEntry struct {
+	// FilePath is absolute path to the file and a key of the entry
+	FilePath     string
+	FileSize     int
+	FileModified int
+	IsSplit      bool
+	// TitleIDs are IDs of all titles contained in the file, used for lookups
+	TitleIDs       []string `boltholdSliceIndex:"TitleIDs"`
+	ExtractionType string
+	IsMultiContent bool
+	BaseGames      []LibraryEntryGame
+	DLCs           []LibraryEntryDLC
+	Updates        []LibraryEntryUpdate
+}
+
+// ConsoleReport is the list of titles installed on a console, as last reported by its client.
+type ConsoleReport struct {
+	// ConsoleID identifies the console and is a key of the report
+	ConsoleID string
+	// User is name of the NUT user that sent the report, empty without authentication
+	User string
+	// Client is user agent of the reporting client
+	Client     string
+	ReportedAt time.Time
+	Titles     []ConsoleReportTitle
+}
+
+type ConsoleReportTitle struct {
+	ID      string
+	Version int
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -57,42 +58,78 @@ func (sp *fileWrapper) Close() error {
 
 func NewSplitFileReader(filePath string) (*splitFile, error) {
 	result := splitFile{}
-	index := strings.LastIndex(filePath, string(os.PathSeparator))
-	splitFileFolder := filePath[:index]
-	files, err := ioutil.ReadDir(splitFileFolder)
+	splitFileFolder := filepath.Dir(filePath)
+	parts, err := splitFileParts(filePath)
 	if err != nil {
 		return nil, err
 	}
-	result.path = splitFileFolder
-	result.chunkSize = files[0].Size()
-	result.info = make([]os.FileInfo, 0, len(files))
-	result.files = make([]ReadAtCloser, len(files))
-	for _, file := range files {
-		if _, err := strconv.Atoi(file.Name()[len(file.Name())-1:]); err == nil {
-			result.info = append(result.info, file)
-		}
+	if len(parts) == 0 {
+		return nil, errors.New("no parts of split file found in " + splitFileFolder)
 	}
+	result.path = splitFileFolder
+	result.chunkSize = parts[0].Size()
+	result.info = parts
+	result.files = make([]ReadAtCloser, len(parts))
 	return &result, nil
 }
 
+// ReadAt reads from consecutive parts as if they were a single file.
 func (sp *splitFile) ReadAt(p []byte, off int64) (n int, err error) {
-	//calculate the part containing the offset
-	part := int(off / sp.chunkSize)
-
-	if len(sp.info) < part {
-		return 0, errors.New("missing part " + strconv.Itoa(part))
+	if off < 0 {
+		return 0, errors.New("offset is out of bounds")
 	}
+	for n < len(p) {
+		//calculate the part containing the offset
+		part := int((off + int64(n)) / sp.chunkSize)
+		if part >= len(sp.info) {
+			return n, io.EOF
+		}
 
-	if len(sp.files) == 0 || sp.files[part] == nil {
-		file, _ := _openFile(path.Join(sp.path, sp.info[part].Name()))
-		sp.files[part] = file
+		if sp.files[part] == nil {
+			file, err := _openFile(path.Join(sp.path, sp.info[part].Name()))
+			if err != nil {
+				return n, err
+			}
+			sp.files[part] = file
+		}
+
+		read, err := sp.files[part].ReadAt(p[n:], off+int64(n)-sp.chunkSize*int64(part))
+		n += read
+		if err == io.EOF {
+			if part == len(sp.info)-1 {
+				return n, io.EOF
+			}
+			if read == 0 {
+				return n, errors.New("missing data in part " + strconv.Itoa(part))
+			}
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
 	}
-	off = off - sp.chunkSize*int64(part)
+	return n, nil
+}
 
-	if off < 0 || off > sp.info[part].Size() {
-		return 0, errors.New("offset is out of bounds")
+// splitFileParts returns parts of the split file the part belongs to, ordered by name. Parts are files in the same
+// folder with the same name followed by the part number, either only numbered files or files like "game.nsp.00".
+func splitFileParts(partPath string) ([]os.FileInfo, error) {
+	splitFileFolder := filepath.Dir(partPath)
+	name := strings.TrimRight(filepath.Base(partPath), "0123456789")
+	files, err := ioutil.ReadDir(splitFileFolder)
+	if err != nil {
+		return nil, err
 	}
-	return sp.files[part].ReadAt(p, off)
+	parts := make([]os.FileInfo, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), name) {
+			continue
+		}
+		if _, err := strconv.ParseUint(strings.TrimPrefix(file.Name(), name), 10, 64); err == nil {
+			parts = append(parts, file)
+		}
+	}
+	return parts, nil
 }
 
 func _openFile(path string) (*os.File, error) {
@@ -117,6 +154,26 @@ func (sp *splitFile) Close() error {
 	return nil
 }
 
+// FileSize returns size of the file, split files are measured as all their parts together.
+func FileSize(filePath string) (int64, error) {
+	if _, err := strconv.Atoi(filePath[len(filePath)-1:]); err == nil {
+		parts, err := splitFileParts(filePath)
+		if err != nil {
+			return 0, err
+		}
+		size := int64(0)
+		for _, part := range parts {
+			size += part.Size()
+		}
+		return size, nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
 func OpenFile(filePath string) (ReadAtCloser, error) {
 	//check if it's a split file
 	if _, err := strconv.Atoi(filePath[len(filePath)-1:]); err == nil {
@@ -0,0 +1,41 @@
+package switchfs
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitFileReaderParts(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"A.xci.00": "0123",
+		"A.xci.01": "45",
+		"B.xci.00": "abcd",
+		"B.xci.01": "efgh",
+		"B.xci.02": "i",
+		"C.nsp":    "other",
+	} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	tests := map[string]string{
+		"A.xci.00": "012345",
+		"B.xci.00": "abcdefghi",
+	}
+	for name, expected := range tests {
+		path := filepath.Join(dir, name)
+		size, err := FileSize(path)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(expected)), size, name)
+
+		file, err := OpenFile(path)
+		assert.Nil(t, err)
+		content, err := io.ReadAll(io.NewSectionReader(file, 0, size))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(content), name)
+		assert.Nil(t, file.Close())
+	}
+}